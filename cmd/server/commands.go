@@ -0,0 +1,249 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mrumyantsev/currency-converter-app/internal/app/server"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/exporter"
+)
+
+const (
+	cmdApiKey       = "apikey"
+	cmdApiKeyCreate = "create"
+	cmdApiKeyList   = "list"
+	cmdApiKeyRevoke = "revoke"
+
+	cmdAverages        = "averages"
+	cmdAveragesRebuild = "rebuild"
+
+	cmdExport = "export"
+
+	cmdImport = "import"
+
+	cmdInfo     = "info"
+	cmdInfoSync = "sync"
+
+	cmdHistory         = "history"
+	cmdHistoryBackfill = "backfill"
+
+	cmdArchive       = "archive"
+	cmdArchivePrune  = "prune"
+	cmdArchiveVerify = "verify"
+
+	cmdReplay = "replay"
+)
+
+func runCommand(app *server.App, name string, args []string) error {
+	switch name {
+	case cmdApiKey:
+		return runApiKeyCommand(app, args)
+	case cmdAverages:
+		return runAveragesCommand(app, args)
+	case cmdExport:
+		return runExportCommand(app, args)
+	case cmdImport:
+		return runImportCommand(app, args)
+	case cmdInfo:
+		return runInfoCommand(app, args)
+	case cmdHistory:
+		return runHistoryCommand(app, args)
+	case cmdArchive:
+		return runArchiveCommand(app, args)
+	case cmdReplay:
+		return runReplayCommand(app, args)
+	default:
+		return errors.New("unknown command: " + name)
+	}
+}
+
+func runApiKeyCommand(app *server.App, args []string) error {
+	if len(args) == 0 {
+		return errors.New("no apikey subcommand specified, use one of: " +
+			cmdApiKeyCreate + ", " + cmdApiKeyList + ", " + cmdApiKeyRevoke)
+	}
+
+	flags := flag.NewFlagSet(cmdApiKey+" "+args[0], flag.ExitOnError)
+
+	switch args[0] {
+	case cmdApiKeyCreate:
+		name := flags.String("name", "", "Name of the API key owner")
+		rateLimit := flags.Int("rate", 0, "Requests per minute (default from config)")
+		rateBurst := flags.Int("burst", 0, "Burst size (default from config)")
+
+		_ = flags.Parse(args[1:])
+
+		return app.CreateApiKey(*name, *rateLimit, *rateBurst)
+	case cmdApiKeyList:
+		_ = flags.Parse(args[1:])
+
+		return app.ListApiKeys()
+	case cmdApiKeyRevoke:
+		id := flags.Int("id", 0, "Id of the API key to revoke")
+
+		_ = flags.Parse(args[1:])
+
+		return app.RevokeApiKey(*id)
+	default:
+		return errors.New("unknown apikey subcommand: " + args[0])
+	}
+}
+
+func runAveragesCommand(app *server.App, args []string) error {
+	if len(args) == 0 {
+		return errors.New("no averages subcommand specified, use: " + cmdAveragesRebuild)
+	}
+
+	flags := flag.NewFlagSet(cmdAverages+" "+args[0], flag.ExitOnError)
+
+	switch args[0] {
+	case cmdAveragesRebuild:
+		_ = flags.Parse(args[1:])
+
+		return app.RebuildAverageRates()
+	default:
+		return errors.New("unknown averages subcommand: " + args[0])
+	}
+}
+
+func runExportCommand(app *server.App, args []string) error {
+	flags := flag.NewFlagSet(cmdExport, flag.ExitOnError)
+
+	codes := flags.String("codes", "", "Comma separated currency codes (default all)")
+	from := flags.String("from", "", "First date of the period, YYYY-MM-DD")
+	to := flags.String("to", "", "Last date of the period, YYYY-MM-DD")
+	format := flags.String("format", exporter.FormatCsv, "Format: csv, xlsx-csv or jsonl")
+	columns := flags.String("columns", "", "Comma separated columns (default all)")
+	decimal := flags.String("decimal", "", "Decimal separator of CSV formats: . or ,")
+	delimiter := flags.String("delimiter", "", "Delimiter of CSV formats: a character or tab")
+	output := flags.String("output", "", "Output file (default stdout)")
+	isArchive := flags.Bool("archive", false, "Store the report in the archive instead of the output")
+
+	_ = flags.Parse(args)
+
+	opts, err := exporter.NewOptions(*format, splitList(*columns), *decimal, *delimiter)
+	if err != nil {
+		return err
+	}
+
+	return app.Export(splitList(*codes), *from, *to, opts, *output, *isArchive)
+}
+
+func runImportCommand(app *server.App, args []string) error {
+	flags := flag.NewFlagSet(cmdImport, flag.ExitOnError)
+
+	dir := flags.String("dir", "", "Directory of daily XML feeds")
+	csvPath := flags.String("csv", "", "CSV file of date, code, nominal and value rows")
+	delimiter := flags.String("delimiter", ",", "Delimiter of the CSV file: a character or tab")
+	isDryRun := flags.Bool("dry-run", false, "Validate the data without importing it")
+
+	_ = flags.Parse(args)
+
+	switch {
+	case (*dir == "") == (*csvPath == ""):
+		return errors.New("specify either -dir or -csv to import from")
+	case *dir != "":
+		return app.ImportXmlDir(*dir, *isDryRun)
+	}
+
+	comma := '\t'
+
+	if *delimiter != "tab" {
+		if utf8.RuneCountInString(*delimiter) != 1 {
+			return errors.New("delimiter must be a single character or tab")
+		}
+
+		comma, _ = utf8.DecodeRuneInString(*delimiter)
+	}
+
+	return app.ImportCsv(*csvPath, comma, *isDryRun)
+}
+
+func runInfoCommand(app *server.App, args []string) error {
+	if len(args) == 0 {
+		return errors.New("no info subcommand specified, use: " + cmdInfoSync)
+	}
+
+	flags := flag.NewFlagSet(cmdInfo+" "+args[0], flag.ExitOnError)
+
+	switch args[0] {
+	case cmdInfoSync:
+		_ = flags.Parse(args[1:])
+
+		return app.SyncInfo()
+	default:
+		return errors.New("unknown info subcommand: " + args[0])
+	}
+}
+
+func runHistoryCommand(app *server.App, args []string) error {
+	if len(args) == 0 {
+		return errors.New("no history subcommand specified, use: " + cmdHistoryBackfill)
+	}
+
+	flags := flag.NewFlagSet(cmdHistory+" "+args[0], flag.ExitOnError)
+
+	switch args[0] {
+	case cmdHistoryBackfill:
+		from := flags.String("from", "", "First date of the period, YYYY-MM-DD")
+		to := flags.String("to", "", "Last date of the period, YYYY-MM-DD")
+		isDryRun := flags.Bool("dry-run", false, "Validate the data without importing it")
+
+		_ = flags.Parse(args[1:])
+
+		return app.BackfillHistory(*from, *to, *isDryRun)
+	default:
+		return errors.New("unknown history subcommand: " + args[0])
+	}
+}
+
+func runArchiveCommand(app *server.App, args []string) error {
+	if len(args) == 0 {
+		return errors.New("no archive subcommand specified, use one of: " +
+			cmdArchivePrune + ", " + cmdArchiveVerify)
+	}
+
+	flags := flag.NewFlagSet(cmdArchive+" "+args[0], flag.ExitOnError)
+
+	switch args[0] {
+	case cmdArchivePrune:
+		_ = flags.Parse(args[1:])
+
+		return app.PruneArchive()
+	case cmdArchiveVerify:
+		_ = flags.Parse(args[1:])
+
+		return app.VerifyArchive()
+	default:
+		return errors.New("unknown archive subcommand: " + args[0])
+	}
+}
+
+func runReplayCommand(app *server.App, args []string) error {
+	flags := flag.NewFlagSet(cmdReplay, flag.ExitOnError)
+
+	dir := flags.String("dir", "", "Directory of archived payloads (default archive directory)")
+	from := flags.String("from", "", "First date of the period, YYYY-MM-DD (default first payload)")
+	to := flags.String("to", "", "Last date of the period, YYYY-MM-DD (default last payload)")
+	fetchDelay := flags.Duration("fetch-delay", time.Minute, "Time an update takes to fetch the payload")
+
+	_ = flags.Parse(args)
+
+	return app.Replay(*dir, *from, *to, *fetchDelay)
+}
+
+// splitList splits the comma separated list, dropping empty items.
+func splitList(list string) []string {
+	var items []string
+
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
@@ -20,12 +20,16 @@ func init() {
 }
 
 func main() {
+	isSave := flag.Bool("s", false, "Save currency data to a local file")
+
+	flag.Parse()
+
 	app, err := server.New()
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed to initialize application")
 	}
 
-	if isUserWantSave() {
+	if *isSave {
 		if err = app.SaveCurrencyDataToFile(); err != nil {
 			log.Fatal().Err(err).Msg("failed to save currencies to file")
 		}
@@ -33,15 +37,15 @@ func main() {
 		return
 	}
 
+	if flag.NArg() > 0 {
+		if err = runCommand(app, flag.Arg(0), flag.Args()[1:]); err != nil {
+			log.Fatal().Err(err).Msg("failed to run command " + flag.Arg(0))
+		}
+
+		return
+	}
+
 	if err = app.Run(); err != nil {
 		log.Fatal().Err(err).Msg("failed to run application")
 	}
 }
-
-func isUserWantSave() bool {
-	f := flag.Bool("s", false, "Save currency data to a local file")
-
-	flag.Parse()
-
-	return *f
-}
@@ -9,17 +9,19 @@ require (
 	golang.org/x/net v0.19.0
 )
 
+require github.com/andybalholm/brotli v1.1.0
+
 require (
 	github.com/golang-jwt/jwt v3.2.2+incompatible // indirect
 	github.com/labstack/gommon v0.4.2 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
-	github.com/mrumyantsev/go-errlib v1.0.2 // indirect
+	github.com/mrumyantsev/go-errlib v1.0.2
 	github.com/rs/zerolog v1.32.0
 	github.com/valyala/bytebufferpool v1.0.0 // indirect
 	github.com/valyala/fasttemplate v1.2.2 // indirect
 	golang.org/x/crypto v0.17.0 // indirect
 	golang.org/x/sys v0.15.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
-	golang.org/x/time v0.5.0 // indirect
+	golang.org/x/time v0.5.0
 )
@@ -1,9 +1,13 @@
 package server
 
 import (
+	"bufio"
 	"context"
+	"fmt"
+	"io"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 
 	"github.com/labstack/echo/v4/middleware"
@@ -13,30 +17,54 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/archive"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/auth"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/calculator"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/clock"
 	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
 	"github.com/mrumyantsev/currency-converter-app/internal/pkg/database"
 	"github.com/mrumyantsev/currency-converter-app/internal/pkg/endpoint"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/exporter"
 	fsops "github.com/mrumyantsev/currency-converter-app/internal/pkg/fs-ops"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/importer"
 	memcache "github.com/mrumyantsev/currency-converter-app/internal/pkg/mem-cache"
 	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/openapi"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/replay"
 	"github.com/mrumyantsev/currency-converter-app/internal/pkg/repository"
 	"github.com/mrumyantsev/currency-converter-app/internal/pkg/server"
 	"github.com/mrumyantsev/currency-converter-app/internal/pkg/service"
+	sourceclient "github.com/mrumyantsev/currency-converter-app/internal/pkg/source-client"
 	timechecks "github.com/mrumyantsev/currency-converter-app/internal/pkg/time-checks"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/validator"
 	xmlparser "github.com/mrumyantsev/currency-converter-app/internal/pkg/xml-parser"
 	"github.com/mrumyantsev/go-errlib"
 )
 
+const (
+	ratesVersion   = 1
+	feedDateLayout = "02.01.2006"
+)
+
 type App struct {
 	config     *config.Config
+	archive    *archive.Archive
+	calculator *calculator.Calculator
 	fsOps      *fsops.FsOps
 	xmlParser  *xmlparser.XmlParser
+	validator  *validator.Validator
 	timeChecks *timechecks.TimeChecks
+	clock      clock.Clock
 	memCache   *memcache.MemCache
 	database   *database.Database
 	service    *service.Service
 	endpoint   *endpoint.Endpoint
 	server     *server.Server
+
+	// replay serves archived payloads in place of the source, until the
+	// simulated clock reaches replayEnd
+	replay    *replay.Replay
+	replayEnd time.Time
 }
 
 func New() (*App, error) {
@@ -48,23 +76,78 @@ func New() (*App, error) {
 
 	memCache := memcache.New()
 
+	clk := clock.NewReal()
+
+	timeChecks, err := timechecks.New(cfg, clk)
+	if err != nil {
+		return nil, errlib.Wrap(err, "could not create time checks")
+	}
+
 	db := database.New(cfg)
 
 	repository := repository.New(cfg, db)
 
-	service := service.New(cfg, repository)
+	archive, err := archive.New(cfg)
+	if err != nil {
+		return nil, errlib.Wrap(err, "could not create archive")
+	}
+
+	service := service.New(cfg, repository, archive, clk)
+
+	sourceClient, err := sourceclient.New(cfg, clk)
+	if err != nil {
+		return nil, errlib.Wrap(err, "could not create source client")
+	}
 
-	endpoint := endpoint.New(cfg, memCache, service)
+	endpoint := endpoint.New(cfg, memCache, service, sourceClient, clk)
 
-	mwCors := middleware.CORS()
+	mwRecover := middleware.Recover()
 
-	server := server.New(cfg, endpoint, mwCors)
+	mwCors := middleware.CORSWithConfig(middleware.CORSConfig{
+		AllowOrigins:  cfg.CorsAllowOrigins,
+		AllowMethods:  cfg.CorsAllowMethods,
+		AllowHeaders:  cfg.CorsAllowHeaders,
+		ExposeHeaders: cfg.CorsExposeHeaders,
+		MaxAge:        cfg.CorsMaxAge,
+	})
+
+	mwSecure := middleware.SecureWithConfig(middleware.SecureConfig{
+		XSSProtection:         "0",
+		ContentTypeNosniff:    "nosniff",
+		XFrameOptions:         "DENY",
+		HSTSMaxAge:            cfg.HstsMaxAge,
+		ContentSecurityPolicy: cfg.ContentSecurityPolicy,
+		ReferrerPolicy:        "no-referrer",
+	})
+
+	mwBodyLimit := middleware.BodyLimitWithConfig(middleware.BodyLimitConfig{
+		Skipper: endpoint.HasOwnBodyLimit,
+		Limit:   cfg.HttpServerBodyLimit,
+	})
+
+	mwAuth := auth.New(cfg, service.ApiKeys, clk).Middleware
+
+	spec, err := openapi.Load()
+	if err != nil {
+		return nil, errlib.Wrap(err, "could not load openapi specification")
+	}
+
+	mwValidator, err := spec.Validator()
+	if err != nil {
+		return nil, errlib.Wrap(err, "could not create request validator")
+	}
+
+	server := server.New(cfg, endpoint, mwRecover, mwSecure, mwCors, mwBodyLimit, mwAuth, mwValidator)
 
 	return &App{
 		config:     cfg,
+		archive:    archive,
+		calculator: calculator.New(cfg),
 		fsOps:      fsops.New(cfg),
 		xmlParser:  xmlparser.New(cfg),
-		timeChecks: timechecks.New(cfg),
+		validator:  validator.New(cfg),
+		timeChecks: timeChecks,
+		clock:      clk,
 		memCache:   memCache,
 		database:   db,
 		service:    service,
@@ -83,6 +166,10 @@ func (a *App) Run() error {
 
 	log.Debug().Msg("database connection opened")
 
+	if err = a.server.LoadCertificate(); err != nil {
+		return errlib.Wrap(err, "could not prepare http server")
+	}
+
 	goErr := make(chan error, 1)
 
 	isShutdown := false
@@ -102,11 +189,25 @@ func (a *App) Run() error {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
-	select {
-	case err := <-goErr:
-		return err
-	case <-quit:
-		break
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+
+loop:
+	for {
+		select {
+		case err := <-goErr:
+			return err
+		case <-reload:
+			log.Info().Msg("reload signal read")
+
+			if err = a.server.ReloadCertificate(); err != nil {
+				log.Error().Err(err).Msg("could not reload tls certificate")
+			} else {
+				log.Info().Msg("tls certificate reloaded")
+			}
+		case <-quit:
+			break loop
+		}
 	}
 
 	// Graceful shutdown
@@ -135,13 +236,25 @@ func (a *App) Run() error {
 	return nil
 }
 
+// SaveCurrencyDataToFile saves the daily feed to the file, which is read
+// instead of the source, if it is configured so. The feed is archived as
+// any other one.
 func (a *App) SaveCurrencyDataToFile() error {
-	data, err := a.endpoint.CurrenciesFromSource.CurrenciesFromSource()
+	if a.config.IsArchiveEnabled {
+		if err := a.database.Connect(); err != nil {
+			return errlib.Wrap(err, "could not connect to database")
+		}
+		defer func() { _ = a.database.Disconnect() }()
+	}
+
+	resp, err := a.endpoint.CurrenciesFromSource.CurrenciesFromSource()
 	if err != nil {
 		return errlib.Wrap(err, "could not get currencies from web")
 	}
 
-	if err = a.fsOps.OverwriteCurrencyDataFile(data); err != nil {
+	a.archivePayload(resp)
+
+	if err = a.fsOps.OverwriteCurrencyDataFile(resp.Body); err != nil {
 		return errlib.Wrap(err, "could not write currencies to file")
 	}
 
@@ -150,214 +263,908 @@ func (a *App) SaveCurrencyDataToFile() error {
 	return nil
 }
 
+// CreateApiKey issues a new API key and prints it. Zero limits are
+// replaced with the configured defaults.
+func (a *App) CreateApiKey(name string, rateLimit int, rateBurst int) error {
+	if name == "" {
+		return errors.New("api key name is empty")
+	}
+
+	if rateLimit <= 0 {
+		rateLimit = a.config.ApiKeyDefaultRateLimit
+	}
+
+	if rateBurst <= 0 {
+		rateBurst = a.config.ApiKeyDefaultRateBurst
+	}
+
+	if err := a.database.Connect(); err != nil {
+		return errlib.Wrap(err, "could not connect to database")
+	}
+	defer func() { _ = a.database.Disconnect() }()
+
+	key, apiKey, err := a.service.ApiKeys.Create(name, rateLimit, rateBurst)
+	if err != nil {
+		return errlib.Wrap(err, "could not create api key")
+	}
+
+	fmt.Printf("id:    %d\nname:  %s\nrate:  %d/min, burst %d\nkey:   %s\n",
+		apiKey.Id, apiKey.Name, apiKey.RateLimit, apiKey.RateBurst, key)
+
+	log.Info().Msg("api key created, store it now: it will not be shown again")
+
+	return nil
+}
+
+// ListApiKeys prints all API keys, including revoked ones.
+func (a *App) ListApiKeys() error {
+	if err := a.database.Connect(); err != nil {
+		return errlib.Wrap(err, "could not connect to database")
+	}
+	defer func() { _ = a.database.Disconnect() }()
+
+	apiKeys, err := a.service.ApiKeys.GetAll()
+	if err != nil {
+		return errlib.Wrap(err, "could not get api keys")
+	}
+
+	fmt.Printf("%-5s %-24s %-10s %-6s %-26s %s\n",
+		"ID", "NAME", "RATE/MIN", "BURST", "CREATED", "REVOKED")
+
+	for _, apiKey := range apiKeys {
+		fmt.Printf("%-5d %-24s %-10d %-6d %-26s %s\n",
+			apiKey.Id, apiKey.Name, apiKey.RateLimit, apiKey.RateBurst,
+			apiKey.CreatedAt, apiKey.RevokedAt)
+	}
+
+	return nil
+}
+
+// RevokeApiKey revokes the API key with the given id.
+func (a *App) RevokeApiKey(id int) error {
+	if err := a.database.Connect(); err != nil {
+		return errlib.Wrap(err, "could not connect to database")
+	}
+	defer func() { _ = a.database.Disconnect() }()
+
+	if err := a.service.ApiKeys.Revoke(id); err != nil {
+		return errlib.Wrap(err, "could not revoke api key")
+	}
+
+	log.Info().Msg("api key revoked: " + strconv.Itoa(id))
+
+	return nil
+}
+
+// RebuildAverageRates recomputes average rates over the whole history.
+func (a *App) RebuildAverageRates() error {
+	if err := a.database.Connect(); err != nil {
+		return errlib.Wrap(err, "could not connect to database")
+	}
+	defer func() { _ = a.database.Disconnect() }()
+
+	if err := a.service.AverageRates.RefreshAll(); err != nil {
+		return errlib.Wrap(err, "could not rebuild average rates")
+	}
+
+	log.Info().Msg("average rates rebuilt")
+
+	return nil
+}
+
+// Export writes daily rates of the currencies with the given codes, or
+// of all of them, from the first to the last date inclusive to the file,
+// or to stdout, if the path is empty. An interrupted export removes the
+// file, so it is not taken for a whole one. If the report is to be
+// archived, it is written to a temporary file and stored in the archive,
+// once it is whole.
+func (a *App) Export(codes []string, from string, to string, opts exporter.Options, path string, isArchive bool) (err error) {
+	for _, date := range []string{from, to} {
+		if _, err = time.Parse(time.DateOnly, date); err != nil {
+			return errors.New("date is not in YYYY-MM-DD format: " + date)
+		}
+	}
+
+	if from > to {
+		return errors.New("period starts after it ends: " + from + ".." + to)
+	}
+
+	if err = a.database.Connect(); err != nil {
+		return errlib.Wrap(err, "could not connect to database")
+	}
+	defer func() { _ = a.database.Disconnect() }()
+
+	if isArchive && (path != "") {
+		return errors.New("report is either archived or written to a file")
+	}
+
+	output := os.Stdout
+
+	if isArchive {
+		if output, err = os.CreateTemp("", "export-*"+opts.FileExtension()); err != nil {
+			return errlib.Wrap(err, "could not create temporary export file")
+		}
+
+		defer func() {
+			_ = output.Close()
+			_ = os.Remove(output.Name())
+		}()
+	}
+
+	if path != "" {
+		if output, err = os.Create(path); err != nil {
+			return errlib.Wrap(err, "could not create export file")
+		}
+
+		defer func() {
+			if closeErr := output.Close(); (err == nil) && (closeErr != nil) {
+				err = errlib.Wrap(closeErr, "could not close export file")
+			}
+
+			if err != nil {
+				_ = os.Remove(path)
+			}
+		}()
+	}
+
+	var (
+		buffer = bufio.NewWriter(output)
+		export = exporter.New(buffer, opts, a.calculator)
+		count  int
+	)
+
+	err = a.service.Export.Stream(codes, from, to, func(row models.ExportRow) error {
+		count++
+
+		return export.Write(row)
+	})
+	if err != nil {
+		return errlib.Wrap(err, "could not export rates")
+	}
+
+	if err = export.Close(); err != nil {
+		return errlib.Wrap(err, "could not finish export")
+	}
+
+	if err = buffer.Flush(); err != nil {
+		return errlib.Wrap(err, "could not write export")
+	}
+
+	if isArchive {
+		if _, err = output.Seek(0, io.SeekStart); err != nil {
+			return errlib.Wrap(err, "could not rewind temporary export file")
+		}
+
+		createdAt := a.clock.Now()
+
+		name := "export-" + from + "-" + to + "-" + createdAt.Format("150405") + opts.FileExtension()
+
+		key, err := a.archive.WriteReport(name, output, createdAt)
+		if err != nil {
+			return errlib.Wrap(err, "could not archive export")
+		}
+
+		log.Info().Msg("export archived: " + key)
+	}
+
+	log.Info().Int("rows", count).Msg("rates exported")
+
+	return nil
+}
+
+// ImportXmlDir imports the history from daily XML feeds of the source,
+// saved as files of the directory. Nothing is written, if the dry run is
+// requested.
+func (a *App) ImportXmlDir(dir string, isDryRun bool) error {
+	if err := a.database.Connect(); err != nil {
+		return errlib.Wrap(err, "could not connect to database")
+	}
+	defer func() { _ = a.database.Disconnect() }()
+
+	snapshots, err := importer.New(a.config, a.xmlParser, a.clock).ReadXmlDir(dir)
+	if err != nil {
+		return importError(err)
+	}
+
+	return a.importSnapshots(snapshots, isDryRun)
+}
+
+// ImportCsv imports the history from a CSV of dates, codes, nominals and
+// values of known currencies. Nothing is written, if the dry run is
+// requested.
+func (a *App) ImportCsv(path string, delimiter rune, isDryRun bool) error {
+	if err := a.database.Connect(); err != nil {
+		return errlib.Wrap(err, "could not connect to database")
+	}
+	defer func() { _ = a.database.Disconnect() }()
+
+	file, err := os.Open(path)
+	if err != nil {
+		return errlib.Wrap(err, "could not open import file")
+	}
+	defer func() { _ = file.Close() }()
+
+	info, err := a.service.Import.GetInfo()
+	if err != nil {
+		return errlib.Wrap(err, "could not get known currencies")
+	}
+
+	snapshots, err := importer.New(a.config, a.xmlParser, a.clock).ReadCsv(file, filepath.Base(path), delimiter, info)
+	if err != nil {
+		return importError(err)
+	}
+
+	return a.importSnapshots(snapshots, isDryRun)
+}
+
+// SyncInfo updates the known currencies from the reference list of the
+// source, so their codes, names, nominals and codes in the source are
+// the authoritative ones.
+func (a *App) SyncInfo() error {
+	if err := a.database.Connect(); err != nil {
+		return errlib.Wrap(err, "could not connect to database")
+	}
+	defer func() { _ = a.database.Disconnect() }()
+
+	resp, err := a.endpoint.CurrenciesFromSource.CurrencyReference()
+	if err != nil {
+		return errlib.Wrap(err, "could not get reference list of currencies from web")
+	}
+
+	a.archivePayload(resp)
+
+	references, err := a.xmlParser.ParseReference(resp.Body)
+	if err != nil {
+		return errlib.Wrap(err, "could not parse reference list of currencies")
+	}
+
+	created, updated, err := a.service.Import.SyncInfo(references)
+	if err != nil {
+		return errlib.Wrap(err, "could not sync currency info")
+	}
+
+	log.Info().Int("created", created).Int("updated", updated).Msg("currency info synced")
+
+	return nil
+}
+
+// BackfillHistory imports the history from the dynamics of every known
+// currency, which has a code in the source, from the first to the last
+// date inclusive. Dates, which already have a snapshot, are skipped, so
+// the info is to be synced before, for snapshots to have all currencies.
+// Nothing is written, if the dry run is requested.
+func (a *App) BackfillHistory(from string, to string, isDryRun bool) error {
+	fromDate, err := time.Parse(time.DateOnly, from)
+	if err != nil {
+		return errors.New("date is not in YYYY-MM-DD format: " + from)
+	}
+
+	toDate, err := time.Parse(time.DateOnly, to)
+	if err != nil {
+		return errors.New("date is not in YYYY-MM-DD format: " + to)
+	}
+
+	if fromDate.After(toDate) {
+		return errors.New("period starts after it ends: " + from + ".." + to)
+	}
+
+	if err = a.database.Connect(); err != nil {
+		return errlib.Wrap(err, "could not connect to database")
+	}
+	defer func() { _ = a.database.Disconnect() }()
+
+	info, err := a.service.Import.GetInfo()
+	if err != nil {
+		return errlib.Wrap(err, "could not get known currencies")
+	}
+
+	var dynamics []models.CurrencyDynamic
+
+	for _, currency := range info {
+		if currency.SourceId == "" {
+			log.Debug().Msg("currency has no code in source, skipped: " + currency.CharCode)
+
+			continue
+		}
+
+		resp, err := a.endpoint.CurrenciesFromSource.CurrencyDynamic(currency.SourceId, fromDate, toDate)
+		if err != nil {
+			return errlib.Wrap(err, "could not get dynamic of "+currency.CharCode+" from web")
+		}
+
+		a.archivePayload(resp)
+
+		dynamic, err := a.xmlParser.ParseDynamic(resp.Body)
+		if err != nil {
+			return errlib.Wrap(err, "could not parse dynamic of "+currency.CharCode)
+		}
+
+		log.Debug().Int("rates", len(dynamic.Rates)).Msg("dynamic received: " + currency.CharCode)
+
+		dynamics = append(dynamics, dynamic)
+	}
+
+	if len(dynamics) == 0 {
+		return errors.New("known currencies have no codes in source, sync currency info first")
+	}
+
+	snapshots, err := importer.New(a.config, a.xmlParser, a.clock).ReadDynamics(dynamics, info)
+	if err != nil {
+		return importError(err)
+	}
+
+	return a.importSnapshots(snapshots, isDryRun)
+}
+
+// PruneArchive removes the payloads, which are older than the retention
+// period.
+func (a *App) PruneArchive() error {
+	if err := a.database.Connect(); err != nil {
+		return errlib.Wrap(err, "could not connect to database")
+	}
+	defer func() { _ = a.database.Disconnect() }()
+
+	count, err := a.service.Payloads.Prune(a.config.ArchiveRetention)
+	if err != nil {
+		return errlib.Wrap(err, "could not prune archive")
+	}
+
+	log.Info().Int("payloads", count).Msg("archive pruned")
+
+	return nil
+}
+
+// VerifyArchive checks, that every archived payload still has the hash it
+// was recorded with, and reports the ones, which are missing or changed.
+func (a *App) VerifyArchive() error {
+	if err := a.database.Connect(); err != nil {
+		return errlib.Wrap(err, "could not connect to database")
+	}
+	defer func() { _ = a.database.Disconnect() }()
+
+	payloads, err := a.service.Payloads.GetAll()
+	if err != nil {
+		return errlib.Wrap(err, "could not get archived payloads")
+	}
+
+	failed := 0
+
+	for _, payload := range payloads {
+		isValid, err := a.service.Payloads.Verify(payload)
+
+		switch {
+		case err != nil:
+			log.Error().Err(err).Int("id", payload.Id).Msg("payload is unreadable: " + payload.Key)
+		case !isValid:
+			log.Error().Int("id", payload.Id).Str("sha256", payload.Sha256).Msg("payload hash does not match: " + payload.Key)
+		default:
+			continue
+		}
+
+		failed++
+	}
+
+	if failed > 0 {
+		return errors.New(strconv.Itoa(failed) + " of " + strconv.Itoa(len(payloads)) + " archived payloads failed verification")
+	}
+
+	log.Info().Int("payloads", len(payloads)).Msg("archive verified")
+
+	return nil
+}
+
+// Replay runs the updates on a simulated clock, which goes from the first
+// date to the end of the last date, with the payloads of the directory in
+// place of the source. The directory defaults to the local archive, and
+// the period to the one of its payloads. Days of schedule pass in no time,
+// so it needs an empty database, which has no updates of real time.
+func (a *App) Replay(dir string, from string, to string, fetchDelay time.Duration) error {
+	if dir == "" {
+		dir = a.config.ArchiveDir
+	}
+
+	source, err := replay.New(a.config, dir, fetchDelay)
+	if err != nil {
+		return errlib.Wrap(err, "could not create replay")
+	}
+
+	// the replay starts when the first payload was fetched, unless a later
+	// date is given. Dates are days of the zone of the schedule, not the
+	// one of the host.
+	location := a.config.UpdateLocation
+
+	start := source.First()
+
+	lastYear, lastMonth, lastDay := source.Last().In(location).Date()
+	end := time.Date(lastYear, lastMonth, lastDay+1, 0, 0, 0, 0, location)
+
+	if from != "" {
+		fromDate, err := time.ParseInLocation(time.DateOnly, from, location)
+		if err != nil {
+			return errlib.Wrap(err, "could not parse first date")
+		}
+
+		if fromDate.After(start) {
+			start = fromDate
+		}
+	}
+
+	if to != "" {
+		toDate, err := time.ParseInLocation(time.DateOnly, to, location)
+		if err != nil {
+			return errlib.Wrap(err, "could not parse last date")
+		}
+
+		end = toDate.AddDate(0, 0, 1)
+	}
+
+	if !start.Before(end) {
+		return errors.New("replay period is empty: " + start.Format(time.RFC3339) + ".." + end.Format(time.RFC3339))
+	}
+
+	if err = a.database.Connect(); err != nil {
+		return errlib.Wrap(err, "could not connect to database")
+	}
+	defer func() { _ = a.database.Disconnect() }()
+
+	latestUpdateDatetime, err := a.service.UpdateDatetime.GetLatest()
+	if err != nil {
+		return errlib.Wrap(err, "could not get latest update datetime")
+	}
+
+	if latestUpdateDatetime.Id != 0 {
+		return errors.New("database already has updates, replay needs an empty one")
+	}
+
+	clk := clock.NewSimulated(start)
+
+	if a.timeChecks, err = timechecks.New(a.config, clk); err != nil {
+		return errlib.Wrap(err, "could not create time checks")
+	}
+
+	a.clock = clk
+	a.replay = source
+	a.replayEnd = end
+
+	log.Info().Int("payloads", source.Len()).
+		Msg("replay started: " + start.Format(time.RFC3339) + ".." + end.Format(time.RFC3339))
+
+	if err = a.workLoop(); err != nil {
+		return errlib.Wrap(err, "could not proceed work loop")
+	}
+
+	log.Info().Msg("replay finished at " + clk.Now().Format(time.RFC3339))
+
+	return nil
+}
+
+// importSnapshots stores the snapshots one by one, each in its own
+// transaction, and skips the dates, which already have one. An import,
+// which has failed, can be repeated, and continues where it stopped.
+func (a *App) importSnapshots(snapshots []models.DatedCurrencies, isDryRun bool) error {
+	first, last := snapshots[0].EffectiveDate, snapshots[len(snapshots)-1].EffectiveDate
+
+	if isDryRun {
+		log.Info().Int("snapshots", len(snapshots)).Msg("import data is valid: " + first + ".." + last)
+
+		return nil
+	}
+
+	var created, skipped int
+
+	for _, snapshot := range snapshots {
+		isCreated, err := a.service.Import.CreateSnapshot(snapshot)
+		if err != nil {
+			return errlib.Wrap(err, "could not import snapshot of "+snapshot.EffectiveDate)
+		}
+
+		if isCreated {
+			created++
+
+			log.Debug().Msg("snapshot imported: " + snapshot.EffectiveDate)
+		} else {
+			skipped++
+
+			log.Debug().Msg("snapshot already exists: " + snapshot.EffectiveDate)
+		}
+	}
+
+	log.Info().Int("created", created).Int("skipped", skipped).Msg("snapshots imported: " + first + ".." + last)
+
+	if created == 0 {
+		return nil
+	}
+
+	if err := a.service.AverageRates.RefreshAll(); err != nil {
+		return errlib.Wrap(err, "could not rebuild average rates, rebuild them with a command")
+	}
+
+	log.Info().Msg("average rates rebuilt")
+
+	return nil
+}
+
 func (a *App) workLoop() error {
 	var (
 		timeToNextUpdate time.Duration
+		isSourceFailed   bool
 		err              error
 	)
 
 	for {
-		if err = a.updateCurrencyDataInStorages(); err != nil {
+		if isSourceFailed, err = a.updateCurrencyDataInStorages(); err != nil {
 			return errlib.Wrap(err, "could not update currency data in storages")
 		}
 
-		timeToNextUpdate, err = a.timeChecks.TimeToNextUpdate()
-		if err != nil {
-			return errlib.Wrap(err, "could not get time to next update")
+		// replayed payloads are taken from an archive, which is not to be
+		// pruned on simulated time
+		if a.replay == nil {
+			a.pruneArchive()
+		}
+
+		timeToNextUpdate = a.timeChecks.TimeToNextUpdate()
+
+		// a failed update is tried again before the scheduled one, while
+		// the stored data is served
+		if isSourceFailed && (a.config.SourceRetryMaxDelay < timeToNextUpdate) {
+			timeToNextUpdate = a.config.SourceRetryMaxDelay
 		}
 
 		log.Info().Msg("next update will occur after " +
 			(timeToNextUpdate).Round(time.Second).String())
 
-		if err = a.calculateOutputData(); err != nil {
-			return errlib.Wrap(err, "could not calculate output data")
+		nextUpdateDatetime := a.clock.Now().Add(timeToNextUpdate)
+
+		// there is nothing to serve, until the first update succeeds
+		if updateDatetime := a.memCache.UpdateDatetime(); (updateDatetime != nil) && (updateDatetime.Id != 0) {
+			if err = a.calculateOutputData(nextUpdateDatetime); err != nil {
+				return errlib.Wrap(err, "could not calculate output data")
+			}
 		}
 
-		time.Sleep(timeToNextUpdate)
-	}
+		if (a.replay != nil) && !nextUpdateDatetime.Before(a.replayEnd) {
+			return nil
+		}
 
-	return nil
+		a.clock.Sleep(timeToNextUpdate)
+	}
 }
 
-func (a *App) updateCurrencyDataInStorages() error {
-	currentDatetime := time.Now().Format(time.RFC3339)
+// updateCurrencyDataInStorages stores the data of the source, if the
+// stored one is outdated, and caches the latest stored data. Failures of
+// the source are reported with isSourceFailed, not as errors, so the
+// stored data keeps being served, while the source is unavailable.
+func (a *App) updateCurrencyDataInStorages() (isSourceFailed bool, err error) {
+	currentDatetime := a.clock.Now().Format(time.RFC3339)
 
 	var (
-		latestUpdateDatetime models.UpdateDatetime
-		latestCurrencies     models.Currencies
-		isNeedUpdate         bool
-		err                  error
+		latestUpdateDatetime   models.UpdateDatetime
+		previousUpdateDatetime models.UpdateDatetime
+		latestCurrencies       models.Currencies
+		previousCurrencies     models.Currencies
+		latestEffectiveDate    string
+		payloadId              int
+		isNeedUpdate           bool
 	)
 
 	log.Info().Msg("checking latest update datetime...")
 
 	latestUpdateDatetime, err = a.service.UpdateDatetime.GetLatest()
 	if err != nil {
-		return errlib.Wrap(err, "could not get current update datetime")
+		return false, errlib.Wrap(err, "could not get current update datetime")
 	}
 
 	isNeedUpdate, err = a.timeChecks.IsNeedForUpdateDb(&latestUpdateDatetime)
 	if err != nil {
-		return errlib.Wrap(err, "could not check is need update for db or not")
+		return false, errlib.Wrap(err, "could not check is need update for db or not")
 	}
 
 	if isNeedUpdate {
 		log.Info().Msg("data is outdated")
 		log.Info().Msg("initializing update process...")
 
-		if latestCurrencies, err = a.parsedDataFromSource(); err != nil {
-			return errlib.Wrap(err, "could not get parsed data from source")
+		latestCurrencies, payloadId, err = a.parsedDataFromSource()
+
+		switch {
+		case errors.Is(err, sourceclient.ErrNotModified):
+			log.Info().Msg("data from source is not modified since last request, stored data is kept")
+
+			isNeedUpdate = false
+		case err != nil:
+			log.Error().Err(err).Msg("could not get parsed data from source, stored data is kept")
+
+			isNeedUpdate = false
+			isSourceFailed = true
+		default:
+			if isNeedUpdate, err = a.isValidSnapshot(latestCurrencies, latestUpdateDatetime); err != nil {
+				return false, errlib.Wrap(err, "could not validate data from source")
+			}
 		}
+	}
 
+	if isNeedUpdate {
 		log.Info().Msg("saving data...")
 
-		latestUpdateDatetime, err = a.service.UpdateDatetime.Create(currentDatetime)
+		if latestEffectiveDate, err = effectiveDate(latestCurrencies.Date, a.clock.Now().In(a.config.UpdateLocation)); err != nil {
+			return false, errlib.Wrap(err, "could not get effective date of data")
+		}
+
+		latestUpdateDatetime, err = a.service.UpdateDatetime.Create(currentDatetime, latestEffectiveDate,
+			latestCurrencies.Name)
 		if err != nil {
-			return errlib.Wrap(err, "could not insert datetime into db")
+			return false, errlib.Wrap(err, "could not insert datetime into db")
 		}
 
 		err = a.service.Currencies.Create(latestCurrencies, latestUpdateDatetime.Id)
 		if err != nil {
-			return errlib.Wrap(err, "could not insert currencies into db")
+			return false, errlib.Wrap(err, "could not insert currencies into db")
 		}
+
+		if payloadId != 0 {
+			if err = a.service.Payloads.SetUpdateDatetime(payloadId, latestUpdateDatetime.Id); err != nil {
+				log.Error().Err(err).Msg("could not link archived payload to update")
+			}
+		}
+
+		// averages are derived data, which can be rebuilt with a command,
+		// so a failure here must not stop the service
+		if err = a.service.AverageRates.Refresh(latestEffectiveDate); err != nil {
+			log.Error().Err(err).Msg("could not refresh average rates")
+		}
+	}
+
+	if latestUpdateDatetime.Id == 0 {
+		log.Warn().Msg("there is no stored data to serve, update will be retried")
+
+		return isSourceFailed, nil
 	}
 
 	latestCurrencies, err = a.service.Currencies.GetLatest(latestUpdateDatetime.Id)
 	if err != nil {
-		return errlib.Wrap(err, "could not get currencies from db")
+		return false, errlib.Wrap(err, "could not get currencies from db")
+	}
+
+	previousUpdateDatetime, err = a.service.UpdateDatetime.GetPrevious(latestUpdateDatetime.Id)
+	if err != nil {
+		return false, errlib.Wrap(err, "could not get previous update datetime")
+	}
+
+	if previousUpdateDatetime.Id != 0 {
+		previousCurrencies, err = a.service.Currencies.GetLatest(previousUpdateDatetime.Id)
+		if err != nil {
+			return false, errlib.Wrap(err, "could not get previous currencies from db")
+		}
 	}
 
 	a.memCache.SetUpdateDatetime(&latestUpdateDatetime)
 	a.memCache.SetCurrencies(&latestCurrencies)
+	a.memCache.SetPreviousCurrencies(&previousCurrencies)
 
-	log.Info().Msg("data is now up to date")
+	if isSourceFailed {
+		log.Warn().Msg("data is outdated, update will be retried")
+	} else {
+		log.Info().Msg("data is now up to date")
+	}
 
-	return nil
+	return isSourceFailed, nil
+}
+
+// isValidSnapshot validates the snapshot from the source against the
+// latest stored one. A rejected snapshot is reported and not stored, and
+// the stored data keeps being served, until the next update.
+func (a *App) isValidSnapshot(currencies models.Currencies, latestUpdateDatetime models.UpdateDatetime) (bool, error) {
+	var (
+		storedCurrencies models.Currencies
+		err              error
+	)
+
+	if latestUpdateDatetime.Id != 0 {
+		storedCurrencies, err = a.service.Currencies.GetLatest(latestUpdateDatetime.Id)
+		if err != nil {
+			return false, errlib.Wrap(err, "could not get stored currencies from db")
+		}
+	}
+
+	err = a.validator.Validate(currencies, storedCurrencies)
+	if err == nil {
+		return true, nil
+	}
+
+	var report *validator.Report
+
+	if !errors.As(err, &report) {
+		return false, err
+	}
+
+	for _, problem := range report.Problems {
+		log.Error().Str("code", problem.Code).Msg(problem.Message)
+	}
+
+	log.Error().Int("problems", len(report.Problems)).
+		Msg("data from source is rejected, stored data is kept")
+
+	return false, nil
 }
 
-func (a *App) parsedDataFromSource() (models.Currencies, error) {
+// parsedDataFromSource returns the currencies of the daily feed and the
+// id of its archived payload, which is zero, if it has not been archived.
+func (a *App) parsedDataFromSource() (models.Currencies, int, error) {
 	var (
 		currencies   models.Currencies
 		currencyData []byte
+		payloadId    int
 		err          error
 	)
 
 	log.Info().Msg("getting new data...")
 
-	if a.config.IsReadCurrencyDataFromFile {
+	switch {
+	case a.replay != nil:
+		log.Debug().Msg("getting data from replay...")
+
+		var fetchedAt time.Time
+
+		if currencyData, fetchedAt, err = a.replay.Payload(a.clock.Now()); err != nil {
+			return currencies, 0, errlib.Wrap(err, "could not get currencies from replay")
+		}
+
+		log.Info().Msg("replaying payload fetched at " + fetchedAt.Format(time.RFC3339))
+	case a.config.IsReadCurrencyDataFromFile:
 		log.Debug().Msg("getting data from local file...")
 
 		if currencyData, err = a.fsOps.CurrencyData(); err != nil {
-			return currencies, errlib.Wrap(err, "could not get currencies from file")
+			return currencies, 0, errlib.Wrap(err, "could not get currencies from file")
 		}
-	} else {
+	default:
 		log.Debug().Msg("getting data from web...")
 
-		if currencyData, err = a.endpoint.CurrenciesFromSource.CurrenciesFromSource(); err != nil {
-			return currencies, errlib.Wrap(err, "could not get curencies from web")
+		resp, err := a.endpoint.CurrenciesFromSource.CurrenciesFromSource()
+		if err != nil {
+			return currencies, 0, errlib.Wrap(err, "could not get curencies from web")
 		}
-	}
 
-	if err = replaceCommasWithDots(currencyData); err != nil {
-		return currencies, errlib.Wrap(err, "could not replace commas in data")
+		payloadId = a.archivePayload(resp)
+		currencyData = resp.Body
 	}
 
 	log.Info().Msg("parsing data...")
 
 	if currencies, err = a.xmlParser.Parse(currencyData); err != nil {
-		return currencies, errlib.Wrap(err, "could not parse data")
+		return currencies, payloadId, errlib.Wrap(err, "could not parse data")
 	}
 
-	return currencies, nil
+	return currencies, payloadId, nil
 }
 
-func replaceCommasWithDots(data []byte) error {
-	const (
-		startDataIndex = 100
-		charComma      = ','
-		charDot        = '.'
-	)
+// archivePayload archives the response of the source and returns the id
+// of the payload, or zero, if archiving is disabled. A failure to archive
+// is reported, but does not stop the data from being used.
+func (a *App) archivePayload(resp *sourceclient.Response) int {
+	if !a.config.IsArchiveEnabled {
+		return 0
+	}
 
-	if data == nil {
-		return errors.New("data is empty")
+	payload, err := a.service.Payloads.Create(resp.Url, resp.Header, resp.Body, resp.FetchedAt)
+	if err != nil {
+		log.Error().Err(err).Msg("could not archive payload of " + resp.Url)
+
+		return 0
 	}
 
-	lengthOfData := len(data)
+	log.Debug().Str("sha256", payload.Sha256).Msg("payload archived: " + payload.Key)
 
-	for i := startDataIndex; i < lengthOfData; i++ {
-		if data[i] == charComma {
-			data[i] = charDot
-		}
+	return payload.Id
+}
+
+// pruneArchive removes the payloads, which are older than the retention
+// period. Archive is not needed for serving, so failures are only
+// reported.
+func (a *App) pruneArchive() {
+	if !a.config.IsArchiveEnabled {
+		return
 	}
 
-	return nil
+	count, err := a.service.Payloads.Prune(a.config.ArchiveRetention)
+	if err != nil {
+		log.Error().Err(err).Msg("could not prune archive")
+	}
+
+	if count > 0 {
+		log.Info().Int("payloads", count).Msg("archive pruned")
+	}
 }
 
-func (a *App) calculateOutputData() error {
+func (a *App) calculateOutputData(nextUpdateDatetime time.Time) error {
 	currencies := a.memCache.Currencies()
+	previousCurrencies := a.memCache.PreviousCurrencies()
+	latestUpdateDatetime := a.memCache.UpdateDatetime()
 
-	calculatedCurrencies := make(
-		[]models.CalculatedCurrency,
-		0,
-		len(currencies.Currencies),
-	)
-
-	var (
-		calculatedCurrency models.CalculatedCurrency
-		ratio              string
-		err                error
-	)
+	updateDatetime, err := time.Parse(time.RFC3339, latestUpdateDatetime.UpdateDatetime)
+	if err != nil {
+		return errlib.Wrap(err, "could not parse update datetime")
+	}
 
 	log.Info().Msg("calculate output data...")
 
-	for _, currency := range currencies.Currencies {
-		ratio, err = calculateRatio(currency.Value, currency.Multiplier)
-		if err != nil {
-			return errlib.Wrap(err, "could not calculate currency rate")
-		}
+	version, err := service.SnapshotVersion(*latestUpdateDatetime)
+	if err != nil {
+		return errlib.Wrap(err, "could not get snapshot version")
+	}
+
+	info := models.SnapshotInfo{
+		Version:         ratesVersion,
+		SnapshotVersion: version,
+		EffectiveDate:   latestUpdateDatetime.EffectiveDate,
+		FetchedAt:       updateDatetime.Format(time.RFC3339),
+		Source:          a.sourceName(),
+	}
 
-		calculatedCurrency.Name = currency.Name
-		calculatedCurrency.CharCode = currency.CharCode
-		calculatedCurrency.Ratio = ratio
+	rates, err := a.calculator.Rates(info, *currencies, *previousCurrencies, a.config.DefaultBaseCurrency)
+	if err != nil {
+		return errlib.Wrap(err, "could not calculate currency rates")
+	}
 
-		calculatedCurrencies = append(calculatedCurrencies, calculatedCurrency)
+	view, err := memcache.NewView(rates)
+	if err != nil {
+		return errlib.Wrap(err, "could not prepare output data")
 	}
 
-	a.memCache.SetCalculatedCurrencies(calculatedCurrencies)
+	// the data is due to be replaced by the first scheduled update after it
+	// was stored, however many attempts have failed since
+	a.memCache.SetSnapshot(memcache.NewSnapshot(
+		version,
+		updateDatetime,
+		a.timeChecks.NextUpdateDatetime(updateDatetime),
+		nextUpdateDatetime,
+		info,
+		*currencies,
+		*previousCurrencies,
+		view,
+	))
 
 	return nil
 }
 
-func calculateRatio(currencyValue string, currencyMultiplier int) (string, error) {
-	const (
-		floatBitSize   = 64
-		floatFormat    = 'f'
-		floatPrecision = -1
-	)
+func (a *App) sourceName() string {
+	if a.replay != nil {
+		return "replay:" + a.replay.Dir()
+	}
 
-	var (
-		value      float64
-		multiplier float64
-		result     float64
-		output     string
-		err        error
-	)
+	if a.config.IsReadCurrencyDataFromFile {
+		return "file:" + a.config.CurrencySourceFile
+	}
 
-	value, err = strconv.ParseFloat(currencyValue, floatBitSize)
-	if err != nil {
-		return output, errlib.Wrap(err, "could not parse string to float")
+	return a.config.CurrencySourceUrl
+}
+
+// importError logs every issue of invalid import data before the error
+// is reported.
+func importError(err error) error {
+	var validationErr *importer.ValidationError
+
+	if errors.As(err, &validationErr) {
+		for _, issue := range validationErr.Issues {
+			log.Error().Msg(issue.String())
+		}
+
+		return errors.New("import data is invalid, nothing is imported")
 	}
 
-	multiplier = float64(currencyMultiplier)
+	return errlib.Wrap(err, "could not read import data")
+}
 
-	result = 1 / (value / multiplier)
+// effectiveDate converts the date of the source feed to the ISO format.
+// Feeds without a date are considered to be effective on the date of now,
+// which is given in the zone of the update schedule.
+func effectiveDate(feedDate string, now time.Time) (string, error) {
+	if feedDate == "" {
+		return now.Format(time.DateOnly), nil
+	}
 
-	output = strconv.FormatFloat(
-		result,
-		floatFormat,
-		floatPrecision,
-		floatBitSize,
-	)
+	date, err := time.Parse(feedDateLayout, feedDate)
+	if err != nil {
+		return "", errlib.Wrap(err, "could not parse feed date")
+	}
 
-	return output, nil
+	return date.Format(time.DateOnly), nil
 }
@@ -0,0 +1,117 @@
+package apperrors
+
+import (
+	"net/http"
+)
+
+// Stable codes of the domain errors. Clients rely on them, so they must
+// never be changed.
+const (
+	CodeUnknownCurrency     = "unknown_currency"
+	CodeNoDataForDate       = "no_data_for_date"
+	CodeUpstreamUnavailable = "upstream_unavailable"
+	CodeStaleData           = "stale_data"
+	CodeInvalidRequest      = "invalid_request"
+)
+
+// Sentinel errors to be matched with errors.Is.
+var (
+	ErrUnknownCurrency = &Error{
+		Code:   CodeUnknownCurrency,
+		Status: http.StatusNotFound,
+		Title:  "Unknown currency",
+	}
+	ErrNoDataForDate = &Error{
+		Code:   CodeNoDataForDate,
+		Status: http.StatusNotFound,
+		Title:  "No data for the date",
+	}
+	ErrUpstreamUnavailable = &Error{
+		Code:   CodeUpstreamUnavailable,
+		Status: http.StatusServiceUnavailable,
+		Title:  "Currency source is unavailable",
+	}
+	ErrStaleData = &Error{
+		Code:   CodeStaleData,
+		Status: http.StatusServiceUnavailable,
+		Title:  "Currency data is stale",
+	}
+	ErrInvalidRequest = &Error{
+		Code:   CodeInvalidRequest,
+		Status: http.StatusBadRequest,
+		Title:  "Invalid request",
+	}
+)
+
+// An Error is a domain error with a stable code and the HTTP status it
+// is reported with.
+type Error struct {
+	Code   string
+	Status int
+	Title  string
+	Detail string
+	Err    error
+}
+
+func (e *Error) Error() string {
+	msg := e.Title
+
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports errors with the same code as equal, so details do not
+// matter for errors.Is.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+
+	return ok && (t.Code == e.Code)
+}
+
+// UnknownCurrency is returned for codes, that are not in the snapshot.
+func UnknownCurrency(code string) error {
+	return ErrUnknownCurrency.with("currency code "+code+" is not known", nil)
+}
+
+// NoDataForDate is returned, when there is no snapshot for the date.
+func NoDataForDate(date string) error {
+	return ErrNoDataForDate.with("there are no rates for "+date, nil)
+}
+
+// UpstreamUnavailable is returned, when the data can not be obtained
+// from the source.
+func UpstreamUnavailable(detail string, err error) error {
+	return ErrUpstreamUnavailable.with(detail, err)
+}
+
+// StaleData is returned, when the scheduled update did not happen.
+func StaleData(detail string) error {
+	return ErrStaleData.with(detail, nil)
+}
+
+// InvalidRequest is returned for malformed parts of requests, which are
+// not caught by the request validation.
+func InvalidRequest(detail string) error {
+	return ErrInvalidRequest.with(detail, nil)
+}
+
+func (e *Error) with(detail string, err error) *Error {
+	return &Error{
+		Code:   e.Code,
+		Status: e.Status,
+		Title:  e.Title,
+		Detail: detail,
+		Err:    err,
+	}
+}
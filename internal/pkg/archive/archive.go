@@ -0,0 +1,262 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+	"github.com/mrumyantsev/go-errlib"
+)
+
+const (
+	payloadExtension = ".xml"
+	gzipExtension    = ".gz"
+
+	reportsPrefix = "reports/"
+	dateLayout    = "2006/01/02"
+	timeLayout    = "150405"
+
+	// hashPrefixLength is the part of the hash in keys, which keeps keys of
+	// payloads, fetched within a second, apart
+	hashPrefixLength = 12
+)
+
+// An Archive keeps payloads of the source as they were received, and
+// exported reports, by dates in a backend.
+type Archive struct {
+	config  *config.Config
+	backend Backend
+}
+
+// New returns an archive in the configured backend.
+func New(cfg *config.Config) (*Archive, error) {
+	var backend Backend
+
+	switch cfg.ArchiveBackend {
+	case BackendLocal:
+		backend = NewLocalBackend(cfg.ArchiveDir)
+	case BackendMemory:
+		backend = NewMemoryBackend()
+	case BackendS3:
+		s3, err := NewS3Backend(cfg)
+		if err != nil {
+			return nil, errlib.Wrap(err, "could not create s3 backend")
+		}
+
+		backend = s3
+	default:
+		return nil, errors.New("unknown archive backend: " + cfg.ArchiveBackend)
+	}
+
+	return &Archive{
+		config:  cfg,
+		backend: backend,
+	}, nil
+}
+
+// Write stores the data of the payload, fetched from the URL at the given
+// time, and returns the payload with its hash, size and key.
+func (a *Archive) Write(rawUrl string, header map[string][]string, data []byte, fetchedAt time.Time) (models.Payload, error) {
+	sum := sha256.Sum256(data)
+
+	payload := models.Payload{
+		Url:          rawUrl,
+		FetchedAt:    fetchedAt.Format(time.RFC3339),
+		Sha256:       hex.EncodeToString(sum[:]),
+		Size:         len(data),
+		Header:       header,
+		IsCompressed: a.config.IsArchiveCompressed,
+	}
+
+	// keys are in UTC, so archives can be read on hosts in other zones
+	keyTime := fetchedAt.UTC()
+
+	payload.Key = path.Join(
+		keyTime.Format(dateLayout),
+		keyTime.Format(timeLayout)+"-"+PayloadName(rawUrl)+"-"+payload.Sha256[:hashPrefixLength]+payloadExtension,
+	)
+
+	content := data
+
+	if payload.IsCompressed {
+		payload.Key += gzipExtension
+
+		var err error
+
+		if content, err = compress(data); err != nil {
+			return payload, errlib.Wrap(err, "could not compress payload")
+		}
+	}
+
+	if err := a.backend.Put(payload.Key, bytes.NewReader(content)); err != nil {
+		return payload, errlib.Wrap(err, "could not write payload to archive")
+	}
+
+	return payload, nil
+}
+
+// Read returns the data of the payload as it was received.
+func (a *Archive) Read(payload models.Payload) ([]byte, error) {
+	object, err := a.backend.Get(payload.Key)
+	if err != nil {
+		return nil, errlib.Wrap(err, "could not read payload from archive")
+	}
+	defer func() { _ = object.Close() }()
+
+	reader := io.Reader(object)
+
+	if payload.IsCompressed {
+		if reader, err = gzip.NewReader(object); err != nil {
+			return nil, errlib.Wrap(err, "could not decompress payload")
+		}
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, errlib.Wrap(err, "could not read payload from archive")
+	}
+
+	return data, nil
+}
+
+// Verify reads the payload and reports, whether its data still has the
+// hash it was archived with.
+func (a *Archive) Verify(payload models.Payload) (bool, error) {
+	data, err := a.Read(payload)
+	if err != nil {
+		return false, err
+	}
+
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:]) == payload.Sha256, nil
+}
+
+// Remove deletes the data of the payload. A payload, which data is already
+// missing, is removed without an error.
+func (a *Archive) Remove(payload models.Payload) error {
+	if err := a.backend.Delete(payload.Key); err != nil {
+		return errlib.Wrap(err, "could not remove payload from archive")
+	}
+
+	return nil
+}
+
+// WriteReport stores the report under its name among the reports of the
+// date and returns its key.
+func (a *Archive) WriteReport(name string, content io.ReadSeeker, createdAt time.Time) (string, error) {
+	key := reportsPrefix + path.Join(createdAt.Format(dateLayout), name)
+
+	if err := a.backend.Put(key, content); err != nil {
+		return key, errlib.Wrap(err, "could not write report to archive")
+	}
+
+	return key, nil
+}
+
+// A KeyInfo is what the key of a payload tells about it.
+type KeyInfo struct {
+	FetchedAt    time.Time
+	Name         string
+	IsCompressed bool
+}
+
+// ParseKey parses the key of a payload. Keys keep the fetch time to the
+// second in UTC.
+func ParseKey(key string) (KeyInfo, error) {
+	var info KeyInfo
+
+	base := path.Base(key)
+
+	if strings.HasSuffix(base, gzipExtension) {
+		info.IsCompressed = true
+		base = strings.TrimSuffix(base, gzipExtension)
+	}
+
+	if !strings.HasSuffix(base, payloadExtension) {
+		return info, errors.New("not a payload key: " + key)
+	}
+
+	base = strings.TrimSuffix(base, payloadExtension)
+
+	// the base is time, name and hash, joined by dashes, and the name may
+	// have dashes of its own
+	if len(base) < len(timeLayout)+hashPrefixLength+3 {
+		return info, errors.New("not a payload key: " + key)
+	}
+
+	fetchedAt, err := time.ParseInLocation(
+		dateLayout+" "+timeLayout,
+		path.Dir(key)+" "+base[:len(timeLayout)],
+		time.UTC,
+	)
+	if err != nil {
+		return info, errlib.Wrap(err, "could not parse fetch time of payload key "+key)
+	}
+
+	info.FetchedAt = fetchedAt
+	info.Name = base[len(timeLayout)+1 : len(base)-hashPrefixLength-1]
+
+	return info, nil
+}
+
+// PayloadName names a payload after the script of the URL and its query,
+// so payloads of different requests are told apart by names.
+func PayloadName(rawUrl string) string {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return "payload"
+	}
+
+	name := strings.TrimSuffix(path.Base(u.Path), path.Ext(u.Path))
+
+	query := u.Query()
+
+	keys := make([]string, 0, len(query))
+
+	for key := range query {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		for _, value := range query[key] {
+			name += "-" + value
+		}
+	}
+
+	return strings.Map(func(c rune) rune {
+		if ((c >= 'a') && (c <= 'z')) || ((c >= 'A') && (c <= 'Z')) || ((c >= '0') && (c <= '9')) || (c == '_') {
+			return c
+		}
+
+		return '-'
+	}, name)
+}
+
+func compress(data []byte) ([]byte, error) {
+	var buffer bytes.Buffer
+
+	writer := gzip.NewWriter(&buffer)
+
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
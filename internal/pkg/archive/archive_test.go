@@ -0,0 +1,73 @@
+package archive
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+)
+
+func TestWriteKey(t *testing.T) {
+	const rawUrl = "https://www.cbr.ru/scripts/XML_daily.asp"
+
+	tests := []struct {
+		name       string
+		zone       string
+		fetchedAt  string
+		compressed bool
+		want       string
+	}{
+		{"utc", "UTC", "2024-01-02 13:30:00", false, "2024/01/02/133000"},
+		{"east of utc on previous day", "Europe/Moscow", "2024-01-02 01:30:00", false, "2024/01/01/223000"},
+		{"west of utc on next day", "America/New_York", "2023-12-31 22:00:05", true, "2024/01/01/030005"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			location, err := time.LoadLocation(tt.zone)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			fetchedAt, err := time.ParseInLocation(time.DateTime, tt.fetchedAt, location)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			archive, err := New(&config.Config{
+				ArchiveBackend:      BackendMemory,
+				IsArchiveCompressed: tt.compressed,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			payload, err := archive.Write(rawUrl, nil, []byte("<ValCurs/>"), fetchedAt)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if prefix := payload.Key[:len(tt.want)]; prefix != tt.want {
+				t.Fatalf("key %s starts with %s, want %s", payload.Key, prefix, tt.want)
+			}
+
+			// keys are read back to the same moment on a host in any zone
+			info, err := ParseKey(payload.Key)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !info.FetchedAt.Equal(fetchedAt) {
+				t.Fatalf("fetched at %s, want %s", info.FetchedAt, fetchedAt)
+			}
+
+			if info.Name != "XML_daily" {
+				t.Fatalf("name = %s, want XML_daily", info.Name)
+			}
+
+			if info.IsCompressed != tt.compressed {
+				t.Fatalf("compressed = %t, want %t", info.IsCompressed, tt.compressed)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package archive
+
+import (
+	"errors"
+	"io"
+)
+
+// Names of the backends.
+const (
+	BackendLocal  = "local"
+	BackendS3     = "s3"
+	BackendMemory = "memory"
+)
+
+// ErrNotFound is returned by backends for keys, which have no objects.
+var ErrNotFound = errors.New("archive object is not found")
+
+// A Backend stores objects by keys, which are slash separated paths. An
+// object is either stored whole or not stored at all.
+type Backend interface {
+	// Put stores the content under the key, replacing any object, which
+	// is stored there.
+	Put(key string, content io.ReadSeeker) error
+	// Get returns the content of the object, or ErrNotFound.
+	Get(key string) (io.ReadCloser, error)
+	// Delete removes the object. Missing objects are not an error.
+	Delete(key string) error
+	// List returns the sorted keys of the objects, which start with the
+	// prefix.
+	List(prefix string) ([]string, error)
+}
@@ -0,0 +1,157 @@
+package archive
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// testBackend checks the contract of a backend, which is empty.
+func testBackend(t *testing.T, backend Backend) {
+	t.Helper()
+
+	objects := map[string]string{
+		"2024/01/02/133000-XML_daily.asp-0123456789ab.xml":    "first",
+		"2024/01/02/133001-XML_daily.asp-ba9876543210.xml.gz": "second",
+		"2024/01/10/133000-XML_daily.asp-0123456789ab.xml":    "third",
+		"reports/2024/01/10/120000-export.csv":                "report",
+	}
+
+	keys, err := backend.List("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(keys) != 0 {
+		t.Fatalf("empty backend lists %v", keys)
+	}
+
+	for key, content := range objects {
+		if err = backend.Put(key, bytes.NewReader([]byte(content))); err != nil {
+			t.Fatalf("put %s: %v", key, err)
+		}
+	}
+
+	for key, content := range objects {
+		if got := readObject(t, backend, key); got != content {
+			t.Fatalf("get %s = %q, want %q", key, got, content)
+		}
+	}
+
+	key := "2024/01/02/133000-XML_daily.asp-0123456789ab.xml"
+
+	if err = backend.Put(key, bytes.NewReader([]byte("replaced"))); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := readObject(t, backend, key); got != "replaced" {
+		t.Fatalf("replaced object = %q, want replaced", got)
+	}
+
+	listTests := []struct {
+		prefix string
+		want   []string
+	}{
+		{"", []string{
+			"2024/01/02/133000-XML_daily.asp-0123456789ab.xml",
+			"2024/01/02/133001-XML_daily.asp-ba9876543210.xml.gz",
+			"2024/01/10/133000-XML_daily.asp-0123456789ab.xml",
+			"reports/2024/01/10/120000-export.csv",
+		}},
+		{"2024/01/02/", []string{
+			"2024/01/02/133000-XML_daily.asp-0123456789ab.xml",
+			"2024/01/02/133001-XML_daily.asp-ba9876543210.xml.gz",
+		}},
+		{"reports/", []string{"reports/2024/01/10/120000-export.csv"}},
+		{"2025/", nil},
+	}
+
+	for _, tt := range listTests {
+		keys, err = backend.List(tt.prefix)
+		if err != nil {
+			t.Fatalf("list %q: %v", tt.prefix, err)
+		}
+
+		if (len(keys) != 0 || len(tt.want) != 0) && !reflect.DeepEqual(keys, tt.want) {
+			t.Fatalf("list %q = %v, want %v", tt.prefix, keys, tt.want)
+		}
+	}
+
+	if _, err = backend.Get("2024/01/03/missing.xml"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("get of missing object error = %v, want ErrNotFound", err)
+	}
+
+	if err = backend.Delete(key); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = backend.Get(key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("get of deleted object error = %v, want ErrNotFound", err)
+	}
+
+	if err = backend.Delete(key); err != nil {
+		t.Fatalf("delete of missing object error = %v, want none", err)
+	}
+
+	if keys, err = backend.List("2024/01/02/"); (err != nil) || (len(keys) != 1) {
+		t.Fatalf("list after delete = %v, %v, want a single key", keys, err)
+	}
+}
+
+func readObject(t *testing.T, backend Backend, key string) string {
+	t.Helper()
+
+	object, err := backend.Get(key)
+	if err != nil {
+		t.Fatalf("get %s: %v", key, err)
+	}
+	defer func() { _ = object.Close() }()
+
+	data, err := io.ReadAll(object)
+	if err != nil {
+		t.Fatalf("read %s: %v", key, err)
+	}
+
+	return string(data)
+}
+
+func TestMemoryBackend(t *testing.T) {
+	testBackend(t, NewMemoryBackend())
+}
+
+func TestLocalBackend(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "archive")
+
+	backend := NewLocalBackend(root)
+
+	testBackend(t, backend)
+
+	// temporary files of objects, which are being written, are not listed
+	tmpFile := filepath.Join(root, "2024", "01", "10", tmpFilePrefix+"123")
+
+	if err := os.WriteFile(tmpFile, []byte("partial"), filePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := backend.List("2024/01/10/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(keys) != 1 {
+		t.Fatalf("list = %v, want a single key", keys)
+	}
+
+	// directories, which are left empty, are removed with the last object
+	if err = backend.Delete("reports/2024/01/10/120000-export.csv"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = os.Stat(filepath.Join(root, "reports")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("empty directories are left: %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package archive
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mrumyantsev/go-errlib"
+)
+
+const (
+	filePerm = 0644
+	dirPerm  = 0755
+
+	tmpFilePrefix = ".object-"
+)
+
+// A LocalBackend stores objects as files of the directory tree, so keys
+// are paths of the files relative to its root.
+type LocalBackend struct {
+	root string
+}
+
+func NewLocalBackend(root string) *LocalBackend {
+	return &LocalBackend{root: filepath.Clean(root)}
+}
+
+// Put writes the file through a temporary one, so a file, which is being
+// written, is never taken for a whole one.
+func (b *LocalBackend) Put(key string, content io.ReadSeeker) error {
+	filePath := b.filePath(key)
+
+	if err := os.MkdirAll(filepath.Dir(filePath), dirPerm); err != nil {
+		return errlib.Wrap(err, "could not make archive directory")
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), tmpFilePrefix+"*")
+	if err != nil {
+		return errlib.Wrap(err, "could not create temporary file")
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err = io.Copy(tmp, content); err != nil {
+		_ = tmp.Close()
+
+		return errlib.Wrap(err, "could not write temporary file")
+	}
+
+	if err = tmp.Sync(); err != nil {
+		_ = tmp.Close()
+
+		return errlib.Wrap(err, "could not sync temporary file")
+	}
+
+	if err = tmp.Close(); err != nil {
+		return errlib.Wrap(err, "could not close temporary file")
+	}
+
+	if err = os.Chmod(tmp.Name(), filePerm); err != nil {
+		return errlib.Wrap(err, "could not set permissions of temporary file")
+	}
+
+	if err = os.Rename(tmp.Name(), filePath); err != nil {
+		return errlib.Wrap(err, "could not rename temporary file")
+	}
+
+	return nil
+}
+
+func (b *LocalBackend) Get(key string) (io.ReadCloser, error) {
+	file, err := os.Open(b.filePath(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, errlib.Wrap(err, "could not open archive file")
+	}
+
+	return file, nil
+}
+
+// Delete removes the file and the directories of its path, which are left
+// empty.
+func (b *LocalBackend) Delete(key string) error {
+	filePath := b.filePath(key)
+
+	if err := os.Remove(filePath); (err != nil) && !errors.Is(err, os.ErrNotExist) {
+		return errlib.Wrap(err, "could not remove archive file")
+	}
+
+	for dir := filepath.Dir(filePath); dir != b.root; dir = filepath.Dir(dir) {
+		// directories, which are not empty, are not removed
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+
+	return nil
+}
+
+// List walks the directory tree. Temporary files of objects, which are
+// being written, are skipped, and a missing root is an empty archive.
+func (b *LocalBackend) List(prefix string) ([]string, error) {
+	var keys []string
+
+	err := filepath.WalkDir(b.root, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || strings.HasPrefix(d.Name(), tmpFilePrefix) {
+			return nil
+		}
+
+		key, err := filepath.Rel(b.root, filePath)
+		if err != nil {
+			return err
+		}
+
+		if key = filepath.ToSlash(key); strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+
+		return nil
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errlib.Wrap(err, "could not walk archive directory")
+	}
+
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+func (b *LocalBackend) filePath(key string) string {
+	return filepath.Join(b.root, filepath.FromSlash(key))
+}
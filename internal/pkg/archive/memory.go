@@ -0,0 +1,73 @@
+package archive
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mrumyantsev/go-errlib"
+)
+
+// A MemoryBackend keeps objects in memory of the process. It is meant for
+// development and trying out, since objects are lost on exit.
+type MemoryBackend struct {
+	mu      sync.RWMutex
+	objects map[string][]byte
+}
+
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{objects: make(map[string][]byte)}
+}
+
+func (b *MemoryBackend) Put(key string, content io.ReadSeeker) error {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return errlib.Wrap(err, "could not read object content")
+	}
+
+	b.mu.Lock()
+	b.objects[key] = data
+	b.mu.Unlock()
+
+	return nil
+}
+
+func (b *MemoryBackend) Get(key string) (io.ReadCloser, error) {
+	b.mu.RLock()
+	data, ok := b.objects[key]
+	b.mu.RUnlock()
+
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (b *MemoryBackend) Delete(key string) error {
+	b.mu.Lock()
+	delete(b.objects, key)
+	b.mu.Unlock()
+
+	return nil
+}
+
+func (b *MemoryBackend) List(prefix string) ([]string, error) {
+	var keys []string
+
+	b.mu.RLock()
+
+	for key := range b.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+
+	b.mu.RUnlock()
+
+	sort.Strings(keys)
+
+	return keys, nil
+}
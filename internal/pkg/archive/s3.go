@@ -0,0 +1,442 @@
+package archive
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+	"github.com/mrumyantsev/go-errlib"
+)
+
+const (
+	s3Service   = "s3"
+	s3Algorithm = "AWS4-HMAC-SHA256"
+	s3Request   = "aws4_request"
+
+	amzDateLayout  = "20060102T150405Z"
+	amzScopeLayout = "20060102"
+
+	headerAmzDate          = "X-Amz-Date"
+	headerAmzContentSha256 = "X-Amz-Content-Sha256"
+	headerAmzSecurityToken = "X-Amz-Security-Token"
+	headerAuthorization    = "Authorization"
+	headerContentType      = "Content-Type"
+
+	contentTypeOctetStream = "application/octet-stream"
+
+	// errorBodyLimit is the part of error responses, which is reported
+	errorBodyLimit = 512
+)
+
+// An S3Backend stores objects in a bucket of S3 or of a compatible
+// storage, such as MinIO. Requests are signed with AWS Signature
+// Version 4, and keys are put under the prefix.
+type S3Backend struct {
+	endpoint     *url.URL
+	region       string
+	bucket       string
+	prefix       string
+	accessKey    string
+	secretKey    string
+	sessionToken string
+	isPathStyle  bool
+	client       *http.Client
+}
+
+func NewS3Backend(cfg *config.Config) (*S3Backend, error) {
+	if cfg.ArchiveS3Bucket == "" {
+		return nil, errors.New("no s3 bucket of archive specified")
+	}
+
+	if (cfg.ArchiveS3AccessKey == "") || (cfg.ArchiveS3SecretKey == "") {
+		return nil, errors.New("both s3 access key and secret key of archive must be specified")
+	}
+
+	endpoint, err := url.Parse(cfg.ArchiveS3Endpoint)
+	if err != nil {
+		return nil, errlib.Wrap(err, "could not parse s3 endpoint of archive")
+	}
+
+	if (endpoint.Scheme != "http") && (endpoint.Scheme != "https") {
+		return nil, errors.New("s3 endpoint of archive must be an http or https url: " + cfg.ArchiveS3Endpoint)
+	}
+
+	return &S3Backend{
+		endpoint:     endpoint,
+		region:       cfg.ArchiveS3Region,
+		bucket:       cfg.ArchiveS3Bucket,
+		prefix:       strings.Trim(cfg.ArchiveS3Prefix, "/"),
+		accessKey:    cfg.ArchiveS3AccessKey,
+		secretKey:    cfg.ArchiveS3SecretKey,
+		sessionToken: cfg.ArchiveS3SessionToken,
+		isPathStyle:  cfg.IsArchiveS3PathStyle,
+		client: &http.Client{
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+			},
+			Timeout: cfg.ArchiveS3Timeout,
+		},
+	}, nil
+}
+
+// Put uploads the content with a single request. The content is read
+// twice: for its hash, which the request is signed with, and to send it.
+func (b *S3Backend) Put(key string, content io.ReadSeeker) error {
+	hash := sha256.New()
+
+	size, err := io.Copy(hash, content)
+	if err != nil {
+		return errlib.Wrap(err, "could not read object content")
+	}
+
+	if _, err = content.Seek(0, io.SeekStart); err != nil {
+		return errlib.Wrap(err, "could not rewind object content")
+	}
+
+	// the body is not closed by the client, since the content is owned by
+	// the caller
+	req, err := http.NewRequest(http.MethodPut, b.objectUrl(key), io.NopCloser(content))
+	if err != nil {
+		return errlib.Wrap(err, "could not create s3 request")
+	}
+
+	req.ContentLength = size
+	req.Header.Set(headerContentType, contentTypeOctetStream)
+
+	resp, err := b.do(req, hex.EncodeToString(hash.Sum(nil)))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
+
+	return nil
+}
+
+func (b *S3Backend) Get(key string) (io.ReadCloser, error) {
+	req, err := http.NewRequest(http.MethodGet, b.objectUrl(key), nil)
+	if err != nil {
+		return nil, errlib.Wrap(err, "could not create s3 request")
+	}
+
+	resp, err := b.do(req, emptyPayloadHash)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		_ = resp.Body.Close()
+
+		return nil, ErrNotFound
+	default:
+		defer func() { _ = resp.Body.Close() }()
+
+		return nil, responseError(resp)
+	}
+}
+
+func (b *S3Backend) Delete(key string) error {
+	req, err := http.NewRequest(http.MethodDelete, b.objectUrl(key), nil)
+	if err != nil {
+		return errlib.Wrap(err, "could not create s3 request")
+	}
+
+	resp, err := b.do(req, emptyPayloadHash)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	default:
+		return responseError(resp)
+	}
+}
+
+// listBucketResult is the part of the response of ListObjectsV2, which is
+// needed to list keys.
+type listBucketResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List requests pages of keys with ListObjectsV2, until the storage tells,
+// that the list is complete.
+func (b *S3Backend) List(prefix string) ([]string, error) {
+	var (
+		keys              []string
+		continuationToken string
+		keyPrefix         = b.objectKey("")
+	)
+
+	for {
+		query := url.Values{}
+
+		query.Set("list-type", "2")
+		query.Set("prefix", b.objectKey(prefix))
+
+		if continuationToken != "" {
+			query.Set("continuation-token", continuationToken)
+		}
+
+		u := b.bucketUrl("")
+		u.RawQuery = query.Encode()
+
+		req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+		if err != nil {
+			return nil, errlib.Wrap(err, "could not create s3 request")
+		}
+
+		result, err := b.listPage(req)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, object := range result.Contents {
+			keys = append(keys, strings.TrimPrefix(object.Key, keyPrefix))
+		}
+
+		if !result.IsTruncated || (result.NextContinuationToken == "") {
+			break
+		}
+
+		continuationToken = result.NextContinuationToken
+	}
+
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+func (b *S3Backend) listPage(req *http.Request) (listBucketResult, error) {
+	var result listBucketResult
+
+	resp, err := b.do(req, emptyPayloadHash)
+	if err != nil {
+		return result, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return result, responseError(resp)
+	}
+
+	if err = xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return result, errlib.Wrap(err, "could not decode s3 list of objects")
+	}
+
+	return result, nil
+}
+
+func (b *S3Backend) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	// storages reject signatures, which are far from their time, so the
+	// request is signed on the wall clock
+	b.sign(req, payloadHash, time.Now())
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, errlib.Wrap(err, "could not send s3 request")
+	}
+
+	return resp, nil
+}
+
+// objectUrl returns the URL of the object.
+func (b *S3Backend) objectUrl(key string) string {
+	u := b.bucketUrl(b.objectKey(key))
+
+	return u.String()
+}
+
+// objectKey returns the key of the object in the bucket, which is under
+// the prefix.
+func (b *S3Backend) objectKey(key string) string {
+	if b.prefix == "" {
+		return key
+	}
+
+	return b.prefix + "/" + key
+}
+
+// bucketUrl returns the URL of the path in the bucket either with the
+// bucket in the path, as MinIO and other compatible storages expect by
+// default, or in the host name.
+func (b *S3Backend) bucketUrl(objectPath string) *url.URL {
+	u := *b.endpoint
+
+	if b.isPathStyle {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + b.bucket + "/" + objectPath
+	} else {
+		u.Host = b.bucket + "." + u.Host
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + objectPath
+	}
+
+	u.RawPath = s3Escape(u.Path, false)
+
+	return &u
+}
+
+// sign adds the date, the payload hash and the authorization headers of
+// Signature Version 4 to the request. All the headers, which are set on
+// the request by then, are signed.
+func (b *S3Backend) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.UTC().Format(amzDateLayout)
+	scopeDate := now.UTC().Format(amzScopeLayout)
+
+	req.Header.Set(headerAmzDate, amzDate)
+	req.Header.Set(headerAmzContentSha256, payloadHash)
+
+	if b.sessionToken != "" {
+		req.Header.Set(headerAmzSecurityToken, b.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+
+	for name, values := range req.Header {
+		trimmed := make([]string, len(values))
+
+		for i, value := range values {
+			trimmed[i] = strings.Join(strings.Fields(value), " ")
+		}
+
+		headers[strings.ToLower(name)] = strings.Join(trimmed, ",")
+	}
+
+	names := make([]string, 0, len(headers))
+
+	for name := range headers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s3Escape(req.URL.Path, false),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := scopeDate + "/" + b.region + "/" + s3Service + "/" + s3Request
+
+	stringToSign := strings.Join([]string{
+		s3Algorithm,
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSha256([]byte("AWS4"+b.secretKey), scopeDate)
+	key = hmacSha256(key, b.region)
+	key = hmacSha256(key, s3Service)
+	key = hmacSha256(key, s3Request)
+
+	signature := hex.EncodeToString(hmacSha256(key, stringToSign))
+
+	req.Header.Set(headerAuthorization, s3Algorithm+" Credential="+b.accessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// emptyPayloadHash is the hash of requests without a body.
+var emptyPayloadHash = sha256Hex(nil)
+
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+
+	for key := range query {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	var pairs []string
+
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+
+		sort.Strings(values)
+
+		for _, value := range values {
+			pairs = append(pairs, s3Escape(key, true)+"="+s3Escape(value, true))
+		}
+	}
+
+	return strings.Join(pairs, "&")
+}
+
+// s3Escape percent-encodes every byte of the string, but unreserved
+// characters, and slashes, unless they are to be encoded too.
+func s3Escape(s string, isEscapeSlash bool) string {
+	const hexDigits = "0123456789ABCDEF"
+
+	var builder strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		if ((c >= 'A') && (c <= 'Z')) || ((c >= 'a') && (c <= 'z')) || ((c >= '0') && (c <= '9')) ||
+			(c == '-') || (c == '_') || (c == '.') || (c == '~') || ((c == '/') && !isEscapeSlash) {
+			builder.WriteByte(c)
+
+			continue
+		}
+
+		builder.WriteByte('%')
+		builder.WriteByte(hexDigits[c>>4])
+		builder.WriteByte(hexDigits[c&0x0F])
+	}
+
+	return builder.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+
+	_, _ = mac.Write([]byte(data))
+
+	return mac.Sum(nil)
+}
+
+// responseError reports the status and the beginning of the body, which
+// has the code and the message of the error of the storage.
+func responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, errorBodyLimit))
+
+	return errors.New("s3 responded with status " + resp.Status + ": " + strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/clock"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+	ratelimiter "github.com/mrumyantsev/currency-converter-app/internal/pkg/rate-limiter"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/service"
+	"github.com/mrumyantsev/go-errlib"
+)
+
+const (
+	ContextKeyApiKey = "apiKey"
+
+	headerRateLimitLimit     = "X-RateLimit-Limit"
+	headerRateLimitRemaining = "X-RateLimit-Remaining"
+	headerRateLimitReset     = "X-RateLimit-Reset"
+	headerRetryAfter         = "Retry-After"
+
+	anonymousIdPrefix = "ip:"
+	lookupIdPrefix    = "lookup:"
+	apiKeyIdPrefix    = "key:"
+)
+
+// An Auth authenticates API clients by their keys and meters them.
+// Requests without a key fall into the anonymous tier, which is limited
+// per client IP address.
+type Auth struct {
+	config      *config.Config
+	service     service.ApiKeys
+	rateLimiter *ratelimiter.RateLimiter
+}
+
+func New(cfg *config.Config, svc service.ApiKeys, clk clock.Clock) *Auth {
+	return &Auth{
+		config:      cfg,
+		service:     svc,
+		rateLimiter: ratelimiter.New(cfg, clk),
+	}
+}
+
+// Middleware is an echo middleware, which rejects requests with unknown
+// or revoked keys and requests exceeding the client rate limit.
+func (a *Auth) Middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		var (
+			id     string
+			limits ratelimiter.Limits
+		)
+
+		key := ctx.Request().Header.Get(a.config.ApiKeyHeader)
+
+		if key == "" {
+			if !a.config.IsAllowAnonymousAccess {
+				return echo.NewHTTPError(http.StatusUnauthorized, "api key is required")
+			}
+
+			id = anonymousIdPrefix + ctx.RealIP()
+			limits = ratelimiter.Limits{
+				PerMinute: a.config.AnonymousRateLimit,
+				Burst:     a.config.AnonymousRateBurst,
+			}
+		} else {
+			apiKey, err := a.authenticate(ctx, key)
+			if err != nil {
+				return err
+			}
+
+			ctx.Set(ContextKeyApiKey, apiKey)
+
+			id = apiKeyIdPrefix + strconv.Itoa(apiKey.Id)
+			limits = ratelimiter.Limits{
+				PerMinute: apiKey.RateLimit,
+				Burst:     apiKey.RateBurst,
+			}
+		}
+
+		result := a.rateLimiter.Allow(id, limits)
+
+		setRateLimitHeaders(ctx, result)
+
+		if !result.Allowed {
+			return tooManyRequests(ctx, result)
+		}
+
+		return next(ctx)
+	}
+}
+
+// authenticate returns the API key of the request. Cached keys are taken
+// at once and are metered by their own limits only. Keys, which are not
+// cached, are looked up in the database, so the lookups are limited per
+// address before the key is known, and guessing keys costs as much as any
+// other request. Clients with valid keys are charged once per cache TTL.
+func (a *Auth) authenticate(ctx echo.Context, key string) (models.ApiKey, error) {
+	if apiKey, ok := a.service.GetCached(key); ok {
+		return apiKey, nil
+	}
+
+	lookup := a.rateLimiter.Allow(lookupIdPrefix+ctx.RealIP(), ratelimiter.Limits{
+		PerMinute: a.config.ApiKeyLookupRateLimit,
+		Burst:     a.config.ApiKeyLookupRateBurst,
+	})
+	if !lookup.Allowed {
+		return models.ApiKey{}, tooManyRequests(ctx, lookup)
+	}
+
+	apiKey, err := a.service.Authenticate(key)
+	if errors.Is(err, service.ErrInvalidApiKey) {
+		return apiKey, echo.NewHTTPError(http.StatusUnauthorized, "api key is invalid")
+	}
+	if err != nil {
+		return apiKey, errlib.Wrap(err, "could not authenticate api key")
+	}
+
+	return apiKey, nil
+}
+
+func setRateLimitHeaders(ctx echo.Context, result ratelimiter.Result) {
+	header := ctx.Response().Header()
+
+	header.Set(headerRateLimitLimit, strconv.Itoa(result.Limit))
+	header.Set(headerRateLimitRemaining, strconv.Itoa(result.Remaining))
+	header.Set(headerRateLimitReset, strconv.Itoa(ceilSeconds(result.Reset.Seconds())))
+}
+
+func tooManyRequests(ctx echo.Context, result ratelimiter.Result) error {
+	ctx.Response().Header().Set(headerRetryAfter, strconv.Itoa(ceilSeconds(result.RetryAfter.Seconds())))
+
+	return echo.NewHTTPError(http.StatusTooManyRequests, "rate limit exceeded")
+}
+
+func ceilSeconds(seconds float64) int {
+	return int(math.Ceil(seconds))
+}
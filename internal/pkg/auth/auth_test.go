@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/clock"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/service"
+)
+
+const testApiKeyHeader = "X-Api-Key"
+
+// fakeApiKeys caches keys, after they are authenticated, as the service
+// does, and counts lookups of the database.
+type fakeApiKeys struct {
+	keys    map[string]models.ApiKey
+	cached  map[string]bool
+	lookups int
+}
+
+func (f *fakeApiKeys) Create(string, int, int) (string, models.ApiKey, error) {
+	return "", models.ApiKey{}, nil
+}
+
+func (f *fakeApiKeys) Authenticate(key string) (models.ApiKey, error) {
+	if apiKey, ok := f.GetCached(key); ok {
+		return apiKey, nil
+	}
+
+	f.lookups++
+
+	apiKey, ok := f.keys[key]
+	if !ok {
+		return apiKey, service.ErrInvalidApiKey
+	}
+
+	f.cached[key] = true
+
+	return apiKey, nil
+}
+
+func (f *fakeApiKeys) GetCached(key string) (models.ApiKey, bool) {
+	if !f.cached[key] {
+		return models.ApiKey{}, false
+	}
+
+	return f.keys[key], true
+}
+
+func (f *fakeApiKeys) GetAll() ([]models.ApiKey, error) { return nil, nil }
+
+func (f *fakeApiKeys) Revoke(int) error { return nil }
+
+func newTestAuth() (*Auth, *fakeApiKeys) {
+	svc := &fakeApiKeys{
+		keys: map[string]models.ApiKey{
+			"fast":  {Id: 1, RateLimit: 60000, RateBurst: 100},
+			"other": {Id: 2, RateLimit: 60000, RateBurst: 100},
+			"slow":  {Id: 3, RateLimit: 60, RateBurst: 2},
+		},
+		cached: make(map[string]bool),
+	}
+
+	cfg := &config.Config{
+		ApiKeyHeader:          testApiKeyHeader,
+		ApiKeyLookupRateLimit: 60,
+		ApiKeyLookupRateBurst: 2,
+		RateLimiterExpiresIn:  time.Minute,
+	}
+
+	clk := clock.NewSimulated(time.Date(2024, time.March, 2, 12, 0, 0, 0, time.UTC))
+
+	return New(cfg, svc, clk), svc
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    []string
+		want    []int
+		lookups int
+	}{
+		{"cached key is not limited by lookups", repeat("fast", 50), repeatStatus(http.StatusOK, 50), 1},
+		{"keys behind one address", append(repeat("fast", 10), repeat("other", 10)...), repeatStatus(http.StatusOK, 20), 2},
+		{"own limit of key", repeat("slow", 3), []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}, 1},
+		{"invalid keys are limited", []string{"guess1", "guess2", "guess3"},
+			[]int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests}, 2},
+		{"lookup of valid key after guesses", []string{"guess1", "guess2", "fast"},
+			[]int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth, svc := newTestAuth()
+
+			e := echo.New()
+
+			handler := auth.Middleware(func(ctx echo.Context) error {
+				return ctx.NoContent(http.StatusOK)
+			})
+
+			for i, key := range tt.keys {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.Header.Set(testApiKeyHeader, key)
+
+				rec := httptest.NewRecorder()
+
+				status := http.StatusOK
+
+				if err := handler(e.NewContext(req, rec)); err != nil {
+					httpErr, ok := err.(*echo.HTTPError)
+					if !ok {
+						t.Fatalf("request %d: error = %v", i+1, err)
+					}
+
+					status = httpErr.Code
+				}
+
+				if status != tt.want[i] {
+					t.Fatalf("request %d with key %s: status = %d, want %d", i+1, key, status, tt.want[i])
+				}
+			}
+
+			if svc.lookups != tt.lookups {
+				t.Fatalf("got %d lookups, want %d", svc.lookups, tt.lookups)
+			}
+		})
+	}
+}
+
+func repeat(key string, n int) []string {
+	keys := make([]string, n)
+
+	for i := range keys {
+		keys[i] = key
+	}
+
+	return keys
+}
+
+func repeatStatus(status int, n int) []int {
+	statuses := make([]int, n)
+
+	for i := range statuses {
+		statuses[i] = status
+	}
+
+	return statuses
+}
@@ -0,0 +1,350 @@
+package calculator
+
+import (
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+
+	apperrors "github.com/mrumyantsev/currency-converter-app/internal/pkg/app-errors"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+	"github.com/mrumyantsev/go-errlib"
+)
+
+const (
+	// SourceCurrency is the currency all source rates are quoted in.
+	SourceCurrency = "RUB"
+
+	sourceCurrencyNumCode = 643
+	sourceCurrencyName    = "Российский рубль"
+
+	percentDecimalPlaces = 4
+)
+
+var (
+	hundred = big.NewRat(100, 1)
+	tenth   = big.NewRat(1, 10)
+	ten     = big.NewRat(10, 1)
+)
+
+// A Calculator derives output rates from source currency values. All the
+// arithmetic is done on exact fractions, rounding happens only on output.
+type Calculator struct {
+	config *config.Config
+}
+
+func New(cfg *config.Config) *Calculator {
+	return &Calculator{config: cfg}
+}
+
+// Rates computes the rates envelope for the given base currency, which is
+// looked up by its alphabetic or numeric code.
+func (c *Calculator) Rates(
+	info models.SnapshotInfo,
+	currencies models.Currencies,
+	previous models.Currencies,
+	base string,
+) (models.CurrencyRates, error) {
+	var err error
+
+	rates := models.CurrencyRates{SnapshotInfo: info}
+
+	rates.Base, rates.Currencies, err = c.Calculate(currencies, previous, base)
+
+	return rates, err
+}
+
+// Calculate computes rates of the currencies against the base currency.
+// Rates of other bases than the source currency are cross rates through
+// it, then the source currency is listed as well, and the base is not.
+// The previous currencies are used for day-over-day changes and may be
+// empty. The alphabetic code of the base is returned too.
+func (c *Calculator) Calculate(
+	currencies models.Currencies,
+	previous models.Currencies,
+	base string,
+) (string, []models.CalculatedCurrency, error) {
+	all := withSourceCurrency(currencies.Currencies)
+
+	baseCurrency, ok := FindCurrency(all, base)
+	if !ok {
+		return "", nil, apperrors.UnknownCurrency(base)
+	}
+
+	baseUnitRate, err := UnitRate(baseCurrency)
+	if err != nil {
+		return "", nil, errlib.Wrap(err, "could not calculate unit rate of base "+baseCurrency.CharCode)
+	}
+
+	previousUnitRates, err := crossUnitRates(previous.Currencies, baseCurrency.CharCode)
+	if err != nil {
+		return "", nil, errlib.Wrap(err, "could not calculate previous unit rates")
+	}
+
+	calculatedCurrencies := make(
+		[]models.CalculatedCurrency,
+		0,
+		len(all),
+	)
+
+	for _, currency := range all {
+		if currency.CharCode == baseCurrency.CharCode {
+			continue
+		}
+
+		unitRate, err := UnitRate(currency)
+		if err != nil {
+			return "", nil, errlib.Wrap(err, "could not calculate unit rate of "+currency.CharCode)
+		}
+
+		unitRate.Quo(unitRate, baseUnitRate)
+
+		value := new(big.Rat).Mul(unitRate, big.NewRat(int64(currency.Multiplier), 1))
+
+		calculatedCurrency := models.CalculatedCurrency{
+			NumCode:     currency.NumCode,
+			CharCode:    currency.CharCode,
+			Name:        currency.Name,
+			Nominal:     currency.Multiplier,
+			Value:       c.Format(value),
+			UnitRate:    c.Format(unitRate),
+			InverseRate: c.Format(new(big.Rat).Inv(unitRate)),
+		}
+
+		if previousUnitRate, ok := previousUnitRates[currency.CharCode]; ok {
+			calculatedCurrency.Change, calculatedCurrency.ChangePercent =
+				c.change(previousUnitRate, unitRate)
+		}
+
+		calculatedCurrencies = append(calculatedCurrencies, calculatedCurrency)
+	}
+
+	return baseCurrency.CharCode, calculatedCurrencies, nil
+}
+
+// Matrix computes cross rates between every pair of the currencies with
+// the given codes, or of all the currencies, if no codes are given. The
+// alphabetic codes of the currencies are returned in the same order.
+func (c *Calculator) Matrix(currencies models.Currencies, codes []string) ([]string, [][]string, error) {
+	all := withSourceCurrency(currencies.Currencies)
+
+	selected := all
+
+	if len(codes) > 0 {
+		selected = make([]models.Currency, 0, len(codes))
+
+		for _, code := range codes {
+			currency, ok := FindCurrency(all, code)
+			if !ok {
+				return nil, nil, apperrors.UnknownCurrency(code)
+			}
+
+			selected = append(selected, currency)
+		}
+	}
+
+	charCodes := make([]string, len(selected))
+	unitRates := make([]*big.Rat, len(selected))
+
+	for i, currency := range selected {
+		unitRate, err := UnitRate(currency)
+		if err != nil {
+			return nil, nil, errlib.Wrap(err, "could not calculate unit rate of "+currency.CharCode)
+		}
+
+		charCodes[i], unitRates[i] = currency.CharCode, unitRate
+	}
+
+	matrix := make([][]string, len(selected))
+
+	for i := range selected {
+		matrix[i] = make([]string, len(selected))
+
+		for j := range selected {
+			matrix[i][j] = c.Format(new(big.Rat).Quo(unitRates[i], unitRates[j]))
+		}
+	}
+
+	return charCodes, matrix, nil
+}
+
+// CrossRate returns the amount of units of the to currency per one unit
+// of the from currency. Both are looked up by alphabetic or numeric code.
+func CrossRate(currencies models.Currencies, from string, to string) (*big.Rat, error) {
+	all := withSourceCurrency(currencies.Currencies)
+
+	unitRates := make([]*big.Rat, 0, 2)
+
+	for _, code := range []string{from, to} {
+		currency, ok := FindCurrency(all, code)
+		if !ok {
+			return nil, apperrors.UnknownCurrency(code)
+		}
+
+		unitRate, err := UnitRate(currency)
+		if err != nil {
+			return nil, errlib.Wrap(err, "could not calculate unit rate of "+currency.CharCode)
+		}
+
+		unitRates = append(unitRates, unitRate)
+	}
+
+	return unitRates[0].Quo(unitRates[0], unitRates[1]), nil
+}
+
+// FindCurrency looks up a currency by its alphabetic or numeric code.
+// The source currency is found only if it is in the list.
+func FindCurrency(currencies []models.Currency, code string) (models.Currency, bool) {
+	numCode, err := strconv.Atoi(code)
+	isNumCode := err == nil
+	code = strings.ToUpper(code)
+
+	for _, currency := range currencies {
+		if (isNumCode && (currency.NumCode == numCode)) ||
+			(!isNumCode && (currency.CharCode == code)) {
+			return currency, true
+		}
+	}
+
+	return models.Currency{}, false
+}
+
+// crossUnitRates returns unit rates of the currencies against the base,
+// if the base is in the list.
+func crossUnitRates(currencies []models.Currency, base string) (map[string]*big.Rat, error) {
+	unitRates := make(map[string]*big.Rat, len(currencies)+1)
+
+	if len(currencies) == 0 {
+		return unitRates, nil
+	}
+
+	all := withSourceCurrency(currencies)
+
+	baseCurrency, ok := FindCurrency(all, base)
+	if !ok {
+		return unitRates, nil
+	}
+
+	baseUnitRate, err := UnitRate(baseCurrency)
+	if err != nil {
+		return nil, errlib.Wrap(err, "could not calculate unit rate of base "+base)
+	}
+
+	for _, currency := range all {
+		unitRate, err := UnitRate(currency)
+		if err != nil {
+			return nil, errlib.Wrap(err, "could not calculate unit rate of "+currency.CharCode)
+		}
+
+		unitRates[currency.CharCode] = unitRate.Quo(unitRate, baseUnitRate)
+	}
+
+	return unitRates, nil
+}
+
+// withSourceCurrency prepends the source currency, which is not in the
+// source data, as all the rates are quoted in it.
+func withSourceCurrency(currencies []models.Currency) []models.Currency {
+	all := make([]models.Currency, 0, len(currencies)+1)
+
+	all = append(all, models.Currency{
+		NumCode:    sourceCurrencyNumCode,
+		CharCode:   SourceCurrency,
+		Multiplier: 1,
+		Name:       sourceCurrencyName,
+		Value:      "1",
+	})
+
+	return append(all, currencies...)
+}
+
+// Format rounds the number to the configured count of decimal places.
+// Numbers below one keep as many significant digits, so rates of small
+// currencies against large ones, such as VND against USD, are not cut to
+// a few digits.
+func (c *Calculator) Format(r *big.Rat) string {
+	places := c.config.OutputDecimalPlaces
+
+	return FormatDecimal(r, places+leadingZeros(r, places))
+}
+
+func (c *Calculator) change(previous *big.Rat, current *big.Rat) (*string, *string) {
+	diff := new(big.Rat).Sub(current, previous)
+	change := c.Format(diff)
+
+	percent := new(big.Rat).Quo(diff, previous)
+	percent.Mul(percent, hundred)
+
+	changePercent := FormatDecimal(percent, percentDecimalPlaces)
+
+	return &change, &changePercent
+}
+
+// UnitRate returns the price of a single unit of the currency.
+func UnitRate(currency models.Currency) (*big.Rat, error) {
+	value, err := ParseDecimal(string(currency.Value))
+	if err != nil {
+		return nil, err
+	}
+
+	if value.Sign() <= 0 {
+		return nil, errors.New("value is not positive")
+	}
+
+	if currency.Multiplier <= 0 {
+		return nil, errors.New("nominal is not positive")
+	}
+
+	return value.Quo(value, big.NewRat(int64(currency.Multiplier), 1)), nil
+}
+
+// ParseDecimal parses a decimal number written with a dot.
+func ParseDecimal(s string) (*big.Rat, error) {
+	if strings.ContainsAny(s, "/eE") {
+		return nil, errors.New("not a decimal number: " + s)
+	}
+
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, errors.New("not a decimal number: " + s)
+	}
+
+	return r, nil
+}
+
+// leadingZeros returns the count of zeros between the decimal point and
+// the first significant digit of the number, which is below one, and no
+// more than the limit.
+func leadingZeros(r *big.Rat, limit int) int {
+	if r.Sign() == 0 {
+		return 0
+	}
+
+	x := new(big.Rat).Abs(r)
+
+	zeros := 0
+
+	for (zeros < limit) && (x.Cmp(tenth) < 0) {
+		x.Mul(x, ten)
+		zeros++
+	}
+
+	return zeros
+}
+
+// FormatDecimal rounds the number half away from zero and drops trailing
+// zeros of the fractional part.
+func FormatDecimal(r *big.Rat, places int) string {
+	s := r.FloatString(places)
+
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+
+	if s == "-0" {
+		s = "0"
+	}
+
+	return s
+}
@@ -0,0 +1,231 @@
+package calculator
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+
+	apperrors "github.com/mrumyantsev/currency-converter-app/internal/pkg/app-errors"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+)
+
+var currencies = models.Currencies{Currencies: []models.Currency{
+	{NumCode: 840, CharCode: "USD", Multiplier: 1, Name: "Доллар США", Value: "91.3336"},
+	{NumCode: 978, CharCode: "EUR", Multiplier: 1, Name: "Евро", Value: "99.1821"},
+	{NumCode: 704, CharCode: "VND", Multiplier: 10000, Name: "Вьетнамский донг", Value: "36.9977"},
+	{NumCode: 392, CharCode: "JPY", Multiplier: 100, Name: "Японская иена", Value: "60.6707"},
+}}
+
+var previousCurrencies = models.Currencies{Currencies: []models.Currency{
+	{NumCode: 840, CharCode: "USD", Multiplier: 1, Name: "Доллар США", Value: "91.1217"},
+	{NumCode: 704, CharCode: "VND", Multiplier: 10000, Name: "Вьетнамский донг", Value: "37"},
+}}
+
+func newTestCalculator() *Calculator {
+	return New(&config.Config{OutputDecimalPlaces: 8})
+}
+
+func ptr(s string) *string {
+	return &s
+}
+
+func TestCalculate(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     string
+		previous models.Currencies
+		want     []models.CalculatedCurrency
+	}{
+		{"source currency", "RUB", previousCurrencies, []models.CalculatedCurrency{
+			{NumCode: 840, CharCode: "USD", Name: "Доллар США", Nominal: 1,
+				Value: "91.3336", UnitRate: "91.3336", InverseRate: "0.010948873",
+				Change: ptr("0.2119"), ChangePercent: ptr("0.2325")},
+			{NumCode: 978, CharCode: "EUR", Name: "Евро", Nominal: 1,
+				Value: "99.1821", UnitRate: "99.1821", InverseRate: "0.010082464"},
+			{NumCode: 704, CharCode: "VND", Name: "Вьетнамский донг", Nominal: 10000,
+				Value: "36.9977", UnitRate: "0.00369977", InverseRate: "270.2870719",
+				Change: ptr("-0.00000023"), ChangePercent: ptr("-0.0062")},
+			{NumCode: 392, CharCode: "JPY", Name: "Японская иена", Nominal: 100,
+				Value: "60.6707", UnitRate: "0.606707", InverseRate: "1.64824207"},
+		}},
+		{"rebased by numeric code", "840", previousCurrencies, []models.CalculatedCurrency{
+			{NumCode: 643, CharCode: "RUB", Name: "Российский рубль", Nominal: 1,
+				Value: "0.010948873", UnitRate: "0.010948873", InverseRate: "91.3336",
+				Change: ptr("-0.000025461182"), ChangePercent: ptr("-0.232")},
+			{NumCode: 978, CharCode: "EUR", Name: "Евро", Nominal: 1,
+				Value: "1.08593223", UnitRate: "1.08593223", InverseRate: "0.92086778"},
+			{NumCode: 704, CharCode: "VND", Name: "Вьетнамский донг", Nominal: 10000,
+				Value: "0.40508312", UnitRate: "0.000040508312", InverseRate: "24686.29131",
+				Change: ptr("-0.000000096724615"), ChangePercent: ptr("-0.2382")},
+			{NumCode: 392, CharCode: "JPY", Name: "Японская иена", Nominal: 100,
+				Value: "0.6642758", UnitRate: "0.006642758", InverseRate: "150.53988169"},
+		}},
+		{"base missing in previous", "eur", previousCurrencies, []models.CalculatedCurrency{
+			{NumCode: 643, CharCode: "RUB", Name: "Российский рубль", Nominal: 1,
+				Value: "0.010082464", UnitRate: "0.010082464", InverseRate: "99.1821"},
+			{NumCode: 840, CharCode: "USD", Name: "Доллар США", Nominal: 1,
+				Value: "0.92086778", UnitRate: "0.92086778", InverseRate: "1.08593223"},
+			{NumCode: 704, CharCode: "VND", Name: "Вьетнамский донг", Nominal: 10000,
+				Value: "0.373028", UnitRate: "0.0000373028", InverseRate: "26807.6393938"},
+			{NumCode: 392, CharCode: "JPY", Name: "Японская иена", Nominal: 100,
+				Value: "0.61171018", UnitRate: "0.0061171018", InverseRate: "163.47610956"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base, got, err := newTestCalculator().Calculate(currencies, tt.previous, tt.base)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, currency := range got {
+				if currency.CharCode == base {
+					t.Fatalf("base %s is listed", base)
+				}
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d currencies, want %d", len(got), len(tt.want))
+			}
+
+			for i := range tt.want {
+				if !reflect.DeepEqual(got[i], tt.want[i]) {
+					t.Errorf("currency %d =\n%s\nwant\n%s", i, describe(got[i]), describe(tt.want[i]))
+				}
+			}
+		})
+	}
+
+	if _, _, err := newTestCalculator().Calculate(currencies, previousCurrencies, "XYZ"); !errors.Is(err, apperrors.ErrUnknownCurrency) {
+		t.Fatalf("error of unknown base = %v, want unknown currency", err)
+	}
+}
+
+func describe(c models.CalculatedCurrency) string {
+	s := c.CharCode + " value " + c.Value + " unit " + c.UnitRate + " inverse " + c.InverseRate
+
+	if c.Change != nil {
+		s += " change " + *c.Change + " " + *c.ChangePercent + "%"
+	}
+
+	return s
+}
+
+func TestCrossRate(t *testing.T) {
+	tests := []struct {
+		from string
+		to   string
+		want string
+	}{
+		{"USD", "RUB", "91.3336"},
+		{"RUB", "USD", "0.010948873"},
+		{"VND", "JPY", "0.0060981166"},
+		{"JPY", "VND", "163.98505853"},
+		{"704", "840", "0.000040508312"},
+		{"usd", "usd", "1"},
+	}
+
+	calc := newTestCalculator()
+
+	for _, tt := range tests {
+		rate, err := CrossRate(currencies, tt.from, tt.to)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := calc.Format(rate); got != tt.want {
+			t.Errorf("cross rate %s/%s = %s, want %s", tt.from, tt.to, got, tt.want)
+		}
+	}
+
+	if _, err := CrossRate(currencies, "USD", "XYZ"); !errors.Is(err, apperrors.ErrUnknownCurrency) {
+		t.Fatalf("error of unknown currency = %v, want unknown currency", err)
+	}
+}
+
+func TestMatrix(t *testing.T) {
+	calc := newTestCalculator()
+
+	codes, matrix, err := calc.Matrix(currencies, []string{"usd", "704"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"USD", "VND"}; !reflect.DeepEqual(codes, want) {
+		t.Fatalf("codes = %v, want %v", codes, want)
+	}
+
+	if want := [][]string{{"1", "24686.29131"}, {"0.000040508312", "1"}}; !reflect.DeepEqual(matrix, want) {
+		t.Fatalf("matrix = %v, want %v", matrix, want)
+	}
+
+	codes, matrix, err = calc.Matrix(currencies, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if (len(codes) != 5) || (codes[0] != SourceCurrency) || (len(matrix) != 5) || (len(matrix[4]) != 5) {
+		t.Fatalf("matrix of all currencies is %v %v", codes, matrix)
+	}
+
+	if _, _, err = calc.Matrix(currencies, []string{"USD", "XYZ"}); !errors.Is(err, apperrors.ErrUnknownCurrency) {
+		t.Fatalf("error of unknown currency = %v, want unknown currency", err)
+	}
+}
+
+func TestUnitRateRejects(t *testing.T) {
+	tests := []models.Currency{
+		{CharCode: "USD", Multiplier: 1, Value: "0"},
+		{CharCode: "USD", Multiplier: 1, Value: "-1"},
+		{CharCode: "USD", Multiplier: 0, Value: "1"},
+		{CharCode: "USD", Multiplier: 1, Value: "1e2"},
+		{CharCode: "USD", Multiplier: 1, Value: "1/2"},
+		{CharCode: "USD", Multiplier: 1, Value: "91,3336"},
+	}
+
+	for _, currency := range tests {
+		if _, err := UnitRate(currency); err == nil {
+			t.Errorf("unit rate of nominal %d and value %q is accepted", currency.Multiplier, currency.Value)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"0", "0"},
+		{"91.3336", "91.3336"},
+		{"1.000000005", "1.00000001"},
+		{"1.000000004", "1"},
+		{"-1.000000005", "-1.00000001"},
+		{"-0.000000000000000001", "0"},
+		{"123456789.123456789", "123456789.12345679"},
+		{"0.123456789123", "0.12345679"},
+		{"0.0123456789123", "0.012345679"},
+		{"0.000040508312345", "0.000040508312"},
+		{"0.00000000123456789123", "0.0000000012345679"},
+		{"0.0000000000000000123", "0"},
+	}
+
+	calc := newTestCalculator()
+
+	for _, tt := range tests {
+		r, err := ParseDecimal(tt.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := calc.Format(r); got != tt.want {
+			t.Errorf("Format(%s) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+
+	if got := FormatDecimal(big.NewRat(-1, 3), 4); got != "-0.3333" {
+		t.Errorf("FormatDecimal(-1/3, 4) = %s, want -0.3333", got)
+	}
+}
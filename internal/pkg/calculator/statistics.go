@@ -0,0 +1,190 @@
+package calculator
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+	"github.com/mrumyantsev/go-errlib"
+)
+
+// sqrtPrecision is the mantissa precision of square roots in bits, which
+// is far beyond any sensible count of output decimal places.
+const sqrtPrecision = 256
+
+// Stats computes statistics of the daily rates, which must be ordered by
+// date. Only the computed fields of the result are set.
+func (c *Calculator) Stats(dailyRates []models.DailyRate) (models.CurrencyStats, error) {
+	var stats models.CurrencyStats
+
+	if len(dailyRates) == 0 {
+		return stats, errors.New("there are no daily rates")
+	}
+
+	values := make([]*big.Rat, len(dailyRates))
+	sum := new(big.Rat)
+
+	for i, dailyRate := range dailyRates {
+		value, err := ParseDecimal(dailyRate.UnitValue)
+		if err != nil {
+			return stats, errlib.Wrap(err, "could not parse rate of "+dailyRate.EffectiveDate)
+		}
+
+		values[i] = value
+		sum.Add(sum, value)
+	}
+
+	count := big.NewRat(int64(len(values)), 1)
+	mean := new(big.Rat).Quo(sum, count)
+	first, last := values[0], values[len(values)-1]
+
+	change, changePercent := c.change(first, last)
+
+	sorted := make([]*big.Rat, len(values))
+	copy(sorted, values)
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Cmp(sorted[j]) < 0
+	})
+
+	stats.Count = len(values)
+	stats.FirstDate = dailyRates[0].EffectiveDate
+	stats.LastDate = dailyRates[len(dailyRates)-1].EffectiveDate
+	stats.First = c.Format(first)
+	stats.Last = c.Format(last)
+	stats.Min = c.Format(sorted[0])
+	stats.Max = c.Format(sorted[len(sorted)-1])
+	stats.Mean = c.Format(mean)
+	stats.Median = c.Format(median(sorted))
+	stats.StdDev = c.Format(stdDev(values, mean))
+	stats.Change = *change
+	stats.ChangePercent = *changePercent
+
+	return stats, nil
+}
+
+// median returns the middle of the sorted values, or the mean of the two
+// middle ones for an even count.
+func median(sorted []*big.Rat) *big.Rat {
+	middle := len(sorted) / 2
+
+	if len(sorted)%2 == 1 {
+		return new(big.Rat).Set(sorted[middle])
+	}
+
+	m := new(big.Rat).Add(sorted[middle-1], sorted[middle])
+
+	return m.Quo(m, big.NewRat(2, 1))
+}
+
+// stdDev returns the sample standard deviation, which is zero for a
+// single value.
+func stdDev(values []*big.Rat, mean *big.Rat) *big.Rat {
+	if len(values) < 2 {
+		return new(big.Rat)
+	}
+
+	variance := new(big.Rat)
+	diff := new(big.Rat)
+
+	for _, value := range values {
+		diff.Sub(value, mean)
+		variance.Add(variance, diff.Mul(diff, diff))
+	}
+
+	variance.Quo(variance, big.NewRat(int64(len(values)-1), 1))
+
+	root := new(big.Float).SetPrec(sqrtPrecision).SetRat(variance)
+	root.Sqrt(root)
+
+	r, _ := root.Rat(nil)
+
+	return r
+}
+
+// Candles groups the daily rates, which must be ordered by date, into
+// candles of the interval. Carried forward rates take part in the prices,
+// but not in the count.
+func (c *Calculator) Candles(dailyRates []models.DailyRate, interval string) ([]models.Candle, error) {
+	candles := []models.Candle{}
+
+	var high, low, last *big.Rat
+
+	for _, dailyRate := range dailyRates {
+		value, err := ParseDecimal(dailyRate.UnitValue)
+		if err != nil {
+			return nil, errlib.Wrap(err, "could not parse rate of "+dailyRate.EffectiveDate)
+		}
+
+		start, end, err := intervalBounds(dailyRate.EffectiveDate, interval)
+		if err != nil {
+			return nil, err
+		}
+
+		if (len(candles) == 0) || (candles[len(candles)-1].PeriodStart != start) {
+			if len(candles) > 0 {
+				c.closeCandle(&candles[len(candles)-1], high, low, last)
+			}
+
+			candles = append(candles, models.Candle{
+				PeriodStart: start,
+				PeriodEnd:   end,
+				Open:        c.Format(value),
+			})
+
+			high, low = value, value
+		}
+
+		if value.Cmp(high) > 0 {
+			high = value
+		}
+
+		if value.Cmp(low) < 0 {
+			low = value
+		}
+
+		last = value
+
+		if !dailyRate.IsCarriedForward {
+			candles[len(candles)-1].Count++
+		}
+	}
+
+	if len(candles) > 0 {
+		c.closeCandle(&candles[len(candles)-1], high, low, last)
+	}
+
+	return candles, nil
+}
+
+func (c *Calculator) closeCandle(candle *models.Candle, high *big.Rat, low *big.Rat, last *big.Rat) {
+	candle.High = c.Format(high)
+	candle.Low = c.Format(low)
+	candle.Close = c.Format(last)
+}
+
+// intervalBounds returns the first and the last dates of the interval,
+// which includes the date.
+func intervalBounds(date string, interval string) (string, string, error) {
+	day, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		return "", "", errlib.Wrap(err, "could not parse date")
+	}
+
+	var start, end time.Time
+
+	switch interval {
+	case models.IntervalWeek:
+		start = day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+		end = start.AddDate(0, 0, 6)
+	case models.IntervalMonth:
+		start = day.AddDate(0, 0, 1-day.Day())
+		end = start.AddDate(0, 1, -1)
+	default:
+		return "", "", errors.New("unknown interval: " + interval)
+	}
+
+	return start.Format(time.DateOnly), end.Format(time.DateOnly), nil
+}
@@ -0,0 +1,207 @@
+package calculator
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+)
+
+// series returns daily rates of the values on consecutive dates from the
+// ninth of January 2024. Values with a trailing asterisk are carried
+// forward.
+func series(values ...string) []models.DailyRate {
+	dailyRates := make([]models.DailyRate, len(values))
+
+	for i, value := range values {
+		isCarriedForward := value[len(value)-1] == '*'
+		if isCarriedForward {
+			value = value[:len(value)-1]
+		}
+
+		dailyRates[i] = models.DailyRate{
+			EffectiveDate:    "2024-01-" + twoDigits(9+i),
+			UnitValue:        value,
+			IsCarriedForward: isCarriedForward,
+		}
+	}
+
+	return dailyRates
+}
+
+func twoDigits(n int) string {
+	return string([]byte{byte('0' + n/10), byte('0' + n%10)})
+}
+
+func TestStats(t *testing.T) {
+	tests := []struct {
+		name  string
+		rates []models.DailyRate
+		want  models.CurrencyStats
+	}{
+		{"integers", series("2", "4", "4", "4", "5", "5", "7", "9"), models.CurrencyStats{
+			Count: 8, FirstDate: "2024-01-09", LastDate: "2024-01-16",
+			First: "2", Last: "9", Min: "2", Max: "9", Mean: "5", Median: "4.5",
+			StdDev: "2.13808994", Change: "7", ChangePercent: "350",
+		}},
+		{"rates", series("90.0", "91.5", "89.75"), models.CurrencyStats{
+			Count: 3, FirstDate: "2024-01-09", LastDate: "2024-01-11",
+			First: "90", Last: "89.75", Min: "89.75", Max: "91.5", Mean: "90.41666667", Median: "90",
+			StdDev: "0.94648472", Change: "-0.25", ChangePercent: "-0.2778",
+		}},
+		{"unordered values", series("3", "1", "2"), models.CurrencyStats{
+			Count: 3, FirstDate: "2024-01-09", LastDate: "2024-01-11",
+			First: "3", Last: "2", Min: "1", Max: "3", Mean: "2", Median: "2",
+			StdDev: "1", Change: "-1", ChangePercent: "-33.3333",
+		}},
+		{"carried forward rates", series("90", "90*", "90*", "92"), models.CurrencyStats{
+			Count: 4, FirstDate: "2024-01-09", LastDate: "2024-01-12",
+			First: "90", Last: "92", Min: "90", Max: "92", Mean: "90.5", Median: "90",
+			StdDev: "1", Change: "2", ChangePercent: "2.2222",
+		}},
+		{"single point", series("91.3336"), models.CurrencyStats{
+			Count: 1, FirstDate: "2024-01-09", LastDate: "2024-01-09",
+			First: "91.3336", Last: "91.3336", Min: "91.3336", Max: "91.3336", Mean: "91.3336", Median: "91.3336",
+			StdDev: "0", Change: "0", ChangePercent: "0",
+		}},
+		{"small currency", series("0.000040508312", "0.000040508313"), models.CurrencyStats{
+			Count: 2, FirstDate: "2024-01-09", LastDate: "2024-01-10",
+			First: "0.000040508312", Last: "0.000040508313", Min: "0.000040508312", Max: "0.000040508313",
+			Mean: "0.000040508313", Median: "0.000040508313",
+			StdDev: "0.0000000000007071", Change: "0.000000000001", ChangePercent: "0",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newTestCalculator().Stats(tt.rates)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tt.want {
+				t.Fatalf("stats =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatsRejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		rates []models.DailyRate
+	}{
+		{"empty period", nil},
+		{"malformed rate", series("90", "90,5")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newTestCalculator().Stats(tt.rates); err == nil {
+				t.Fatal("stats are computed")
+			}
+		})
+	}
+}
+
+func dailyRate(date string, value string, isCarriedForward bool) models.DailyRate {
+	return models.DailyRate{EffectiveDate: date, UnitValue: value, IsCarriedForward: isCarriedForward}
+}
+
+func TestCandles(t *testing.T) {
+	// from Saturday before the new year to the second week of February
+	rates := []models.DailyRate{
+		dailyRate("2023-12-30", "89.6883", false),
+		dailyRate("2023-12-31", "89.6883", true),
+		dailyRate("2024-01-01", "89.6883", true),
+		dailyRate("2024-01-10", "90", false),
+		dailyRate("2024-01-11", "91.5", false),
+		dailyRate("2024-01-12", "89.75", false),
+		dailyRate("2024-01-13", "89.75", true),
+		dailyRate("2024-01-14", "89.75", true),
+		dailyRate("2024-01-31", "88.6", false),
+		dailyRate("2024-02-01", "89.2", false),
+		dailyRate("2024-02-02", "88.9", false),
+		dailyRate("2024-02-29", "90.8", false),
+	}
+
+	tests := []struct {
+		name     string
+		interval string
+		rates    []models.DailyRate
+		want     []models.Candle
+	}{
+		{"weeks", models.IntervalWeek, rates, []models.Candle{
+			{PeriodStart: "2023-12-25", PeriodEnd: "2023-12-31", Open: "89.6883", High: "89.6883", Low: "89.6883", Close: "89.6883", Count: 1},
+			{PeriodStart: "2024-01-01", PeriodEnd: "2024-01-07", Open: "89.6883", High: "89.6883", Low: "89.6883", Close: "89.6883", Count: 0},
+			{PeriodStart: "2024-01-08", PeriodEnd: "2024-01-14", Open: "90", High: "91.5", Low: "89.75", Close: "89.75", Count: 3},
+			{PeriodStart: "2024-01-29", PeriodEnd: "2024-02-04", Open: "88.6", High: "89.2", Low: "88.6", Close: "88.9", Count: 3},
+			{PeriodStart: "2024-02-26", PeriodEnd: "2024-03-03", Open: "90.8", High: "90.8", Low: "90.8", Close: "90.8", Count: 1},
+		}},
+		{"months", models.IntervalMonth, rates, []models.Candle{
+			{PeriodStart: "2023-12-01", PeriodEnd: "2023-12-31", Open: "89.6883", High: "89.6883", Low: "89.6883", Close: "89.6883", Count: 1},
+			{PeriodStart: "2024-01-01", PeriodEnd: "2024-01-31", Open: "89.6883", High: "91.5", Low: "88.6", Close: "88.6", Count: 4},
+			{PeriodStart: "2024-02-01", PeriodEnd: "2024-02-29", Open: "89.2", High: "90.8", Low: "88.9", Close: "90.8", Count: 3},
+		}},
+		{"week across year", models.IntervalWeek, []models.DailyRate{
+			dailyRate("2024-12-31", "101.6797", false),
+			dailyRate("2025-01-01", "101.6797", true),
+			dailyRate("2025-01-05", "101.6797", true),
+		}, []models.Candle{
+			{PeriodStart: "2024-12-30", PeriodEnd: "2025-01-05", Open: "101.6797", High: "101.6797", Low: "101.6797", Close: "101.6797", Count: 1},
+		}},
+		{"no rates", models.IntervalMonth, nil, []models.Candle{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newTestCalculator().Candles(tt.rates, tt.interval)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("candles =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := newTestCalculator().Candles(rates, "day"); err == nil {
+		t.Fatal("candles of unknown interval are grouped")
+	}
+}
+
+func TestIntervalBounds(t *testing.T) {
+	tests := []struct {
+		date     string
+		interval string
+		start    string
+		end      string
+	}{
+		{"2024-01-01", models.IntervalWeek, "2024-01-01", "2024-01-07"},
+		{"2024-01-07", models.IntervalWeek, "2024-01-01", "2024-01-07"},
+		{"2024-01-08", models.IntervalWeek, "2024-01-08", "2024-01-14"},
+		{"2023-01-01", models.IntervalWeek, "2022-12-26", "2023-01-01"},
+		{"2024-03-01", models.IntervalWeek, "2024-02-26", "2024-03-03"},
+		{"2024-03-31", models.IntervalWeek, "2024-03-25", "2024-03-31"},
+		{"2024-02-15", models.IntervalMonth, "2024-02-01", "2024-02-29"},
+		{"2023-02-28", models.IntervalMonth, "2023-02-01", "2023-02-28"},
+		{"2023-12-31", models.IntervalMonth, "2023-12-01", "2023-12-31"},
+		{"2024-01-01", models.IntervalMonth, "2024-01-01", "2024-01-31"},
+	}
+
+	for _, tt := range tests {
+		start, end, err := intervalBounds(tt.date, tt.interval)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if (start != tt.start) || (end != tt.end) {
+			t.Errorf("%s of %s is %s..%s, want %s..%s", tt.interval, tt.date, start, end, tt.start, tt.end)
+		}
+	}
+
+	if _, _, err := intervalBounds("2024-13-01", models.IntervalWeek); err == nil {
+		t.Error("bounds of invalid date are returned")
+	}
+}
@@ -0,0 +1,71 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// A Clock tells the current time and waits for durations. The schedule of
+// updates is driven by a clock, so it can be run on simulated time.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+// Real is the clock of the system.
+type Real struct{}
+
+func NewReal() *Real {
+	return &Real{}
+}
+
+func (c *Real) Now() time.Time {
+	return time.Now()
+}
+
+func (c *Real) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// A Simulated clock stands still, until it is slept on or set. Sleeping
+// advances it instantly, so days of schedule pass in no time.
+type Simulated struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewSimulated(start time.Time) *Simulated {
+	return &Simulated{now: start}
+}
+
+func (c *Simulated) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *Simulated) Sleep(d time.Duration) {
+	c.Advance(d)
+}
+
+// Advance moves the clock forward. Negative durations are ignored, since
+// time does not go back.
+func (c *Simulated) Advance(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+// Set moves the clock to the given time.
+func (c *Simulated) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = now
+}
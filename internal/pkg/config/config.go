@@ -2,6 +2,9 @@ package config
 
 import (
 	"errors"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
 	"github.com/mrumyantsev/go-errlib"
@@ -17,12 +20,60 @@ type Config struct {
 	IsReadCurrencyDataFromFile   bool   `envconfig:"READ_CURRENCIES_FROM_FILE" default:"false"`
 	CurrencySourceUrl            string `envconfig:"CURRENCIES_SOURCE_URL" default:"https://www.cbr.ru/scripts/XML_daily.asp"`
 	CurrencySourceFile           string `envconfig:"CURRENCIES_SOURCE_FILE" default:"currencies.xml"`
+	CurrencyReferenceUrl         string `envconfig:"CURRENCIES_REFERENCE_URL" default:"https://www.cbr.ru/scripts/XML_valFull.asp"`
+	CurrencyDynamicUrl           string `envconfig:"CURRENCIES_DYNAMIC_URL" default:"https://www.cbr.ru/scripts/XML_dynamic.asp"`
 	HttpRequestProtocol          string `envconfig:"HTTP_REQUEST_PROTOCOL" default:"HTTP/2"`
-	FakeUserAgentHeaderValue     string `envconfig:"FAKE_USER_AGENT_HEADER_VALUE" default:"Mozilla/5.0 (X11; Linux x86_64)"`
 	IsUseMultithreadedParsing    bool   `envconfig:"USE_MULTITHREADED_PARSING" default:"true"`
+	ParserWorkers                int    `envconfig:"PARSER_WORKERS" default:"4"`
 	TimeWhenNeedToUpdateCurrency string `envconfig:"TIME_WHEN_NEED_TO_UPDATE_CURRENCY" default:"13:30:00"`
+	UpdateTimeZone               string `envconfig:"UPDATE_TIME_ZONE" default:"Local"`
 	InitialCurrenciesCapacity    int    `envconfig:"INITIAL_CURRENCIES_CAPACITY" default:"50"`
 
+	SourceConnectTimeout time.Duration `envconfig:"SOURCE_CONNECT_TIMEOUT" default:"10s"`
+	SourceRequestTimeout time.Duration `envconfig:"SOURCE_REQUEST_TIMEOUT" default:"60s"`
+	SourceRetries        int           `envconfig:"SOURCE_RETRIES" default:"3"`
+	SourceRetryDelay     time.Duration `envconfig:"SOURCE_RETRY_DELAY" default:"2s"`
+	SourceRetryMaxDelay  time.Duration `envconfig:"SOURCE_RETRY_MAX_DELAY" default:"1m"`
+	SourceProxyUrl       string        `envconfig:"SOURCE_PROXY_URL" default:""`
+	SourceCaFile         string        `envconfig:"SOURCE_CA_FILE" default:""`
+	SourceUserAgent      string        `envconfig:"SOURCE_USER_AGENT" default:"currency-converter-app (+https://github.com/mrumyantsev/currency-converter-app)"`
+
+	IsArchiveEnabled    bool          `envconfig:"ARCHIVE_ENABLED" default:"true"`
+	ArchiveBackend      string        `envconfig:"ARCHIVE_BACKEND" default:"local"`
+	ArchiveDir          string        `envconfig:"ARCHIVE_DIR" default:"./archive"`
+	IsArchiveCompressed bool          `envconfig:"ARCHIVE_COMPRESS" default:"false"`
+	ArchiveRetention    time.Duration `envconfig:"ARCHIVE_RETENTION" default:"0"`
+
+	ArchiveS3Endpoint     string        `envconfig:"ARCHIVE_S3_ENDPOINT" default:"https://s3.amazonaws.com"`
+	ArchiveS3Region       string        `envconfig:"ARCHIVE_S3_REGION" default:"us-east-1"`
+	ArchiveS3Bucket       string        `envconfig:"ARCHIVE_S3_BUCKET" default:""`
+	ArchiveS3Prefix       string        `envconfig:"ARCHIVE_S3_PREFIX" default:""`
+	ArchiveS3AccessKey    string        `envconfig:"ARCHIVE_S3_ACCESS_KEY" default:""`
+	ArchiveS3SecretKey    string        `envconfig:"ARCHIVE_S3_SECRET_KEY" default:""`
+	ArchiveS3SessionToken string        `envconfig:"ARCHIVE_S3_SESSION_TOKEN" default:""`
+	IsArchiveS3PathStyle  bool          `envconfig:"ARCHIVE_S3_PATH_STYLE" default:"true"`
+	ArchiveS3Timeout      time.Duration `envconfig:"ARCHIVE_S3_TIMEOUT" default:"1m"`
+
+	DefaultBaseCurrency string        `envconfig:"DEFAULT_BASE_CURRENCY" default:"RUB"`
+	OutputDecimalPlaces int           `envconfig:"OUTPUT_DECIMAL_PLACES" default:"8"`
+	StaleDataThreshold  time.Duration `envconfig:"STALE_DATA_THRESHOLD" default:"1h"`
+
+	ValidationMinCurrencies         int      `envconfig:"VALIDATION_MIN_CURRENCIES" default:"20"`
+	ValidationMaxDailyChange        int      `envconfig:"VALIDATION_MAX_DAILY_CHANGE" default:"30"`
+	ValidationMovementConfirmations int      `envconfig:"VALIDATION_MOVEMENT_CONFIRMATIONS" default:"3"`
+	ValidationSkipMovementCodes     []string `envconfig:"VALIDATION_SKIP_MOVEMENT_CODES" default:""`
+
+	HistoryCacheTtl  time.Duration `envconfig:"HISTORY_CACHE_TTL" default:"10m"`
+	HistoryCacheSize int           `envconfig:"HISTORY_CACHE_SIZE" default:"1000"`
+
+	BatchMaxItems    int    `envconfig:"BATCH_MAX_ITEMS" default:"10000"`
+	BatchConcurrency int    `envconfig:"BATCH_CONCURRENCY" default:"8"`
+	BatchBodyLimit   string `envconfig:"BATCH_BODY_LIMIT" default:"8M"`
+
+	ExportFetchSize     int           `envconfig:"EXPORT_FETCH_SIZE" default:"1000"`
+	ExportWriteTimeout  time.Duration `envconfig:"EXPORT_WRITE_TIMEOUT" default:"10m"`
+	ExportMaxConcurrent int           `envconfig:"EXPORT_MAX_CONCURRENT" default:"4"`
+
 	DbDriver   string `envconfig:"DB_DRIVER" default:"postgres"`
 	DbHostname string `envconfig:"DB_HOSTNAME" default:"localhost"`
 	DbPort     string `envconfig:"DB_PORT" default:"5432"`
@@ -33,6 +84,42 @@ type Config struct {
 
 	HttpServerListenIp   string `envconfig:"HTTP_SERVER_LISTEN_IP" default:"0.0.0.0"`
 	HttpServerListenPort string `envconfig:"HTTP_SERVER_LISTEN_PORT" default:"8080"`
+
+	HttpServerReadTimeout       time.Duration `envconfig:"HTTP_SERVER_READ_TIMEOUT" default:"10s"`
+	HttpServerReadHeaderTimeout time.Duration `envconfig:"HTTP_SERVER_READ_HEADER_TIMEOUT" default:"5s"`
+	HttpServerWriteTimeout      time.Duration `envconfig:"HTTP_SERVER_WRITE_TIMEOUT" default:"30s"`
+	HttpServerIdleTimeout       time.Duration `envconfig:"HTTP_SERVER_IDLE_TIMEOUT" default:"120s"`
+	HttpServerMaxHeaderBytes    int           `envconfig:"HTTP_SERVER_MAX_HEADER_BYTES" default:"65536"`
+	HttpServerBodyLimit         string        `envconfig:"HTTP_SERVER_BODY_LIMIT" default:"1M"`
+	IsEnableH2c                 bool          `envconfig:"ENABLE_H2C" default:"false"`
+
+	TlsCertFile string `envconfig:"TLS_CERT_FILE" default:""`
+	TlsKeyFile  string `envconfig:"TLS_KEY_FILE" default:""`
+
+	CorsAllowOrigins  []string `envconfig:"CORS_ALLOW_ORIGINS" default:"*"`
+	CorsAllowMethods  []string `envconfig:"CORS_ALLOW_METHODS" default:"GET,HEAD,POST,OPTIONS"`
+	CorsAllowHeaders  []string `envconfig:"CORS_ALLOW_HEADERS" default:"Content-Type,X-Api-Key,If-None-Match,If-Modified-Since"`
+	CorsExposeHeaders []string `envconfig:"CORS_EXPOSE_HEADERS" default:"ETag,Last-Modified,X-RateLimit-Limit,X-RateLimit-Remaining,X-RateLimit-Reset,Retry-After"`
+	CorsMaxAge        int      `envconfig:"CORS_MAX_AGE" default:"3600"`
+
+	HstsMaxAge            int    `envconfig:"HSTS_MAX_AGE" default:"31536000"`
+	ContentSecurityPolicy string `envconfig:"CONTENT_SECURITY_POLICY" default:"default-src 'none'; frame-ancestors 'none'"`
+
+	ApiKeyHeader           string        `envconfig:"API_KEY_HEADER" default:"X-Api-Key"`
+	ApiKeysCacheTtl        time.Duration `envconfig:"API_KEYS_CACHE_TTL" default:"1m"`
+	ApiKeysCacheSize       int           `envconfig:"API_KEYS_CACHE_SIZE" default:"10000"`
+	ApiKeyLookupRateLimit  int           `envconfig:"API_KEY_LOOKUP_RATE_LIMIT" default:"1200"`
+	ApiKeyLookupRateBurst  int           `envconfig:"API_KEY_LOOKUP_RATE_BURST" default:"200"`
+	ApiKeyDefaultRateLimit int           `envconfig:"API_KEY_DEFAULT_RATE_LIMIT" default:"600"`
+	ApiKeyDefaultRateBurst int           `envconfig:"API_KEY_DEFAULT_RATE_BURST" default:"100"`
+	IsAllowAnonymousAccess bool          `envconfig:"ALLOW_ANONYMOUS_ACCESS" default:"true"`
+	AnonymousRateLimit     int           `envconfig:"ANONYMOUS_RATE_LIMIT" default:"60"`
+	AnonymousRateBurst     int           `envconfig:"ANONYMOUS_RATE_BURST" default:"10"`
+	RateLimiterExpiresIn   time.Duration `envconfig:"RATE_LIMITER_EXPIRES_IN" default:"10m"`
+
+	// UpdateLocation is the loaded time zone of updates. Dates of the
+	// source, like "today", are dates in it.
+	UpdateLocation *time.Location `ignored:"true"`
 }
 
 // New creates an application configuration.
@@ -50,5 +137,35 @@ func (c *Config) Init() error {
 		return errors.New("no database password specified")
 	}
 
+	if (c.TlsCertFile == "") != (c.TlsKeyFile == "") {
+		return errors.New("both tls certificate and key files must be specified")
+	}
+
+	if c.SourceRetries < 0 {
+		return errors.New("number of source retries must not be negative")
+	}
+
+	if c.ExportMaxConcurrent <= 0 {
+		return errors.New("maximum number of concurrent exports must be positive")
+	}
+
+	if !currencyCodeRegexp.MatchString(c.DefaultBaseCurrency) {
+		return errors.New("default base currency must be an alphabetic or numeric ISO 4217 code: " +
+			c.DefaultBaseCurrency)
+	}
+
+	c.DefaultBaseCurrency = strings.ToUpper(c.DefaultBaseCurrency)
+
+	location, err := time.LoadLocation(c.UpdateTimeZone)
+	if err != nil {
+		return errlib.Wrap(err, "could not load time zone of updates")
+	}
+
+	c.UpdateLocation = location
+
 	return nil
 }
+
+// currencyCodeRegexp matches alphabetic and numeric ISO 4217 codes, as
+// currencies are looked up by both.
+var currencyCodeRegexp = regexp.MustCompile(`^([A-Za-z]{3}|[0-9]{1,3})$`)
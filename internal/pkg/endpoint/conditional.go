@@ -0,0 +1,165 @@
+package endpoint
+
+import (
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+	memcache "github.com/mrumyantsev/currency-converter-app/internal/pkg/mem-cache"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/precompressed"
+)
+
+const (
+	headerCacheControl    = "Cache-Control"
+	headerETag            = "ETag"
+	headerLastModified    = "Last-Modified"
+	headerIfNoneMatch     = "If-None-Match"
+	headerIfModifiedSince = "If-Modified-Since"
+)
+
+// setCacheHeaders sets validators and freshness lifetime of a response,
+// which stays valid from now until the next scheduled update. Responses
+// vary by the API key, and the ones, which need a key or are given for
+// one, are private: a shared cache must not serve them to other clients,
+// as they are metered per key.
+func setCacheHeaders(
+	ctx echo.Context,
+	cfg *config.Config,
+	etag string,
+	lastModified time.Time,
+	expires time.Time,
+	now time.Time,
+) {
+	maxAge := int(expires.Sub(now).Seconds())
+	if maxAge < 0 {
+		maxAge = 0
+	}
+
+	cacheability := "public"
+
+	if !cfg.IsAllowAnonymousAccess || (ctx.Request().Header.Get(cfg.ApiKeyHeader) != "") {
+		cacheability = "private"
+	}
+
+	header := ctx.Response().Header()
+
+	header.Add(echo.HeaderVary, cfg.ApiKeyHeader)
+	header.Set(headerETag, etag)
+	header.Set(headerLastModified, lastModified.UTC().Format(http.TimeFormat))
+	header.Set(headerCacheControl, cacheability+", max-age="+strconv.Itoa(maxAge))
+}
+
+// isNotModified evaluates the request preconditions according to
+// RFC 9110: If-None-Match takes precedence over If-Modified-Since.
+func isNotModified(req *http.Request, etag string, lastModified time.Time) bool {
+	if (req.Method != http.MethodGet) && (req.Method != http.MethodHead) {
+		return false
+	}
+
+	if ifNoneMatch := req.Header.Get(headerIfNoneMatch); ifNoneMatch != "" {
+		return isETagMatched(ifNoneMatch, etag)
+	}
+
+	ifModifiedSince := req.Header.Get(headerIfModifiedSince)
+	if ifModifiedSince == "" {
+		return false
+	}
+
+	since, err := http.ParseTime(ifModifiedSince)
+	if err != nil {
+		return false
+	}
+
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// isETagMatched uses weak comparison, as required for If-None-Match.
+func isETagMatched(ifNoneMatch string, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+
+		if (candidate == "*") || (strings.TrimPrefix(candidate, "W/") == etag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sendPrecompressed sends the body variant negotiated by Accept-Encoding,
+// or an empty 304 response, if the client already has it. A client, which
+// accepts none of the variants, gets a 406 error.
+func (e *CurrenciesEndpoint) sendPrecompressed(ctx echo.Context, body *precompressed.Body, snapshot *memcache.Snapshot) error {
+	req := ctx.Request()
+	header := ctx.Response().Header()
+
+	header.Add(echo.HeaderVary, echo.HeaderAcceptEncoding)
+
+	encoding, data, ok := body.Negotiate(req.Header.Get(echo.HeaderAcceptEncoding))
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotAcceptable, "no acceptable content coding, supported are br, gzip and identity")
+	}
+
+	etag := body.ETag(encoding)
+
+	setCacheHeaders(ctx, e.config, etag, snapshot.UpdateDatetime, snapshot.NextUpdateDatetime, e.clock.Now())
+
+	if isNotModified(req, etag, snapshot.UpdateDatetime) {
+		return ctx.NoContent(http.StatusNotModified)
+	}
+
+	if encoding != precompressed.EncodingIdentity {
+		header.Set(echo.HeaderContentEncoding, encoding)
+	}
+
+	return ctx.Blob(http.StatusOK, echo.MIMEApplicationJSONCharsetUTF8, data)
+}
+
+// sendConditionalJSON serializes the value derived from the snapshot, or
+// sends an empty 304 response, if the client already has it.
+func (e *CurrenciesEndpoint) sendConditionalJSON(ctx echo.Context, etag string, snapshot *memcache.Snapshot, value any) error {
+	setCacheHeaders(ctx, e.config, etag, snapshot.UpdateDatetime, snapshot.NextUpdateDatetime, e.clock.Now())
+
+	if isNotModified(ctx.Request(), etag, snapshot.UpdateDatetime) {
+		return ctx.NoContent(http.StatusNotModified)
+	}
+
+	return ctx.JSON(http.StatusOK, value)
+}
+
+// sendConditionalBlob sends the data derived from the snapshot, or an
+// empty 304 response, if the client already has it.
+func (e *CurrenciesEndpoint) sendConditionalBlob(
+	ctx echo.Context,
+	etag string,
+	snapshot *memcache.Snapshot,
+	contentType string,
+	data []byte,
+) error {
+	setCacheHeaders(ctx, e.config, etag, snapshot.UpdateDatetime, snapshot.NextUpdateDatetime, e.clock.Now())
+
+	if isNotModified(ctx.Request(), etag, snapshot.UpdateDatetime) {
+		return ctx.NoContent(http.StatusNotModified)
+	}
+
+	return ctx.Blob(http.StatusOK, contentType, data)
+}
+
+// derivedETag builds a strong entity tag of a representation, which is
+// fully determined by the snapshot version and the request parameters.
+func derivedETag(version string, params ...string) string {
+	hash := fnv.New64a()
+
+	for _, param := range params {
+		_, _ = hash.Write([]byte(param))
+		_, _ = hash.Write([]byte{0})
+	}
+
+	return `"` + version + "-" + strconv.FormatUint(hash.Sum64(), 36) + `"`
+}
@@ -0,0 +1,250 @@
+package endpoint
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	apperrors "github.com/mrumyantsev/currency-converter-app/internal/pkg/app-errors"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/calculator"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/clock"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+	memcache "github.com/mrumyantsev/currency-converter-app/internal/pkg/mem-cache"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/service"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	conversionResultsVersion = 1
+
+	latestDate = ""
+)
+
+// A conversionSource is the snapshot conversions of a date are done with,
+// or the reason it could not be found.
+type conversionSource struct {
+	currencies models.DatedCurrencies
+	err        error
+}
+
+type ConvertEndpoint struct {
+	config     *config.Config
+	memCache   *memcache.MemCache
+	history    service.History
+	calculator *calculator.Calculator
+	clock      clock.Clock
+}
+
+func NewConvertEndpoint(cfg *config.Config, mc *memcache.MemCache, svc service.History, clk clock.Clock) *ConvertEndpoint {
+	return &ConvertEndpoint{
+		config:     cfg,
+		memCache:   mc,
+		history:    svc,
+		calculator: calculator.New(cfg),
+		clock:      clk,
+	}
+}
+
+// ConvertBatch converts every item of the batch with the rates in force
+// on its date. Items fail one by one, so a single bad item does not fail
+// the whole batch.
+func (e *ConvertEndpoint) ConvertBatch(ctx echo.Context) error {
+	var items []models.ConversionItem
+
+	if err := json.NewDecoder(ctx.Request().Body).Decode(&items); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "request body is not an array of conversion items")
+	}
+
+	if len(items) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "there are no conversion items")
+	}
+
+	if len(items) > e.config.BatchMaxItems {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"too many conversion items, maximum is "+strconv.Itoa(e.config.BatchMaxItems))
+	}
+
+	sources := e.sources(items)
+
+	results := models.ConversionResults{
+		Version: conversionResultsVersion,
+		Results: make([]models.ConversionResult, len(items)),
+	}
+
+	forEachConcurrently(len(items), e.config.BatchConcurrency, func(i int) {
+		results.Results[i] = e.convert(items[i], sources[items[i].Date])
+	})
+
+	for _, result := range results.Results {
+		if result.Error != nil {
+			results.Failed++
+		}
+	}
+
+	return ctx.JSON(http.StatusOK, results)
+}
+
+// sources finds snapshots for all distinct dates of the items. The
+// published snapshot serves the latest dates, and the earlier ones are
+// looked up in the history.
+func (e *ConvertEndpoint) sources(items []models.ConversionItem) map[string]conversionSource {
+	sources := make(map[string]conversionSource)
+
+	var (
+		latest conversionSource
+		now    = e.clock.Now()
+		today  = now.In(e.config.UpdateLocation).Format(time.DateOnly)
+	)
+
+	snapshot, err := freshSnapshot(e.config, e.memCache, now)
+	if err != nil {
+		latest.err = err
+	} else {
+		latest.currencies = models.DatedCurrencies{
+			SnapshotVersion: snapshot.Version,
+			EffectiveDate:   snapshot.Info.EffectiveDate,
+			Currencies:      snapshot.Currencies,
+		}
+	}
+
+	sources[latestDate] = latest
+
+	var dates []string
+
+	for _, item := range items {
+		if _, ok := sources[item.Date]; ok {
+			continue
+		}
+
+		date := item.Date
+
+		switch {
+		case !isDate(date):
+			sources[date] = conversionSource{err: apperrors.InvalidRequest("date is not in YYYY-MM-DD format: " + date)}
+		case (latest.err == nil) && (date >= latest.currencies.EffectiveDate):
+			if (date > today) && (date > latest.currencies.EffectiveDate) {
+				sources[date] = conversionSource{err: apperrors.NoDataForDate(date)}
+			} else {
+				sources[date] = latest
+			}
+		default:
+			// reserves the date, so it is looked up only once
+			sources[date] = conversionSource{}
+			dates = append(dates, date)
+		}
+	}
+
+	found := make([]conversionSource, len(dates))
+
+	forEachConcurrently(len(dates), e.config.BatchConcurrency, func(i int) {
+		found[i].currencies, found[i].err = e.history.GetByDate(dates[i])
+	})
+
+	for i, date := range dates {
+		sources[date] = found[i]
+	}
+
+	return sources
+}
+
+func (e *ConvertEndpoint) convert(item models.ConversionItem, source conversionSource) models.ConversionResult {
+	result := models.ConversionResult{
+		From:   strings.ToUpper(item.From),
+		To:     strings.ToUpper(item.To),
+		Amount: item.Amount.String(),
+		Date:   item.Date,
+	}
+
+	if source.err != nil {
+		result.Error = conversionError(source.err)
+
+		return result
+	}
+
+	amount, err := calculator.ParseDecimal(item.Amount.String())
+	if err != nil {
+		result.Error = conversionError(apperrors.InvalidRequest("amount is not a decimal number: " + item.Amount.String()))
+
+		return result
+	}
+
+	rate, err := calculator.CrossRate(source.currencies.Currencies, item.From, item.To)
+	if err != nil {
+		result.Error = conversionError(err)
+
+		return result
+	}
+
+	result.Result = e.calculator.Format(new(big.Rat).Mul(amount, rate))
+	result.Rate = e.calculator.Format(rate)
+	result.SnapshotVersion = source.currencies.SnapshotVersion
+	result.EffectiveDate = source.currencies.EffectiveDate
+
+	return result
+}
+
+// conversionError reports domain errors as they are, and hides details
+// of the internal ones.
+func conversionError(err error) *models.ConversionError {
+	var appErr *apperrors.Error
+
+	if errors.As(err, &appErr) {
+		return &models.ConversionError{
+			Code:   appErr.Code,
+			Title:  appErr.Title,
+			Detail: appErr.Detail,
+		}
+	}
+
+	log.Error().Err(err).Msg("could not convert batch item")
+
+	return &models.ConversionError{
+		Code:  "internal_error",
+		Title: http.StatusText(http.StatusInternalServerError),
+	}
+}
+
+func isDate(s string) bool {
+	_, err := time.Parse(time.DateOnly, s)
+
+	return err == nil
+}
+
+// forEachConcurrently calls fn for every index below n on no more than
+// the given count of goroutines.
+func forEachConcurrently(n int, workers int, fn func(i int)) {
+	if workers > n {
+		workers = n
+	}
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	var (
+		wg   sync.WaitGroup
+		next atomic.Int64
+	)
+
+	wg.Add(workers)
+
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+
+			for i := int(next.Add(1) - 1); i < n; i = int(next.Add(1) - 1) {
+				fn(i)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
@@ -1,76 +1,62 @@
 package endpoint
 
 import (
-	"fmt"
-	"io"
-	"net/http"
 	"net/url"
 	"time"
 
 	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+	sourceclient "github.com/mrumyantsev/currency-converter-app/internal/pkg/source-client"
 	"github.com/mrumyantsev/go-errlib"
-	"github.com/rs/zerolog/log"
 )
 
 const (
-	methodGet       = "GET"
-	headerUserAgent = "User-Agent"
+	// query parameters and date layout of dynamic requests
+	dynamicFromParam  = "date_req1"
+	dynamicToParam    = "date_req2"
+	dynamicCodeParam  = "VAL_NM_RQ"
+	dynamicDateLayout = "02/01/2006"
 )
 
 type CurrenciesFromSourceEndpoint struct {
 	config *config.Config
-	client *http.Client
+	client *sourceclient.Client
 }
 
-func NewCurrenciesFromSourceEndpoint(cfg *config.Config) *CurrenciesFromSourceEndpoint {
+func NewCurrenciesFromSourceEndpoint(cfg *config.Config, client *sourceclient.Client) *CurrenciesFromSourceEndpoint {
 	return &CurrenciesFromSourceEndpoint{
 		config: cfg,
-		client: new(http.Client),
+		client: client,
 	}
 }
 
-func (e *CurrenciesFromSourceEndpoint) CurrenciesFromSource() ([]byte, error) {
-	startTime := time.Now()
-
-	url, err := url.Parse(e.config.CurrencySourceUrl)
-	if err != nil {
-		return nil, errlib.Wrap(err, "could not parse url")
-	}
-
-	req := e.request(url, methodGet)
-
-	resp, err := e.client.Do(req)
-	if err != nil {
-		return nil, errlib.Wrap(err, "could not send request to server")
-	}
-
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, errlib.Wrap(err, "could not read data from response body")
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	elapsedTime := time.Since(startTime)
-
-	log.Debug().Msg("getting http data time overall: " + elapsedTime.String())
+// CurrenciesFromSource returns the daily feed of the latest rates, or
+// sourceclient.ErrNotModified, if it has not changed since it was
+// received last time.
+func (e *CurrenciesFromSourceEndpoint) CurrenciesFromSource() (*sourceclient.Response, error) {
+	return e.fetch(e.config.CurrencySourceUrl, nil)
+}
 
-	return data, nil
+// CurrencyReference returns the reference list of currencies, which have
+// daily rates.
+func (e *CurrenciesFromSourceEndpoint) CurrencyReference() (*sourceclient.Response, error) {
+	return e.fetch(e.config.CurrencyReferenceUrl, nil)
 }
 
-func (e *CurrenciesFromSourceEndpoint) request(url *url.URL, method string) *http.Request {
-	log.Debug().Msg(fmt.Sprintf("using %s protocol in request", e.config.HttpRequestProtocol))
-	log.Debug().Msg(fmt.Sprintf("using user-agent header: %s", e.config.FakeUserAgentHeaderValue))
+// CurrencyDynamic returns the feed of rates of the currency with the code
+// of the source from the first to the last date inclusive.
+func (e *CurrenciesFromSourceEndpoint) CurrencyDynamic(sourceId string, from time.Time, to time.Time) (*sourceclient.Response, error) {
+	return e.fetch(e.config.CurrencyDynamicUrl, url.Values{
+		dynamicFromParam: {from.Format(dynamicDateLayout)},
+		dynamicToParam:   {to.Format(dynamicDateLayout)},
+		dynamicCodeParam: {sourceId},
+	})
+}
 
-	if method == "" {
-		method = methodGet
+func (e *CurrenciesFromSourceEndpoint) fetch(rawUrl string, query url.Values) (*sourceclient.Response, error) {
+	resp, err := e.client.Get(rawUrl, query)
+	if err != nil {
+		return nil, errlib.Wrap(err, "could not get data from source")
 	}
 
-	return &http.Request{
-		Method: method,
-		URL:    url,
-		Proto:  e.config.HttpRequestProtocol,
-		Header: map[string][]string{
-			headerUserAgent: {e.config.FakeUserAgentHeaderValue},
-		},
-	}
+	return resp, nil
 }
@@ -1,34 +1,70 @@
 package endpoint
 
 import (
-	"net/http"
+	"errors"
+	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	apperrors "github.com/mrumyantsev/currency-converter-app/internal/pkg/app-errors"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/calculator"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/clock"
 	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
 	memcache "github.com/mrumyantsev/currency-converter-app/internal/pkg/mem-cache"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
 	"github.com/mrumyantsev/currency-converter-app/internal/pkg/service"
 	"github.com/mrumyantsev/go-errlib"
 	"github.com/rs/zerolog/log"
 )
 
+const (
+	pathParamCode   = "code"
+	queryParamCodes = "codes"
+	queryParamBase  = "base"
+)
+
 type CurrenciesEndpoint struct {
-	config   *config.Config
-	memCache *memcache.MemCache
-	service  service.Currencies
+	config     *config.Config
+	memCache   *memcache.MemCache
+	service    service.Currencies
+	calculator *calculator.Calculator
+	clock      clock.Clock
 }
 
-func NewCurrenciesEndpoint(cfg *config.Config, mc *memcache.MemCache, svc service.Currencies) *CurrenciesEndpoint {
+func NewCurrenciesEndpoint(
+	cfg *config.Config,
+	mc *memcache.MemCache,
+	svc service.Currencies,
+	clk clock.Clock,
+) *CurrenciesEndpoint {
 	return &CurrenciesEndpoint{
-		config:   cfg,
-		memCache: mc,
-		service:  svc,
+		config:     cfg,
+		memCache:   mc,
+		service:    svc,
+		calculator: calculator.New(cfg),
+		clock:      clk,
 	}
 }
 
 func (e *CurrenciesEndpoint) Currencies(ctx echo.Context) error {
-	calculatedCurrencies := e.memCache.CalculatedCurrencies()
+	snapshot, view, err := e.snapshotView(ctx)
+	if err != nil {
+		return err
+	}
+
+	if codes := ctx.QueryParam(queryParamCodes); codes != "" {
+		return e.filteredCurrencies(ctx, snapshot, view, parseCodes(codes))
+	}
+
+	if err = e.sendPrecompressed(ctx, view.Body, snapshot); err != nil {
+		// a client, which accepts no variant of the body, is answered
+		// with an error, which is not a failure to send
+		var httpErr *echo.HTTPError
+
+		if errors.As(err, &httpErr) {
+			return err
+		}
 
-	if err := ctx.JSON(http.StatusOK, calculatedCurrencies); err != nil {
 		errMsg := "could not send reponse data"
 
 		log.Error().Err(err).Msg(errMsg)
@@ -38,3 +74,127 @@ func (e *CurrenciesEndpoint) Currencies(ctx echo.Context) error {
 
 	return nil
 }
+
+// Currency sends the rate of a single currency, which is looked up by its
+// alphabetic or numeric code.
+func (e *CurrenciesEndpoint) Currency(ctx echo.Context) error {
+	snapshot, view, err := e.snapshotView(ctx)
+	if err != nil {
+		return err
+	}
+
+	code := ctx.Param(pathParamCode)
+
+	currency, ok := view.Currency(code)
+	if !ok {
+		return apperrors.UnknownCurrency(code)
+	}
+
+	rate := models.CurrencyRate{
+		SnapshotInfo: view.Rates.SnapshotInfo,
+		Currency:     currency,
+	}
+
+	etag := derivedETag(snapshot.Version, view.Rates.Base, currency.CharCode)
+
+	return e.sendConditionalJSON(ctx, etag, snapshot, rate)
+}
+
+func (e *CurrenciesEndpoint) filteredCurrencies(
+	ctx echo.Context,
+	snapshot *memcache.Snapshot,
+	view *memcache.View,
+	codes []string,
+) error {
+	rates := models.CurrencyRates{
+		SnapshotInfo: view.Rates.SnapshotInfo,
+		Currencies:   make([]models.CalculatedCurrency, 0, len(codes)),
+	}
+
+	for _, code := range codes {
+		currency, ok := view.Currency(code)
+		if !ok {
+			return apperrors.UnknownCurrency(code)
+		}
+
+		rates.Currencies = append(rates.Currencies, currency)
+	}
+
+	etag := derivedETag(snapshot.Version, append([]string{view.Rates.Base}, codes...)...)
+
+	return e.sendConditionalJSON(ctx, etag, snapshot, rates)
+}
+
+// snapshotView returns the fresh snapshot and its view of the base
+// currency requested by the client or of the default one.
+func (e *CurrenciesEndpoint) snapshotView(ctx echo.Context) (*memcache.Snapshot, *memcache.View, error) {
+	snapshot, err := freshSnapshot(e.config, e.memCache, e.clock.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	base := strings.ToUpper(ctx.QueryParam(queryParamBase))
+	if base == "" {
+		return snapshot, snapshot.DefaultView, nil
+	}
+
+	view, err := snapshot.View(base, func() (*memcache.View, error) {
+		rates, err := e.calculator.Rates(
+			snapshot.Info,
+			snapshot.Currencies,
+			snapshot.PreviousCurrencies,
+			base,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		return memcache.NewView(rates)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return snapshot, view, nil
+}
+
+// parseCodes splits a comma separated list of currency codes. Codes are
+// upper cased and repeated ones are dropped, the order is kept.
+func parseCodes(list string) []string {
+	var (
+		codes = []string{}
+		seen  = make(map[string]bool)
+	)
+
+	for _, code := range strings.Split(list, ",") {
+		code = strings.ToUpper(strings.TrimSpace(code))
+
+		if (code == "") || seen[code] {
+			continue
+		}
+
+		seen[code] = true
+		codes = append(codes, code)
+	}
+
+	return codes
+}
+
+// freshSnapshot returns the published snapshot, unless it is missing or
+// the scheduled update, which was to replace it, is overdue by now. The
+// snapshot is published again after failed, rejected and not modified
+// updates, so staleness is told by the time its data was stored, not by
+// the time of the next attempt.
+func freshSnapshot(cfg *config.Config, mc *memcache.MemCache, now time.Time) (*memcache.Snapshot, error) {
+	snapshot := mc.Snapshot()
+	if snapshot == nil {
+		return nil, apperrors.UpstreamUnavailable("currency data has not been loaded yet", nil)
+	}
+
+	if overdue := now.Sub(snapshot.DueDatetime); overdue > cfg.StaleDataThreshold {
+		return nil, apperrors.StaleData("scheduled update is overdue by " +
+			overdue.Round(time.Second).String())
+	}
+
+	return snapshot, nil
+}
@@ -0,0 +1,56 @@
+package endpoint
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	apperrors "github.com/mrumyantsev/currency-converter-app/internal/pkg/app-errors"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+	memcache "github.com/mrumyantsev/currency-converter-app/internal/pkg/mem-cache"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+)
+
+func TestFreshSnapshot(t *testing.T) {
+	var (
+		stored = time.Date(2024, time.March, 1, 13, 30, 5, 0, time.UTC)
+		due    = time.Date(2024, time.March, 2, 13, 30, 0, 0, time.UTC)
+	)
+
+	tests := []struct {
+		name    string
+		now     time.Time
+		next    time.Time
+		isStale bool
+	}{
+		{"before due update", due.Add(-time.Minute), due.Add(-time.Minute), false},
+		{"due update is late within threshold", due.Add(time.Hour), due.Add(time.Hour + time.Minute), false},
+		// updates, which fail, are retried, and the snapshot is published
+		// again with the time of the next attempt
+		{"due update is late over threshold", due.Add(time.Hour + time.Second), due.Add(time.Hour + time.Minute), true},
+		{"days after due update", due.AddDate(0, 0, 2), due.AddDate(0, 0, 2).Add(time.Minute), true},
+	}
+
+	cfg := &config.Config{StaleDataThreshold: time.Hour}
+
+	view := &memcache.View{Rates: models.CurrencyRates{SnapshotInfo: models.SnapshotInfo{Base: "RUB"}}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mc := memcache.New()
+
+			mc.SetSnapshot(memcache.NewSnapshot("1", stored, due, tt.next, models.SnapshotInfo{},
+				models.Currencies{}, models.Currencies{}, view))
+
+			_, err := freshSnapshot(cfg, mc, tt.now)
+
+			if isStale := errors.Is(err, apperrors.ErrStaleData); isStale != tt.isStale {
+				t.Fatalf("stale = %t, want %t, error: %v", isStale, tt.isStale, err)
+			}
+		})
+	}
+
+	if _, err := freshSnapshot(cfg, memcache.New(), due); !errors.Is(err, apperrors.ErrUpstreamUnavailable) {
+		t.Fatalf("error without snapshot = %v, want upstream unavailable", err)
+	}
+}
@@ -1,32 +1,103 @@
 package endpoint
 
 import (
+	"time"
+
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/clock"
 	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
 	memcache "github.com/mrumyantsev/currency-converter-app/internal/pkg/mem-cache"
 	"github.com/mrumyantsev/currency-converter-app/internal/pkg/service"
+	sourceclient "github.com/mrumyantsev/currency-converter-app/internal/pkg/source-client"
 )
 
 type CurrenciesFromSource interface {
-	CurrenciesFromSource() ([]byte, error)
+	CurrenciesFromSource() (*sourceclient.Response, error)
+	CurrencyReference() (*sourceclient.Response, error)
+	CurrencyDynamic(sourceId string, from time.Time, to time.Time) (*sourceclient.Response, error)
 }
 
 type Currencies interface {
 	Currencies(ctx echo.Context) error
+	Currency(ctx echo.Context) error
+	Matrix(ctx echo.Context) error
+}
+
+type Convert interface {
+	ConvertBatch(ctx echo.Context) error
+}
+
+type History interface {
+	Stats(ctx echo.Context) error
+	Averages(ctx echo.Context) error
+	Candles(ctx echo.Context) error
+}
+
+type Export interface {
+	Export(ctx echo.Context) error
+}
+
+type OpenApi interface {
+	OpenApi(ctx echo.Context) error
 }
 
 type Endpoint struct {
+	config               *config.Config
 	CurrenciesFromSource CurrenciesFromSource
 	Currencies           Currencies
+	Convert              Convert
+	History              History
+	Export               Export
+	OpenApi              OpenApi
 }
 
-func New(cfg *config.Config, mc *memcache.MemCache, svc *service.Service) *Endpoint {
+func New(
+	cfg *config.Config,
+	mc *memcache.MemCache,
+	svc *service.Service,
+	client *sourceclient.Client,
+	clk clock.Clock,
+) *Endpoint {
 	return &Endpoint{
-		CurrenciesFromSource: NewCurrenciesFromSourceEndpoint(cfg),
-		Currencies:           NewCurrenciesEndpoint(cfg, mc, svc.Currencies),
+		config:               cfg,
+		CurrenciesFromSource: NewCurrenciesFromSourceEndpoint(cfg, client),
+		Currencies:           NewCurrenciesEndpoint(cfg, mc, svc.Currencies, clk),
+		Convert:              NewConvertEndpoint(cfg, mc, svc.History, clk),
+		History:              NewHistoryEndpoint(cfg, svc.DailyRates, svc.AverageRates),
+		Export:               NewExportEndpoint(cfg, svc.Export),
+		OpenApi:              NewOpenApiEndpoint(cfg),
 	}
 }
 
+const (
+	apiV1Prefix = "/api/v1"
+
+	convertBatchPath = "/convert/batch"
+)
+
+// ownBodyLimitPaths are the routes, which accept larger bodies than the
+// server does by default, and have their own body limit.
+var ownBodyLimitPaths = map[string]bool{
+	apiV1Prefix + convertBatchPath: true,
+}
+
+// HasOwnBodyLimit reports whether the route of the request has its own
+// body limit, so the default one must be skipped.
+func (e *Endpoint) HasOwnBodyLimit(ctx echo.Context) bool {
+	return ownBodyLimitPaths[ctx.Path()]
+}
+
 func (e *Endpoint) InitRoutes(echo *echo.Echo) {
-	echo.GET("/currencies", e.Currencies.Currencies)
+	v1 := echo.Group(apiV1Prefix)
+
+	v1.GET("/currencies", e.Currencies.Currencies)
+	v1.GET("/currencies/:code", e.Currencies.Currency)
+	v1.GET("/currencies/:code/stats", e.History.Stats)
+	v1.GET("/currencies/:code/averages", e.History.Averages)
+	v1.GET("/currencies/:code/candles", e.History.Candles)
+	v1.GET("/matrix", e.Currencies.Matrix)
+	v1.POST(convertBatchPath, e.Convert.ConvertBatch, middleware.BodyLimit(e.config.BatchBodyLimit))
+	v1.GET("/export", e.Export.Export)
+	v1.GET("/openapi.json", e.OpenApi.OpenApi)
 }
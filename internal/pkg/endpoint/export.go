@@ -0,0 +1,148 @@
+package endpoint
+
+import (
+	"bufio"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/calculator"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/exporter"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/service"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	queryParamColumns   = "columns"
+	queryParamDecimal   = "decimal"
+	queryParamDelimiter = "delimiter"
+
+	exportBufferSize = 64 * 1024
+
+	headerRetryAfter = "Retry-After"
+
+	// exportRetryAfter is the number of seconds, after which clients are
+	// told to retry, when all the export slots are busy
+	exportRetryAfter = "30"
+)
+
+type ExportEndpoint struct {
+	config     *config.Config
+	service    service.Export
+	calculator *calculator.Calculator
+
+	// slots limits the number of concurrent exports, as each of them
+	// holds a database transaction while it is sent
+	slots chan struct{}
+}
+
+func NewExportEndpoint(cfg *config.Config, svc service.Export) *ExportEndpoint {
+	return &ExportEndpoint{
+		config:     cfg,
+		service:    svc,
+		calculator: calculator.New(cfg),
+		slots:      make(chan struct{}, cfg.ExportMaxConcurrent),
+	}
+}
+
+// Export streams daily rates of the requested currencies, or of all of
+// them, from the first to the last date inclusive as a file. Rows are
+// sent as they are read, so errors after the first sent bytes can only
+// be logged, and abort the connection. When the maximum number of exports
+// is in progress, the request is rejected at once, instead of waiting.
+func (e *ExportEndpoint) Export(ctx echo.Context) error {
+	from, to, err := period(ctx)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case e.slots <- struct{}{}:
+		defer func() { <-e.slots }()
+	default:
+		ctx.Response().Header().Set(headerRetryAfter, exportRetryAfter)
+
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "too many exports are in progress")
+	}
+
+	var columns []string
+
+	if list := ctx.QueryParam(queryParamColumns); list != "" {
+		columns = parseColumns(list)
+	}
+
+	opts, err := exporter.NewOptions(
+		ctx.QueryParam(queryParamFormat),
+		columns,
+		ctx.QueryParam(queryParamDecimal),
+		ctx.QueryParam(queryParamDelimiter),
+	)
+	if err != nil {
+		return err
+	}
+
+	// exports take longer than the server write timeout allows. The deadline
+	// is of the network, so it is taken on the wall clock.
+	err = http.NewResponseController(ctx.Response()).SetWriteDeadline(time.Now().Add(e.config.ExportWriteTimeout))
+	if err != nil {
+		log.Warn().Err(err).Msg("could not extend write deadline for exporting")
+	}
+
+	header := ctx.Response().Header()
+
+	header.Set(echo.HeaderContentType, opts.ContentType())
+	header.Set(echo.HeaderContentDisposition,
+		`attachment; filename="export-`+from+`-`+to+opts.FileExtension()+`"`)
+
+	var (
+		buffer = bufio.NewWriterSize(ctx.Response(), exportBufferSize)
+		export = exporter.New(buffer, opts, e.calculator)
+	)
+
+	err = e.service.Stream(parseCodes(ctx.QueryParam(queryParamCodes)), from, to, func(row models.ExportRow) error {
+		return export.Write(row)
+	})
+	if err == nil {
+		err = export.Close()
+	}
+	if err == nil {
+		err = buffer.Flush()
+	}
+
+	if err != nil {
+		if !ctx.Response().Committed {
+			header.Del(echo.HeaderContentType)
+			header.Del(echo.HeaderContentDisposition)
+
+			return err
+		}
+
+		log.Error().Err(err).Str("path", ctx.Request().URL.Path).Msg("export was interrupted")
+
+		// aborts the connection, so the cut file is not taken for a whole one
+		panic(http.ErrAbortHandler)
+	}
+
+	if !ctx.Response().Committed {
+		ctx.Response().WriteHeader(http.StatusOK)
+	}
+
+	return nil
+}
+
+// parseColumns splits the comma separated list of columns, keeping their
+// order.
+func parseColumns(list string) []string {
+	columns := []string{}
+
+	for _, column := range strings.Split(list, ",") {
+		if column = strings.TrimSpace(column); column != "" {
+			columns = append(columns, column)
+		}
+	}
+
+	return columns
+}
@@ -0,0 +1,166 @@
+package endpoint
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	apperrors "github.com/mrumyantsev/currency-converter-app/internal/pkg/app-errors"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/calculator"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/service"
+	"github.com/mrumyantsev/go-errlib"
+)
+
+const (
+	queryParamFrom     = "from"
+	queryParamTo       = "to"
+	queryParamPeriod   = "period"
+	queryParamInterval = "interval"
+
+	statsVersion        = 1
+	averageRatesVersion = 1
+	candlesVersion      = 1
+)
+
+type HistoryEndpoint struct {
+	config              *config.Config
+	service             service.DailyRates
+	averageRatesService service.AverageRates
+	calculator          *calculator.Calculator
+}
+
+func NewHistoryEndpoint(
+	cfg *config.Config,
+	svc service.DailyRates,
+	averageRatesSvc service.AverageRates,
+) *HistoryEndpoint {
+	return &HistoryEndpoint{
+		config:              cfg,
+		service:             svc,
+		averageRatesService: averageRatesSvc,
+		calculator:          calculator.New(cfg),
+	}
+}
+
+// Stats sends statistics of daily rates of a currency over the period
+// from the first to the last date inclusive.
+func (e *HistoryEndpoint) Stats(ctx echo.Context) error {
+	code := ctx.Param(pathParamCode)
+
+	from, to, err := period(ctx)
+	if err != nil {
+		return err
+	}
+
+	charCode, dailyRates, err := e.service.Get(code, from, to)
+	if err != nil {
+		return err
+	}
+
+	if len(dailyRates) == 0 {
+		return apperrors.NoDataForDate(from + ".." + to)
+	}
+
+	stats, err := e.calculator.Stats(dailyRates)
+	if err != nil {
+		return err
+	}
+
+	stats.Version = statsVersion
+	stats.Code = charCode
+	stats.Base = calculator.SourceCurrency
+	stats.From = from
+	stats.To = to
+
+	return ctx.JSON(http.StatusOK, stats)
+}
+
+// Averages sends average rates of a currency over the periods of the
+// requested kind, which overlap the dates from the first to the last.
+func (e *HistoryEndpoint) Averages(ctx echo.Context) error {
+	code := ctx.Param(pathParamCode)
+
+	from, to, err := period(ctx)
+	if err != nil {
+		return err
+	}
+
+	averagePeriod := ctx.QueryParam(queryParamPeriod)
+	if averagePeriod == "" {
+		averagePeriod = models.PeriodMonth
+	}
+
+	charCode, averages, err := e.averageRatesService.Get(code, averagePeriod, from, to)
+	if err != nil {
+		return err
+	}
+
+	for i := range averages {
+		average, err := calculator.ParseDecimal(averages[i].Average)
+		if err != nil {
+			return errlib.Wrap(err, "could not parse average rate")
+		}
+
+		averages[i].Average = e.calculator.Format(average)
+	}
+
+	return ctx.JSON(http.StatusOK, models.AverageRates{
+		Version:  averageRatesVersion,
+		Code:     charCode,
+		Base:     calculator.SourceCurrency,
+		Period:   averagePeriod,
+		From:     from,
+		To:       to,
+		Averages: averages,
+	})
+}
+
+// Candles sends open, high, low and close rates of a currency over the
+// intervals, which overlap the dates from the first to the last. Gaps on
+// weekends and holidays are filled with the last published rate.
+func (e *HistoryEndpoint) Candles(ctx echo.Context) error {
+	code := ctx.Param(pathParamCode)
+
+	from, to, err := period(ctx)
+	if err != nil {
+		return err
+	}
+
+	interval := ctx.QueryParam(queryParamInterval)
+	if interval == "" {
+		interval = models.IntervalWeek
+	}
+
+	charCode, dailyRates, err := e.service.GetCarriedForward(code, from, to)
+	if err != nil {
+		return err
+	}
+
+	candles, err := e.calculator.Candles(dailyRates, interval)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, models.Candles{
+		Version:  candlesVersion,
+		Code:     charCode,
+		Base:     calculator.SourceCurrency,
+		Interval: interval,
+		From:     from,
+		To:       to,
+		Candles:  candles,
+	})
+}
+
+// period returns the first and the last dates of the requested period,
+// which formats are checked by the request validation.
+func period(ctx echo.Context) (string, string, error) {
+	from, to := ctx.QueryParam(queryParamFrom), ctx.QueryParam(queryParamTo)
+
+	if from > to {
+		return "", "", apperrors.InvalidRequest("period starts after it ends: " + from + ".." + to)
+	}
+
+	return from, to, nil
+}
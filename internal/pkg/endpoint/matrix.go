@@ -0,0 +1,87 @@
+package endpoint
+
+import (
+	"bytes"
+	"encoding/csv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/calculator"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+	"github.com/mrumyantsev/go-errlib"
+)
+
+const (
+	queryParamFormat = "format"
+
+	formatJson = "json"
+	formatCsv  = "csv"
+
+	mimeTextCsvCharsetUTF8 = "text/csv; charset=UTF-8"
+)
+
+// Matrix sends cross rates between every pair of the requested currencies,
+// all of them computed from the same snapshot. The matrix is sent as JSON
+// or as CSV, which opens in a spreadsheet as is.
+func (e *CurrenciesEndpoint) Matrix(ctx echo.Context) error {
+	snapshot, err := freshSnapshot(e.config, e.memCache, e.clock.Now())
+	if err != nil {
+		return err
+	}
+
+	var codes []string
+
+	if list := ctx.QueryParam(queryParamCodes); list != "" {
+		codes = parseCodes(list)
+	}
+
+	matrix := models.CurrencyMatrix{SnapshotInfo: snapshot.Info}
+
+	matrix.Base = calculator.SourceCurrency
+
+	if matrix.Codes, matrix.Rates, err = e.calculator.Matrix(snapshot.Currencies, codes); err != nil {
+		return err
+	}
+
+	format := ctx.QueryParam(queryParamFormat)
+	if format == "" {
+		format = formatJson
+	}
+
+	etag := derivedETag(snapshot.Version, append([]string{"matrix", format}, matrix.Codes...)...)
+
+	if format == formatJson {
+		return e.sendConditionalJSON(ctx, etag, snapshot, matrix)
+	}
+
+	data, err := matrixCsv(matrix)
+	if err != nil {
+		return errlib.Wrap(err, "could not write matrix to csv")
+	}
+
+	ctx.Response().Header().Set(echo.HeaderContentDisposition,
+		`attachment; filename="matrix-`+matrix.EffectiveDate+`.csv"`)
+
+	return e.sendConditionalBlob(ctx, etag, snapshot, mimeTextCsvCharsetUTF8, data)
+}
+
+// matrixCsv writes the matrix as a table with currency codes in the
+// header row and in the first column.
+func matrixCsv(matrix models.CurrencyMatrix) ([]byte, error) {
+	var buf bytes.Buffer
+
+	w := csv.NewWriter(&buf)
+
+	if err := w.Write(append([]string{""}, matrix.Codes...)); err != nil {
+		return nil, err
+	}
+
+	for i, row := range matrix.Rates {
+		if err := w.Write(append([]string{matrix.Codes[i]}, row...)); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+
+	return buf.Bytes(), w.Error()
+}
@@ -0,0 +1,21 @@
+package endpoint
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/openapi"
+)
+
+type OpenApiEndpoint struct {
+	config *config.Config
+}
+
+func NewOpenApiEndpoint(cfg *config.Config) *OpenApiEndpoint {
+	return &OpenApiEndpoint{config: cfg}
+}
+
+func (e *OpenApiEndpoint) OpenApi(ctx echo.Context) error {
+	return ctx.Blob(http.StatusOK, echo.MIMEApplicationJSONCharsetUTF8, openapi.Document())
+}
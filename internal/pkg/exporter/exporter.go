@@ -0,0 +1,314 @@
+package exporter
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	apperrors "github.com/mrumyantsev/currency-converter-app/internal/pkg/app-errors"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/calculator"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+	"github.com/mrumyantsev/go-errlib"
+)
+
+// Export formats. The xlsx-csv one is a CSV, which spreadsheets with
+// decimal commas open as is: it has a byte order mark, CRLF line
+// endings, semicolons as delimiters and commas as decimal separators.
+const (
+	FormatCsv     = "csv"
+	FormatXlsxCsv = "xlsx-csv"
+	FormatJsonl   = "jsonl"
+)
+
+// Export columns.
+const (
+	ColumnDate     = "date"
+	ColumnNumCode  = "num_code"
+	ColumnCharCode = "char_code"
+	ColumnName     = "name"
+	ColumnNominal  = "nominal"
+	ColumnValue    = "value"
+	ColumnUnitRate = "unit_rate"
+)
+
+const (
+	delimiterTab = "tab"
+
+	jsonLineSize = 256
+
+	byteOrderMark = "\uFEFF"
+)
+
+// AllColumns are the columns exported by default, in their order.
+var AllColumns = []string{
+	ColumnDate,
+	ColumnNumCode,
+	ColumnCharCode,
+	ColumnName,
+	ColumnNominal,
+	ColumnValue,
+	ColumnUnitRate,
+}
+
+// Options define the representation of exported rows. The decimal
+// separator and the delimiter apply to CSV formats only.
+type Options struct {
+	Format           string
+	Columns          []string
+	DecimalSeparator string
+	Delimiter        rune
+}
+
+// NewOptions validates the options. Empty ones take defaults of the
+// format, and the delimiter may be given as "tab".
+func NewOptions(format string, columns []string, decimalSeparator string, delimiter string) (Options, error) {
+	opts := Options{
+		Format:           format,
+		Columns:          columns,
+		DecimalSeparator: decimalSeparator,
+	}
+
+	switch format {
+	case "", FormatCsv:
+		opts.Format = FormatCsv
+		opts.Delimiter = ','
+
+		if opts.DecimalSeparator == "" {
+			opts.DecimalSeparator = "."
+		}
+	case FormatXlsxCsv:
+		opts.Delimiter = ';'
+
+		if opts.DecimalSeparator == "" {
+			opts.DecimalSeparator = ","
+		}
+	case FormatJsonl:
+		opts.DecimalSeparator = "."
+	default:
+		return opts, apperrors.InvalidRequest("unknown export format: " + format)
+	}
+
+	if len(opts.Columns) == 0 {
+		opts.Columns = AllColumns
+	}
+
+	for _, column := range opts.Columns {
+		if !isColumn(column) {
+			return opts, apperrors.InvalidRequest("unknown export column: " + column)
+		}
+	}
+
+	if (opts.DecimalSeparator != ".") && (opts.DecimalSeparator != ",") {
+		return opts, apperrors.InvalidRequest("decimal separator must be a dot or a comma")
+	}
+
+	switch {
+	case delimiter == "":
+	case delimiter == delimiterTab:
+		opts.Delimiter = '\t'
+	case utf8.RuneCountInString(delimiter) == 1:
+		opts.Delimiter, _ = utf8.DecodeRuneInString(delimiter)
+	default:
+		return opts, apperrors.InvalidRequest("delimiter must be a single character or tab")
+	}
+
+	if (opts.Format != FormatJsonl) && (string(opts.Delimiter) == opts.DecimalSeparator) {
+		return opts, apperrors.InvalidRequest("delimiter must differ from decimal separator")
+	}
+
+	if (opts.Delimiter == '"') || (opts.Delimiter == '\r') || (opts.Delimiter == '\n') ||
+		(opts.Delimiter == utf8.RuneError) {
+		return opts, apperrors.InvalidRequest("delimiter is not allowed: " + strconv.QuoteRune(opts.Delimiter))
+	}
+
+	return opts, nil
+}
+
+// ContentType returns the media type of the exported data.
+func (o Options) ContentType() string {
+	if o.Format == FormatJsonl {
+		return "application/x-ndjson"
+	}
+
+	return "text/csv; charset=UTF-8"
+}
+
+// FileExtension returns the extension of a file with the exported data.
+func (o Options) FileExtension() string {
+	if o.Format == FormatJsonl {
+		return ".jsonl"
+	}
+
+	return ".csv"
+}
+
+// An Exporter writes rows one by one, so any number of them can be
+// exported without holding them in memory.
+type Exporter struct {
+	options         Options
+	calculator      *calculator.Calculator
+	writer          io.Writer
+	csvWriter       *csv.Writer
+	jsonLine        []byte
+	isHeaderWritten bool
+}
+
+func New(w io.Writer, opts Options, calc *calculator.Calculator) *Exporter {
+	e := &Exporter{
+		options:    opts,
+		calculator: calc,
+		writer:     w,
+	}
+
+	if opts.Format == FormatJsonl {
+		e.jsonLine = make([]byte, 0, jsonLineSize)
+	} else {
+		e.csvWriter = csv.NewWriter(w)
+		e.csvWriter.Comma = opts.Delimiter
+		e.csvWriter.UseCRLF = opts.Format == FormatXlsxCsv
+	}
+
+	return e
+}
+
+// Write exports the row. The header is written before the first one.
+func (e *Exporter) Write(row models.ExportRow) error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+
+	values := make([]any, len(e.options.Columns))
+
+	for i, column := range e.options.Columns {
+		value, err := e.value(row, column)
+		if err != nil {
+			return err
+		}
+
+		values[i] = value
+	}
+
+	if e.jsonLine != nil {
+		return e.writeJsonLine(values)
+	}
+
+	record := make([]string, len(values))
+
+	for i, value := range values {
+		switch v := value.(type) {
+		case int:
+			record[i] = strconv.Itoa(v)
+		case string:
+			record[i] = v
+		}
+	}
+
+	return e.csvWriter.Write(record)
+}
+
+// Close writes the header, if there were no rows, and flushes the
+// buffered data. It does not close the underlying writer.
+func (e *Exporter) Close() error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+
+	if e.csvWriter != nil {
+		e.csvWriter.Flush()
+
+		return e.csvWriter.Error()
+	}
+
+	return nil
+}
+
+// writeJsonLine writes the values as an object with keys in the order
+// of the columns, which a map would not keep.
+func (e *Exporter) writeJsonLine(values []any) error {
+	line := append(e.jsonLine[:0], '{')
+
+	for i, column := range e.options.Columns {
+		if i > 0 {
+			line = append(line, ',')
+		}
+
+		line = strconv.AppendQuote(line, column)
+		line = append(line, ':')
+
+		value, err := json.Marshal(values[i])
+		if err != nil {
+			return errlib.Wrap(err, "could not marshal exported value")
+		}
+
+		line = append(line, value...)
+	}
+
+	line = append(line, '}', '\n')
+
+	e.jsonLine = line
+
+	if _, err := e.writer.Write(line); err != nil {
+		return errlib.Wrap(err, "could not write json line")
+	}
+
+	return nil
+}
+
+func (e *Exporter) writeHeader() error {
+	if e.isHeaderWritten || (e.csvWriter == nil) {
+		return nil
+	}
+
+	e.isHeaderWritten = true
+
+	if e.options.Format == FormatXlsxCsv {
+		if _, err := io.WriteString(e.writer, byteOrderMark); err != nil {
+			return errlib.Wrap(err, "could not write byte order mark")
+		}
+	}
+
+	return e.csvWriter.Write(e.options.Columns)
+}
+
+func (e *Exporter) value(row models.ExportRow, column string) (any, error) {
+	switch column {
+	case ColumnDate:
+		return row.EffectiveDate, nil
+	case ColumnNumCode:
+		return row.NumCode, nil
+	case ColumnCharCode:
+		return row.CharCode, nil
+	case ColumnName:
+		return row.Name, nil
+	case ColumnNominal:
+		return row.Nominal, nil
+	case ColumnValue:
+		return e.decimal(row.Value)
+	case ColumnUnitRate:
+		return e.decimal(row.UnitValue)
+	}
+
+	return nil, nil
+}
+
+func (e *Exporter) decimal(s string) (string, error) {
+	r, err := calculator.ParseDecimal(s)
+	if err != nil {
+		return "", errlib.Wrap(err, "could not parse exported value")
+	}
+
+	return strings.Replace(e.calculator.Format(r), ".", e.options.DecimalSeparator, 1), nil
+}
+
+func isColumn(column string) bool {
+	for _, c := range AllColumns {
+		if c == column {
+			return true
+		}
+	}
+
+	return false
+}
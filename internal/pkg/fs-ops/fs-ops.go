@@ -64,6 +64,10 @@ func (f *FsOps) OverwriteCurrencyDataFile(data []byte) error {
 
 func makeDirIfNotExist(path string) error {
 	_, err := os.Stat(path)
+	if err == nil {
+		return nil
+	}
+
 	if !errors.Is(err, os.ErrNotExist) {
 		return errlib.Wrap(err, "could not check for save directory existence")
 	}
@@ -0,0 +1,477 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/calculator"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/clock"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+	xmlparser "github.com/mrumyantsev/currency-converter-app/internal/pkg/xml-parser"
+	"github.com/mrumyantsev/go-errlib"
+)
+
+const (
+	xmlExtension   = ".xml"
+	feedDateLayout = "02.01.2006"
+
+	csvFields = 4
+
+	// maxValueScale and maxValueDigits follow the type of stored values
+	maxValueScale  = 4
+	maxValueDigits = 12
+)
+
+var (
+	charCodeRegexp = regexp.MustCompile(`^[A-Z]{3}$`)
+
+	maxValue = new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(maxValueDigits-maxValueScale), nil))
+)
+
+// An Issue is a problem of imported data. Line is zero, if the source
+// has no lines to point at.
+type Issue struct {
+	Source  string
+	Line    int
+	Message string
+}
+
+func (i Issue) String() string {
+	if i.Line == 0 {
+		return i.Source + ": " + i.Message
+	}
+
+	return i.Source + ":" + strconv.Itoa(i.Line) + ": " + i.Message
+}
+
+// A ValidationError lists all the issues found in imported data, so they
+// can be fixed at once.
+type ValidationError struct {
+	Issues []Issue
+}
+
+func (e *ValidationError) Error() string {
+	return strconv.Itoa(len(e.Issues)) + " issues found in imported data, the first is " +
+		e.Issues[0].String()
+}
+
+// An Importer reads snapshots of historical rates from daily XML feeds
+// of the source, saved as files, from feeds of single currencies over a
+// range of dates, or from a CSV of dates, codes, nominals and values, and
+// validates them.
+type Importer struct {
+	config    *config.Config
+	xmlParser *xmlparser.XmlParser
+	clock     clock.Clock
+	issues    []Issue
+}
+
+func New(cfg *config.Config, parser *xmlparser.XmlParser, clk clock.Clock) *Importer {
+	return &Importer{
+		config:    cfg,
+		xmlParser: parser,
+		clock:     clk,
+	}
+}
+
+// ReadXmlDir reads every XML file of the directory as a feed of a single
+// date or an archive of feeds of many dates. Snapshots are returned in
+// the order of dates.
+func (i *Importer) ReadXmlDir(dir string) ([]models.DatedCurrencies, error) {
+	i.issues = nil
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, errlib.Wrap(err, "could not read import directory")
+	}
+
+	var (
+		snapshots []models.DatedCurrencies
+		sources   = make(map[string]string)
+	)
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), xmlExtension) {
+			continue
+		}
+
+		source := entry.Name()
+
+		data, err := os.ReadFile(filepath.Join(dir, source))
+		if err != nil {
+			return nil, errlib.Wrap(err, "could not read import file "+source)
+		}
+
+		days, err := i.xmlParser.ParseArchive(data)
+		if err != nil {
+			i.parseIssues(source, err)
+
+			continue
+		}
+
+		for _, currencies := range days {
+			date, err := time.Parse(feedDateLayout, currencies.Date)
+			if err != nil {
+				i.issue(source, 0, "feed date is not in DD.MM.YYYY format: "+currencies.Date)
+
+				continue
+			}
+
+			effectiveDate := date.Format(time.DateOnly)
+
+			if other, ok := sources[effectiveDate]; ok {
+				i.issue(source, 0, "feed of "+effectiveDate+" is already read from "+other)
+
+				continue
+			}
+
+			sources[effectiveDate] = source
+
+			seen := make(map[int]bool)
+
+			for _, currency := range currencies.Currencies {
+				i.validateCurrency(source, 0, currency, seen)
+			}
+
+			i.validateDate(source, 0, effectiveDate)
+
+			snapshots = append(snapshots, models.DatedCurrencies{
+				EffectiveDate: effectiveDate,
+				Currencies:    currencies,
+			})
+		}
+	}
+
+	if len(sources) == 0 && len(i.issues) == 0 {
+		return nil, errors.New("there are no feeds in xml files of import directory: " + dir)
+	}
+
+	return i.result(snapshots)
+}
+
+// ReadCsv reads rows of a date, an alphabetic or numeric code of a known
+// currency, a nominal and a value. Dates are either in YYYY-MM-DD or in
+// DD.MM.YYYY format, values may have decimal commas, and a header line is
+// skipped. Rows are grouped into snapshots by dates, which are returned
+// in their order.
+func (i *Importer) ReadCsv(r io.Reader, source string, delimiter rune, info []models.Currency) ([]models.DatedCurrencies, error) {
+	i.issues = nil
+
+	known := make(map[string]models.Currency, 2*len(info))
+
+	for _, currency := range info {
+		known[currency.CharCode] = currency
+		known[strconv.Itoa(currency.NumCode)] = currency
+	}
+
+	reader := csv.NewReader(r)
+
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = csvFields
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+
+	var (
+		byDate = make(map[string]*models.DatedCurrencies)
+		seen   = make(map[string]map[int]bool)
+	)
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		var parseErr *csv.ParseError
+
+		if errors.As(err, &parseErr) {
+			i.issue(source, parseErr.Line, parseErr.Err.Error())
+
+			if errors.Is(parseErr.Err, csv.ErrFieldCount) {
+				continue
+			}
+
+			break
+		}
+		if err != nil {
+			return nil, errlib.Wrap(err, "could not read import file "+source)
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		date, ok := parseDate(record[0])
+		if !ok {
+			// the first line may be a header
+			if line != 1 {
+				i.issue(source, line, "date is neither in YYYY-MM-DD nor in DD.MM.YYYY format: "+record[0])
+			}
+
+			continue
+		}
+
+		code := strings.ToUpper(strings.TrimSpace(record[1]))
+
+		if numCode, err := strconv.Atoi(code); err == nil {
+			code = strconv.Itoa(numCode)
+		}
+
+		currency, ok := known[code]
+		if !ok {
+			i.issue(source, line, "unknown currency: "+record[1])
+
+			continue
+		}
+
+		nominal, err := strconv.Atoi(strings.TrimSpace(record[2]))
+		if err != nil {
+			i.issue(source, line, "nominal is not an integer: "+record[2])
+
+			continue
+		}
+
+		value, err := models.NewDecimal(record[3])
+		if err != nil {
+			i.issue(source, line, "value is "+err.Error())
+
+			continue
+		}
+
+		currency.Multiplier = nominal
+		currency.Value = value
+
+		snapshot, ok := byDate[date]
+		if !ok {
+			snapshot = &models.DatedCurrencies{EffectiveDate: date}
+			byDate[date] = snapshot
+			seen[date] = make(map[int]bool)
+
+			i.validateDate(source, line, date)
+		}
+
+		if i.validateCurrency(source, line, currency, seen[date]) {
+			snapshot.Currencies.Currencies = append(snapshot.Currencies.Currencies, currency)
+		}
+	}
+
+	snapshots := make([]models.DatedCurrencies, 0, len(byDate))
+
+	for _, snapshot := range byDate {
+		snapshots = append(snapshots, *snapshot)
+	}
+
+	if len(snapshots) == 0 && len(i.issues) == 0 {
+		return nil, errors.New("there are no rows in import file: " + source)
+	}
+
+	return i.result(snapshots)
+}
+
+// ReadDynamics groups rates of the feeds of single currencies into
+// snapshots by dates, which are returned in their order. Currencies are
+// told by their codes in the source, and their codes and names are taken
+// from the known ones.
+func (i *Importer) ReadDynamics(dynamics []models.CurrencyDynamic, info []models.Currency) ([]models.DatedCurrencies, error) {
+	i.issues = nil
+
+	known := make(map[string]models.Currency, len(info))
+
+	for _, currency := range info {
+		if currency.SourceId != "" {
+			known[currency.SourceId] = currency
+		}
+	}
+
+	var (
+		byDate = make(map[string]*models.DatedCurrencies)
+		seen   = make(map[string]map[int]bool)
+	)
+
+	for _, dynamic := range dynamics {
+		source := "dynamic of " + dynamic.SourceId
+
+		for _, rate := range dynamic.Rates {
+			sourceId := rate.SourceId
+			if sourceId == "" {
+				sourceId = dynamic.SourceId
+			}
+
+			currency, ok := known[sourceId]
+			if !ok {
+				i.issue(source, 0, "unknown currency: "+sourceId)
+
+				continue
+			}
+
+			date, err := time.Parse(feedDateLayout, rate.Date)
+			if err != nil {
+				i.issue(source, 0, "date is not in DD.MM.YYYY format: "+rate.Date)
+
+				continue
+			}
+
+			effectiveDate := date.Format(time.DateOnly)
+
+			currency.Multiplier = rate.Multiplier
+			currency.Value = rate.Value
+			currency.UnitRate = rate.UnitRate
+
+			snapshot, ok := byDate[effectiveDate]
+			if !ok {
+				snapshot = &models.DatedCurrencies{
+					EffectiveDate: effectiveDate,
+					Currencies: models.Currencies{
+						Date: rate.Date,
+						Name: dynamic.Name,
+					},
+				}
+				byDate[effectiveDate] = snapshot
+				seen[effectiveDate] = make(map[int]bool)
+
+				i.validateDate(source, 0, effectiveDate)
+			}
+
+			if i.validateCurrency(source, 0, currency, seen[effectiveDate]) {
+				snapshot.Currencies.Currencies = append(snapshot.Currencies.Currencies, currency)
+			}
+		}
+	}
+
+	snapshots := make([]models.DatedCurrencies, 0, len(byDate))
+
+	for _, snapshot := range byDate {
+		snapshots = append(snapshots, *snapshot)
+	}
+
+	if len(snapshots) == 0 && len(i.issues) == 0 {
+		return nil, errors.New("there are no rates in dynamics of currencies")
+	}
+
+	return i.result(snapshots)
+}
+
+// validateCurrency reports, whether the currency fits the storage and is
+// not a repeated one of its snapshot.
+func (i *Importer) validateCurrency(source string, line int, currency models.Currency, seen map[int]bool) bool {
+	isValid := true
+
+	invalid := func(message string) {
+		i.issue(source, line, currency.CharCode+": "+message)
+
+		isValid = false
+	}
+
+	if (currency.NumCode < 1) || (currency.NumCode > 999) {
+		invalid("numeric code is out of range: " + strconv.Itoa(currency.NumCode))
+	}
+
+	if !charCodeRegexp.MatchString(currency.CharCode) {
+		invalid("alphabetic code is not three capital letters")
+	}
+
+	if strings.TrimSpace(currency.Name) == "" {
+		invalid("name is empty")
+	}
+
+	if currency.Multiplier < 1 {
+		invalid("nominal is not positive: " + strconv.Itoa(currency.Multiplier))
+	}
+
+	if message := validateValue(string(currency.Value)); message != "" {
+		invalid(message)
+	}
+
+	if seen[currency.NumCode] {
+		invalid("currency is repeated")
+	}
+
+	seen[currency.NumCode] = true
+
+	return isValid
+}
+
+func (i *Importer) validateDate(source string, line int, date string) {
+	if date > i.clock.Now().In(i.config.UpdateLocation).Format(time.DateOnly) {
+		i.issue(source, line, "date is in the future: "+date)
+	}
+}
+
+// parseIssues reports every invalid element of the feed, if the parser
+// has found them, or the whole error otherwise.
+func (i *Importer) parseIssues(source string, err error) {
+	var parseErr *xmlparser.ParseError
+
+	if !errors.As(err, &parseErr) {
+		i.issue(source, 0, "could not parse feed: "+err.Error())
+
+		return
+	}
+
+	for _, elementErr := range parseErr.Errors {
+		i.issue(source, elementErr.Line, elementErr.Err.Error())
+	}
+}
+
+func (i *Importer) issue(source string, line int, message string) {
+	i.issues = append(i.issues, Issue{
+		Source:  source,
+		Line:    line,
+		Message: message,
+	})
+}
+
+func (i *Importer) result(snapshots []models.DatedCurrencies) ([]models.DatedCurrencies, error) {
+	if len(i.issues) > 0 {
+		return nil, &ValidationError{Issues: i.issues}
+	}
+
+	sort.Slice(snapshots, func(a, b int) bool {
+		return snapshots[a].EffectiveDate < snapshots[b].EffectiveDate
+	})
+
+	return snapshots, nil
+}
+
+func validateValue(value string) string {
+	r, err := calculator.ParseDecimal(value)
+	if err != nil {
+		return "value is not a decimal number: " + value
+	}
+
+	if r.Sign() <= 0 {
+		return "value is not positive: " + value
+	}
+
+	_, fraction, _ := strings.Cut(value, ".")
+
+	if len(strings.TrimRight(fraction, "0")) > maxValueScale {
+		return "value has more than " + strconv.Itoa(maxValueScale) + " decimal places: " + value
+	}
+
+	if r.Cmp(maxValue) >= 0 {
+		return "value is too large: " + value
+	}
+
+	return ""
+}
+
+func parseDate(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+
+	for _, layout := range []string{time.DateOnly, feedDateLayout} {
+		if date, err := time.Parse(layout, s); err == nil {
+			return date.Format(time.DateOnly), true
+		}
+	}
+
+	return "", false
+}
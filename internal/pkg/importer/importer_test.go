@@ -0,0 +1,363 @@
+package importer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/clock"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+	xmlparser "github.com/mrumyantsev/currency-converter-app/internal/pkg/xml-parser"
+)
+
+var info = []models.Currency{
+	{NumCode: 36, CharCode: "AUD", Name: "Австралийский доллар"},
+	{NumCode: 392, CharCode: "JPY", Name: "Японских иен"},
+	{NumCode: 840, CharCode: "USD", Name: "Доллар США"},
+}
+
+// newImporter returns an importer, which clock is half past midnight of
+// the 2nd of March 2024 in Moscow, while it is still the 1st in UTC.
+func newImporter(t *testing.T) *Importer {
+	t.Helper()
+
+	location, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		ParserWorkers:             2,
+		InitialCurrenciesCapacity: 4,
+		UpdateLocation:            location,
+	}
+
+	clk := clock.NewSimulated(time.Date(2024, time.March, 2, 0, 30, 0, 0, location).UTC())
+
+	return New(cfg, xmlparser.New(cfg), clk)
+}
+
+// summary lists the rates of the snapshots as dates, codes, nominals and
+// values in their order.
+func summary(snapshots []models.DatedCurrencies) []string {
+	var rates []string
+
+	for _, snapshot := range snapshots {
+		for _, currency := range snapshot.Currencies.Currencies {
+			rates = append(rates, snapshot.EffectiveDate+" "+currency.CharCode+" "+
+				strconv.Itoa(currency.Multiplier)+" "+string(currency.Value))
+		}
+	}
+
+	return rates
+}
+
+// checkResult compares the snapshots with the wanted rates, or the error
+// with the wanted issues, if there are any.
+func checkResult(t *testing.T, snapshots []models.DatedCurrencies, err error, want []string, wantIssues []string) {
+	t.Helper()
+
+	if wantIssues != nil {
+		var validationErr *ValidationError
+
+		if !errors.As(err, &validationErr) {
+			t.Fatalf("error = %v, want issues %v", err, wantIssues)
+		}
+
+		issues := make([]string, 0, len(validationErr.Issues))
+
+		for _, issue := range validationErr.Issues {
+			issues = append(issues, issue.String())
+		}
+
+		if !reflect.DeepEqual(issues, wantIssues) {
+			t.Fatalf("issues = %q, want %q", issues, wantIssues)
+		}
+
+		return
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := summary(snapshots); !reflect.DeepEqual(got, want) {
+		t.Fatalf("rates = %q, want %q", got, want)
+	}
+}
+
+func TestReadCsv(t *testing.T) {
+	tests := []struct {
+		name       string
+		delimiter  rune
+		csv        string
+		want       []string
+		wantIssues []string
+	}{
+		{
+			name:      "header is skipped",
+			delimiter: ',',
+			csv:       "date,code,nominal,value\n2024-03-01,USD,1,90.5\n",
+			want:      []string{"2024-03-01 USD 1 90.5"},
+		},
+		{
+			name:       "header only on first line",
+			delimiter:  ',',
+			csv:        "2024-03-01,USD,1,90.5\ndate,code,nominal,value\n",
+			wantIssues: []string{"rates.csv:2: date is neither in YYYY-MM-DD nor in DD.MM.YYYY format: date"},
+		},
+		{
+			name:      "decimal commas",
+			delimiter: ';',
+			csv:       "01.03.2024; USD; 1; 90,5\n01.03.2024;JPY;100;60,1234\n",
+			want:      []string{"2024-03-01 USD 1 90.5", "2024-03-01 JPY 100 60.1234"},
+		},
+		{
+			name:      "numeric and alphabetic codes",
+			delimiter: ',',
+			csv:       "2024-03-01,840,1,90\n2024-03-01,036,1,60\n2024-03-01,jpy,100,61\n",
+			want:      []string{"2024-03-01 USD 1 90", "2024-03-01 AUD 1 60", "2024-03-01 JPY 100 61"},
+		},
+		{
+			name:      "unknown codes",
+			delimiter: ',',
+			csv:       "2024-03-01,XYZ,1,90\n2024-03-01,978,1,98\n",
+			wantIssues: []string{
+				"rates.csv:1: unknown currency: XYZ",
+				"rates.csv:2: unknown currency: 978",
+			},
+		},
+		{
+			name:      "dates in order",
+			delimiter: ',',
+			csv:       "2024-03-01,USD,1,91\n2024-02-29,USD,1,90\n29.02.2024,AUD,1,60\n",
+			want:      []string{"2024-02-29 USD 1 90", "2024-02-29 AUD 1 60", "2024-03-01 USD 1 91"},
+		},
+		{
+			name:      "repeated currency of date",
+			delimiter: ',',
+			csv:       "2024-03-01,USD,1,90\n2024-02-29,USD,1,90\n2024-03-01,840,1,91\n",
+			wantIssues: []string{
+				"rates.csv:3: USD: currency is repeated",
+			},
+		},
+		{
+			name:      "date of zone is not in future",
+			delimiter: ',',
+			csv:       "2024-03-02,USD,1,90\n",
+			want:      []string{"2024-03-02 USD 1 90"},
+		},
+		{
+			name:      "future date",
+			delimiter: ',',
+			csv:       "2024-03-03,USD,1,90\n2024-03-03,AUD,1,60\n",
+			wantIssues: []string{
+				"rates.csv:1: date is in the future: 2024-03-03",
+			},
+		},
+		{
+			name:      "values within limits",
+			delimiter: ',',
+			csv:       "2024-03-01,USD,1,99999999.9999\n2024-03-01,JPY,100,0.0001000\n",
+			want:      []string{"2024-03-01 USD 1 99999999.9999", "2024-03-01 JPY 100 0.0001000"},
+		},
+		{
+			name:      "values out of limits",
+			delimiter: ',',
+			csv:       "2024-03-01,USD,1,100000000\n2024-03-01,JPY,100,0.00001\n2024-03-01,AUD,1,0\n",
+			wantIssues: []string{
+				"rates.csv:1: USD: value is too large: 100000000",
+				"rates.csv:2: JPY: value has more than 4 decimal places: 0.00001",
+				"rates.csv:3: AUD: value is not positive: 0",
+			},
+		},
+		{
+			name:      "invalid nominals and values",
+			delimiter: ',',
+			csv:       "2024-03-01,USD,one,90\n2024-03-01,JPY,0,60\n2024-03-01,AUD,1,-60\n",
+			wantIssues: []string{
+				"rates.csv:1: nominal is not an integer: one",
+				"rates.csv:2: JPY: nominal is not positive: 0",
+				"rates.csv:3: value is not a decimal number: -60",
+			},
+		},
+		{
+			name:      "wrong number of fields",
+			delimiter: ',',
+			csv:       "2024-03-01,USD,1\n2024-03-01,AUD,1,60,1\n2024-03-01,JPY,100,61\n",
+			wantIssues: []string{
+				"rates.csv:1: wrong number of fields",
+				"rates.csv:2: wrong number of fields",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshots, err := newImporter(t).ReadCsv(strings.NewReader(tt.csv), "rates.csv", tt.delimiter, info)
+
+			checkResult(t, snapshots, err, tt.want, tt.wantIssues)
+		})
+	}
+}
+
+func TestReadCsvEmpty(t *testing.T) {
+	_, err := newImporter(t).ReadCsv(strings.NewReader("date,code,nominal,value\n"), "rates.csv", ',', info)
+
+	var validationErr *ValidationError
+
+	if (err == nil) || errors.As(err, &validationErr) {
+		t.Fatalf("error = %v, want no rows", err)
+	}
+}
+
+// feed returns a daily feed of the date in DD.MM.YYYY format with the
+// Valute elements.
+func feed(date string, valutes ...string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+		`<ValCurs Date="` + date + `" name="Foreign Currency Market">` + "\n" +
+		strings.Join(valutes, "\n") + "\n</ValCurs>\n"
+}
+
+func valute(numCode string, charCode string, nominal string, name string, value string) string {
+	return `<Valute ID="R01235"><NumCode>` + numCode + `</NumCode><CharCode>` + charCode +
+		`</CharCode><Nominal>` + nominal + `</Nominal><Name>` + name + `</Name><Value>` + value +
+		`</Value><VunitRate>1</VunitRate></Valute>`
+}
+
+func TestReadXmlDir(t *testing.T) {
+	var (
+		usd = valute("840", "USD", "1", "Доллар США", "90,5")
+		jpy = valute("392", "JPY", "100", "Японских иен", "60,1234")
+	)
+
+	tests := []struct {
+		name       string
+		files      map[string]string
+		want       []string
+		wantIssues []string
+	}{
+		{
+			name: "feeds and archives in order",
+			files: map[string]string{
+				"daily.xml":   feed("01.03.2024", usd, jpy),
+				"archive.XML": feed("28.02.2024", usd) + feed("29.02.2024", jpy),
+				"notes.txt":   "not a feed",
+			},
+			want: []string{
+				"2024-02-28 USD 1 90.5",
+				"2024-02-29 JPY 100 60.1234",
+				"2024-03-01 USD 1 90.5",
+				"2024-03-01 JPY 100 60.1234",
+			},
+		},
+		{
+			name: "date of zone is not in future",
+			files: map[string]string{
+				"daily.xml": feed("02.03.2024", usd),
+			},
+			want: []string{"2024-03-02 USD 1 90.5"},
+		},
+		{
+			name: "repeated dates and currencies",
+			files: map[string]string{
+				"a.xml": feed("01.03.2024", usd, jpy, usd),
+				"b.xml": feed("01.03.2024", usd),
+			},
+			wantIssues: []string{
+				"a.xml: USD: currency is repeated",
+				"b.xml: feed of 2024-03-01 is already read from a.xml",
+			},
+		},
+		{
+			name: "future date",
+			files: map[string]string{
+				"daily.xml": feed("03.03.2024", usd),
+			},
+			wantIssues: []string{"daily.xml: date is in the future: 2024-03-03"},
+		},
+		{
+			name: "values out of limits",
+			files: map[string]string{
+				"daily.xml": feed("01.03.2024",
+					valute("840", "USD", "1", "Доллар США", "100000000"),
+					valute("392", "JPY", "100", "Японских иен", "0,00001")),
+			},
+			wantIssues: []string{
+				"daily.xml: USD: value is too large: 100000000",
+				"daily.xml: JPY: value has more than 4 decimal places: 0.00001",
+			},
+		},
+		{
+			name: "codes out of range",
+			files: map[string]string{
+				"daily.xml": feed("01.03.2024", valute("1000", "usd", "1", "Доллар США", "90,5")),
+			},
+			wantIssues: []string{
+				"daily.xml: usd: numeric code is out of range: 1000",
+				"daily.xml: usd: alphabetic code is not three capital letters",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			for name, content := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			snapshots, err := newImporter(t).ReadXmlDir(dir)
+
+			checkResult(t, snapshots, err, tt.want, tt.wantIssues)
+		})
+	}
+}
+
+func TestReadXmlDirInvalidElements(t *testing.T) {
+	dir := t.TempDir()
+
+	data := feed("01.03.2024",
+		valute("840", "USD", "1", "Доллар США", "90,5"),
+		valute("x392", "JPY", "100", "Японских иен", "60,1234"))
+
+	if err := os.WriteFile(filepath.Join(dir, "daily.xml"), []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := newImporter(t).ReadXmlDir(dir)
+
+	var validationErr *ValidationError
+
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("error = %v, want issues", err)
+	}
+
+	// elements, which can not be parsed, are pointed at by their lines
+	if issues := validationErr.Issues; (len(issues) != 1) || (issues[0].Source != "daily.xml") || (issues[0].Line != 4) {
+		t.Fatalf("issues = %v, want one of line 4 of daily.xml", issues)
+	}
+}
+
+func TestReadXmlDirEmpty(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a feed"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var validationErr *ValidationError
+
+	if _, err := newImporter(t).ReadXmlDir(dir); (err == nil) || errors.As(err, &validationErr) {
+		t.Fatalf("error = %v, want no feeds", err)
+	}
+}
@@ -1,11 +1,177 @@
 package memcache
 
-import "github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/precompressed"
+	"github.com/mrumyantsev/go-errlib"
+)
+
+// A View is the snapshot rates against one base currency, serialized and
+// indexed by currency codes.
+type View struct {
+	Rates      models.CurrencyRates
+	Body       *precompressed.Body
+	byCharCode map[string]int
+	byNumCode  map[int]int
+}
+
+// NewView serializes and compresses the rates and indexes currencies by
+// their codes.
+func NewView(rates models.CurrencyRates) (*View, error) {
+	data, err := json.Marshal(rates)
+	if err != nil {
+		return nil, errlib.Wrap(err, "could not serialize rates")
+	}
+
+	body, err := precompressed.New(data, rates.SnapshotVersion+"-"+rates.Base)
+	if err != nil {
+		return nil, errlib.Wrap(err, "could not compress rates")
+	}
+
+	view := &View{
+		Rates:      rates,
+		Body:       body,
+		byCharCode: make(map[string]int, len(rates.Currencies)),
+		byNumCode:  make(map[int]int, len(rates.Currencies)),
+	}
+
+	for i, currency := range rates.Currencies {
+		view.byCharCode[currency.CharCode] = i
+		view.byNumCode[currency.NumCode] = i
+	}
+
+	return view, nil
+}
+
+// Currency looks up a currency by its alphabetic or numeric ISO 4217
+// code. Alphabetic codes are case insensitive.
+func (v *View) Currency(code string) (models.CalculatedCurrency, bool) {
+	var (
+		i  int
+		ok bool
+	)
+
+	if numCode, err := strconv.Atoi(code); err == nil {
+		i, ok = v.byNumCode[numCode]
+	} else {
+		i, ok = v.byCharCode[strings.ToUpper(code)]
+	}
+
+	if !ok {
+		return models.CalculatedCurrency{}, false
+	}
+
+	return v.Rates.Currencies[i], true
+}
+
+// A Snapshot is the currency data prepared for output. It is published
+// as a whole and never modified afterwards, except for views of other
+// bases than the default one, which are built on demand and kept.
+// DueDatetime is the scheduled update, which was to replace the data. It
+// is kept, when the snapshot is published again after failed updates,
+// unlike NextUpdateDatetime, which is the time of the next attempt.
+type Snapshot struct {
+	Version            string
+	UpdateDatetime     time.Time
+	DueDatetime        time.Time
+	NextUpdateDatetime time.Time
+	Info               models.SnapshotInfo
+	Currencies         models.Currencies
+	PreviousCurrencies models.Currencies
+	DefaultView        *View
+	mu                 sync.Mutex
+	views              map[string]*viewEntry
+}
+
+var errViewNotBuilt = errors.New("view of snapshot is not built")
+
+// A viewEntry is a view, which is built or being built. The channel is closed,
+// when the building is finished.
+type viewEntry struct {
+	done chan struct{}
+	view *View
+	err  error
+}
+
+func NewSnapshot(
+	version string,
+	updateDatetime time.Time,
+	dueDatetime time.Time,
+	nextUpdateDatetime time.Time,
+	info models.SnapshotInfo,
+	currencies models.Currencies,
+	previousCurrencies models.Currencies,
+	defaultView *View,
+) *Snapshot {
+	done := make(chan struct{})
+	close(done)
+
+	return &Snapshot{
+		Version:            version,
+		UpdateDatetime:     updateDatetime,
+		DueDatetime:        dueDatetime,
+		NextUpdateDatetime: nextUpdateDatetime,
+		Info:               info,
+		Currencies:         currencies,
+		PreviousCurrencies: previousCurrencies,
+		DefaultView:        defaultView,
+		views: map[string]*viewEntry{
+			defaultView.Rates.Base: {done: done, view: defaultView},
+		},
+	}
+}
+
+// View returns the view of the given base currency, building it with the
+// given function at the first request. The view is built without holding
+// the lock, as it takes time to compress, so views of other bases are not
+// blocked, and concurrent requests of the same base wait for the single
+// build. Failed builds are not kept, so unknown bases do not pile up.
+func (s *Snapshot) View(base string, build func() (*View, error)) (*View, error) {
+	s.mu.Lock()
+
+	entry, ok := s.views[base]
+	if !ok {
+		entry = &viewEntry{done: make(chan struct{}), err: errViewNotBuilt}
+		s.views[base] = entry
+	}
+
+	s.mu.Unlock()
+
+	if ok {
+		<-entry.done
+
+		return entry.view, entry.err
+	}
+
+	// waiters are released, even if the build panics
+	defer func() {
+		if entry.err != nil {
+			s.mu.Lock()
+			delete(s.views, base)
+			s.mu.Unlock()
+		}
+
+		close(entry.done)
+	}()
+
+	entry.view, entry.err = build()
+
+	return entry.view, entry.err
+}
 
 type MemCache struct {
-	currencies           *models.Currencies
-	updateDatetime       *models.UpdateDatetime
-	calculatedCurrencies []models.CalculatedCurrency
+	mu                 sync.RWMutex
+	currencies         *models.Currencies
+	previousCurrencies *models.Currencies
+	updateDatetime     *models.UpdateDatetime
+	snapshot           *Snapshot
 }
 
 func New() *MemCache {
@@ -13,25 +179,60 @@ func New() *MemCache {
 }
 
 func (m *MemCache) Currencies() *models.Currencies {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	return m.currencies
 }
 
 func (m *MemCache) SetCurrencies(currencies *models.Currencies) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	m.currencies = currencies
 }
 
+// PreviousCurrencies returns currencies of the previous effective date.
+func (m *MemCache) PreviousCurrencies() *models.Currencies {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.previousCurrencies
+}
+
+func (m *MemCache) SetPreviousCurrencies(currencies *models.Currencies) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.previousCurrencies = currencies
+}
+
 func (m *MemCache) UpdateDatetime() *models.UpdateDatetime {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	return m.updateDatetime
 }
 
 func (m *MemCache) SetUpdateDatetime(updateDatetime *models.UpdateDatetime) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	m.updateDatetime = updateDatetime
 }
 
-func (m *MemCache) CalculatedCurrencies() []models.CalculatedCurrency {
-	return m.calculatedCurrencies
+// Snapshot returns the latest published snapshot or nil, if there is
+// none yet.
+func (m *MemCache) Snapshot() *Snapshot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.snapshot
 }
 
-func (m *MemCache) SetCalculatedCurrencies(calculatedCurrencies []models.CalculatedCurrency) {
-	m.calculatedCurrencies = calculatedCurrencies
+func (m *MemCache) SetSnapshot(snapshot *Snapshot) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.snapshot = snapshot
 }
@@ -1,27 +1,340 @@
 package models
 
-import "encoding/xml"
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"strings"
+)
 
 type Currencies struct {
 	XMLName    xml.Name   `xml:"ValCurs"`
+	Date       string     `xml:"Date,attr"`
+	Name       string     `xml:"name,attr"`
 	Currencies []Currency `xml:"Valute"`
 }
 
+// A Currency is a Valute element of a feed. SourceId is the code of the
+// currency in the source, which its other feeds are requested by.
 type Currency struct {
-	NumCode    int    `xml:"NumCode"`
-	CharCode   string `xml:"CharCode"`
-	Multiplier int    `xml:"Nominal"`
-	Name       string `xml:"Name"`
-	Value      string `xml:"Value"`
+	SourceId   string  `xml:"ID,attr"`
+	NumCode    int     `xml:"NumCode"`
+	CharCode   string  `xml:"CharCode"`
+	Multiplier int     `xml:"Nominal"`
+	Name       string  `xml:"Name"`
+	Value      Decimal `xml:"Value"`
+	UnitRate   Decimal `xml:"VunitRate"`
+}
+
+// A Decimal is a non-negative decimal number with a dot as the decimal
+// separator. Feeds of the source write decimal commas, which are turned
+// into dots, when a Decimal is decoded.
+type Decimal string
+
+// NewDecimal accepts digits with a single decimal comma or dot between
+// them and surrounding spaces.
+func NewDecimal(s string) (Decimal, error) {
+	s = strings.TrimSpace(s)
+
+	integer, fraction, isFraction := strings.Cut(strings.Replace(s, ",", ".", 1), ".")
+
+	if !isDigits(integer) || (isFraction && !isDigits(fraction)) {
+		return "", errors.New("not a decimal number: " + s)
+	}
+
+	if isFraction {
+		return Decimal(integer + "." + fraction), nil
+	}
+
+	return Decimal(integer), nil
+}
+
+func (d *Decimal) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	var s string
+
+	if err := decoder.DecodeElement(&s, &start); err != nil {
+		return err
+	}
+
+	decimal, err := NewDecimal(s)
+	if err != nil {
+		return errors.New(start.Name.Local + ": " + err.Error())
+	}
+
+	*d = decimal
+
+	return nil
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for _, c := range s {
+		if (c < '0') || (c > '9') {
+			return false
+		}
+	}
+
+	return true
 }
 
 type UpdateDatetime struct {
 	Id             int    `sql:"id"`
 	UpdateDatetime string `sql:"update_datetime"`
+	EffectiveDate  string `sql:"effective_date"`
+	FeedName       string `sql:"feed_name"`
+}
+
+// A Payload is a response of the source, archived as it was received.
+// Key points at its data in the archive, and UpdateDatetimeId is zero,
+// unless a snapshot has been stored from it.
+type Payload struct {
+	Id               int                 `sql:"id"`
+	Url              string              `sql:"url"`
+	FetchedAt        string              `sql:"fetched_at"`
+	Sha256           string              `sql:"sha256"`
+	Size             int                 `sql:"size"`
+	Header           map[string][]string `sql:"header"`
+	Key              string              `sql:"archive_key"`
+	IsCompressed     bool                `sql:"is_compressed"`
+	UpdateDatetimeId int                 `sql:"update_datetime_id"`
+}
+
+// A CurrencyDynamic is a feed of rates of a single currency over a range
+// of dates.
+type CurrencyDynamic struct {
+	XMLName  xml.Name      `xml:"ValCurs"`
+	SourceId string        `xml:"ID,attr"`
+	Name     string        `xml:"name,attr"`
+	Rates    []DynamicRate `xml:"Record"`
+}
+
+type DynamicRate struct {
+	Date       string  `xml:"Date,attr"`
+	SourceId   string  `xml:"Id,attr"`
+	Multiplier int     `xml:"Nominal"`
+	Value      Decimal `xml:"Value"`
+	UnitRate   Decimal `xml:"VunitRate"`
 }
 
+// A CurrencyReference is an entry of the reference list of currencies of
+// the source. Currencies, which are not in ISO 4217, have zero NumCode
+// and empty CharCode.
+type CurrencyReference struct {
+	SourceId   string
+	NumCode    int
+	CharCode   string
+	Multiplier int
+	Name       string
+	EngName    string
+}
+
+// A DatedCurrencies is a stored snapshot of the source currencies, which
+// were in force on the effective date.
+type DatedCurrencies struct {
+	SnapshotVersion string
+	EffectiveDate   string
+	Currencies      Currencies
+}
+
+// A DailyRate is the price of a single unit of a currency, which was in
+// force on the effective date.
+type DailyRate struct {
+	EffectiveDate    string `sql:"effective_date"`
+	UnitValue        string `sql:"unit_value"`
+	IsCarriedForward bool
+}
+
+// Periods of average rates.
+const (
+	PeriodDay     = "day"
+	PeriodMonth   = "month"
+	PeriodQuarter = "quarter"
+)
+
+// An AverageRate is the mean of daily rates of a currency over a period.
+// Count is the number of dates with rates in the period, and the dates
+// without them are not taken into account.
+type AverageRate struct {
+	PeriodStart string `json:"periodStart" sql:"period_start"`
+	PeriodEnd   string `json:"periodEnd"`
+	Average     string `json:"average" sql:"average_value"`
+	Count       int    `json:"count" sql:"rates_count"`
+}
+
+// An AverageRates is the versioned envelope of average rates of a
+// currency over the periods, which overlap the requested dates.
+type AverageRates struct {
+	Version  int           `json:"version"`
+	Code     string        `json:"code"`
+	Base     string        `json:"base"`
+	Period   string        `json:"period"`
+	From     string        `json:"from"`
+	To       string        `json:"to"`
+	Averages []AverageRate `json:"averages"`
+}
+
+// An ExportRow is the rate of a currency, which was in force on the
+// effective date, as it is stored.
+type ExportRow struct {
+	EffectiveDate string `sql:"effective_date"`
+	NumCode       int    `sql:"num_code"`
+	CharCode      string `sql:"char_code"`
+	Name          string `sql:"name"`
+	Nominal       int    `sql:"multiplier"`
+	Value         string `sql:"currency_value"`
+	UnitValue     string `sql:"unit_value"`
+}
+
+// Intervals of candles.
+const (
+	IntervalWeek  = "week"
+	IntervalMonth = "month"
+)
+
+// A Candle holds the first, the highest, the lowest and the last daily
+// rates of an interval. Weeks start on Monday. Count is the number of
+// dates with published rates in the interval.
+type Candle struct {
+	PeriodStart string `json:"periodStart"`
+	PeriodEnd   string `json:"periodEnd"`
+	Open        string `json:"open"`
+	High        string `json:"high"`
+	Low         string `json:"low"`
+	Close       string `json:"close"`
+	Count       int    `json:"count"`
+}
+
+// A Candles is the versioned envelope of candles of a currency over the
+// intervals, which overlap the requested dates.
+type Candles struct {
+	Version  int      `json:"version"`
+	Code     string   `json:"code"`
+	Base     string   `json:"base"`
+	Interval string   `json:"interval"`
+	From     string   `json:"from"`
+	To       string   `json:"to"`
+	Candles  []Candle `json:"candles"`
+}
+
+// A SnapshotInfo describes the snapshot the rates are calculated from.
+type SnapshotInfo struct {
+	Version         int    `json:"version"`
+	SnapshotVersion string `json:"snapshotVersion"`
+	Base            string `json:"base"`
+	EffectiveDate   string `json:"effectiveDate"`
+	FetchedAt       string `json:"fetchedAt"`
+	Source          string `json:"source"`
+}
+
+// A CurrencyRates is the versioned envelope of the calculated rates.
+type CurrencyRates struct {
+	SnapshotInfo
+	Currencies []CalculatedCurrency `json:"currencies"`
+}
+
+// A CurrencyRate is the versioned envelope of a single currency rate.
+type CurrencyRate struct {
+	SnapshotInfo
+	Currency CalculatedCurrency `json:"currency"`
+}
+
+// A CurrencyMatrix is the versioned envelope of cross rates between the
+// listed currencies. Rates[i][j] is the amount of Codes[j] units per one
+// unit of Codes[i].
+type CurrencyMatrix struct {
+	SnapshotInfo
+	Codes []string   `json:"codes"`
+	Rates [][]string `json:"rates"`
+}
+
+// A ConversionItem is a single conversion of a batch. Rates in force on
+// the date are used, or the latest ones, if the date is empty.
+type ConversionItem struct {
+	From   string      `json:"from"`
+	To     string      `json:"to"`
+	Amount json.Number `json:"amount"`
+	Date   string      `json:"date,omitempty"`
+}
+
+// A ConversionResult is the outcome of a conversion item. Either Error or
+// the rest of the fields are set.
+type ConversionResult struct {
+	From            string           `json:"from"`
+	To              string           `json:"to"`
+	Amount          string           `json:"amount"`
+	Date            string           `json:"date,omitempty"`
+	Result          string           `json:"result,omitempty"`
+	Rate            string           `json:"rate,omitempty"`
+	SnapshotVersion string           `json:"snapshotVersion,omitempty"`
+	EffectiveDate   string           `json:"effectiveDate,omitempty"`
+	Error           *ConversionError `json:"error,omitempty"`
+}
+
+// A ConversionError describes why a conversion item failed, with the same
+// stable code as the corresponding API error has.
+type ConversionError struct {
+	Code   string `json:"code"`
+	Title  string `json:"title"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// A ConversionResults is the versioned envelope of batch results, which
+// are in the order of the items.
+type ConversionResults struct {
+	Version int                `json:"version"`
+	Failed  int                `json:"failed"`
+	Results []ConversionResult `json:"results"`
+}
+
+// A CurrencyStats describes rates of a currency over a period. Dates of
+// the period without rates are not taken into account. StdDev is the
+// sample standard deviation, and changes are from First to Last.
+type CurrencyStats struct {
+	Version       int    `json:"version"`
+	Code          string `json:"code"`
+	Base          string `json:"base"`
+	From          string `json:"from"`
+	To            string `json:"to"`
+	Count         int    `json:"count"`
+	FirstDate     string `json:"firstDate"`
+	LastDate      string `json:"lastDate"`
+	First         string `json:"first"`
+	Last          string `json:"last"`
+	Min           string `json:"min"`
+	Max           string `json:"max"`
+	Mean          string `json:"mean"`
+	Median        string `json:"median"`
+	StdDev        string `json:"stdDev"`
+	Change        string `json:"change"`
+	ChangePercent string `json:"changePercent"`
+}
+
+// A CalculatedCurrency holds rates of a currency against the base one.
+// Value is the price of Nominal units, UnitRate is the price of a single
+// unit, and InverseRate is the amount of units per 1 base unit. Changes
+// are relative to the previous effective date and are null, if there is
+// no data for it.
 type CalculatedCurrency struct {
-	Name     string `json:"name"`
-	CharCode string `json:"charCode"`
-	Ratio    string `json:"ratio"`
+	NumCode       int     `json:"numCode"`
+	CharCode      string  `json:"charCode"`
+	Name          string  `json:"name"`
+	Nominal       int     `json:"nominal"`
+	Value         string  `json:"value"`
+	UnitRate      string  `json:"unitRate"`
+	InverseRate   string  `json:"inverseRate"`
+	Change        *string `json:"change"`
+	ChangePercent *string `json:"changePercent"`
+}
+
+type ApiKey struct {
+	Id        int    `sql:"id"`
+	Name      string `sql:"name"`
+	KeyHash   string `sql:"key_hash"`
+	RateLimit int    `sql:"rate_limit"`
+	RateBurst int    `sql:"rate_burst"`
+	CreatedAt string `sql:"created_at"`
+	RevokedAt string `sql:"revoked_at"`
 }
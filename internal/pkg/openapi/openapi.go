@@ -0,0 +1,633 @@
+package openapi
+
+import (
+	"bytes"
+	_ "embed" // necessary for embedding the document
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mrumyantsev/go-errlib"
+)
+
+const (
+	componentsParametersRef = "#/components/parameters/"
+	componentsResponsesRef  = "#/components/responses/"
+	componentsSchemasRef    = "#/components/schemas/"
+)
+
+//go:embed openapi.json
+var document []byte
+
+// Document returns the OpenAPI document of the service.
+func Document() []byte {
+	return document
+}
+
+// A Spec is the part of an OpenAPI 3 document, which is needed to check
+// routes and validate requests and responses.
+type Spec struct {
+	Servers []struct {
+		Url string `json:"url"`
+	} `json:"servers"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components struct {
+		Parameters map[string]Parameter `json:"parameters"`
+		Responses  map[string]Response  `json:"responses"`
+		Schemas    map[string]*Schema   `json:"schemas"`
+	} `json:"components"`
+}
+
+type PathItem struct {
+	Parameters []Parameter `json:"parameters"`
+	Get        *Operation  `json:"get"`
+	Post       *Operation  `json:"post"`
+	Put        *Operation  `json:"put"`
+	Patch      *Operation  `json:"patch"`
+	Delete     *Operation  `json:"delete"`
+}
+
+type Operation struct {
+	OperationId string              `json:"operationId"`
+	Parameters  []Parameter         `json:"parameters"`
+	RequestBody *RequestBody        `json:"requestBody"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+type Parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Explode  *bool   `json:"explode"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                       `json:"required"`
+	Content  map[string]json.RawMessage `json:"content"`
+}
+
+type Response struct {
+	Ref     string               `json:"$ref"`
+	Content map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Schema struct {
+	Ref        string             `json:"$ref"`
+	Type       string             `json:"type"`
+	Format     string             `json:"format"`
+	Nullable   bool               `json:"nullable"`
+	Enum       []any              `json:"enum"`
+	Pattern    string             `json:"pattern"`
+	MaxLength  *int               `json:"maxLength"`
+	Minimum    *float64           `json:"minimum"`
+	Maximum    *float64           `json:"maximum"`
+	Items      *Schema            `json:"items"`
+	MaxItems   *int               `json:"maxItems"`
+	Required   []string           `json:"required"`
+	Properties map[string]*Schema `json:"properties"`
+	AllOf      []*Schema          `json:"allOf"`
+	OneOf      []*Schema          `json:"oneOf"`
+}
+
+// Load parses the embedded OpenAPI document.
+func Load() (*Spec, error) {
+	spec := new(Spec)
+
+	if err := json.Unmarshal(document, spec); err != nil {
+		return nil, errlib.Wrap(err, "could not parse openapi document")
+	}
+
+	return spec, nil
+}
+
+// BasePath returns the path prefix of all operations.
+func (s *Spec) BasePath() string {
+	if len(s.Servers) == 0 {
+		return ""
+	}
+
+	return strings.TrimSuffix(s.Servers[0].Url, "/")
+}
+
+// CheckRoutes reports the operations without handlers and the handlers
+// without operations, so the document can not drift away from the code.
+func (s *Spec) CheckRoutes(routes []*echo.Route) error {
+	documented := make(map[string]bool)
+
+	for path, item := range s.Paths {
+		for method := range item.operations() {
+			documented[method+" "+s.echoPath(path)] = true
+		}
+	}
+
+	registered := make(map[string]bool)
+
+	for _, route := range routes {
+		registered[route.Method+" "+route.Path] = true
+	}
+
+	var mismatches []string
+
+	for route := range registered {
+		if !documented[route] {
+			mismatches = append(mismatches, "undocumented route: "+route)
+		}
+	}
+
+	for route := range documented {
+		if !registered[route] {
+			mismatches = append(mismatches, "route without handler: "+route)
+		}
+	}
+
+	if len(mismatches) > 0 {
+		sort.Strings(mismatches)
+
+		return errors.New(strings.Join(mismatches, "; "))
+	}
+
+	return nil
+}
+
+// Validator returns an echo middleware, which rejects requests violating
+// the documented parameters and request bodies. Requests to routes, that
+// are not in the document, are passed as is.
+func (s *Spec) Validator() (echo.MiddlewareFunc, error) {
+	operations := make(map[string]*operation)
+
+	for path, item := range s.Paths {
+		for method, op := range item.operations() {
+			compiled, err := s.compile(item, op)
+			if err != nil {
+				return nil, errlib.Wrap(err, "could not compile operation "+op.OperationId)
+			}
+
+			operations[method+" "+s.echoPath(path)] = compiled
+		}
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			op, ok := operations[ctx.Request().Method+" "+ctx.Path()]
+			if !ok {
+				return next(ctx)
+			}
+
+			if err := op.validate(ctx); err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+			}
+
+			return next(ctx)
+		}
+	}, nil
+}
+
+// ValidateResponse checks a response of the route against the document.
+// The status and the content type must be documented for the operation,
+// and JSON bodies must conform to the schema of the content type. Bodies
+// of other content types are not checked.
+func (s *Spec) ValidateResponse(method string, route string, status int, contentType string, body []byte) error {
+	op := s.operation(method, route)
+	if op == nil {
+		return errors.New("undocumented route: " + method + " " + route)
+	}
+
+	response, ok := op.Responses[strconv.Itoa(status)]
+	if !ok {
+		if response, ok = op.Responses["default"]; !ok {
+			return fmt.Errorf("undocumented status %d of operation %s", status, op.OperationId)
+		}
+	}
+
+	if ref := response.Ref; ref != "" {
+		if response, ok = s.Components.Responses[strings.TrimPrefix(ref, componentsResponsesRef)]; !ok {
+			return errors.New("unresolved response reference: " + ref)
+		}
+	}
+
+	if len(response.Content) == 0 {
+		if len(body) > 0 {
+			return fmt.Errorf("undocumented body of status %d of operation %s", status, op.OperationId)
+		}
+
+		return nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return errlib.Wrap(err, "could not parse content type of response")
+	}
+
+	media, ok := response.Content[mediaType]
+	if !ok {
+		return fmt.Errorf("undocumented content type %s of status %d of operation %s",
+			mediaType, status, op.OperationId)
+	}
+
+	if (media.Schema == nil) || !isJson(mediaType) {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var value any
+
+	if err = decoder.Decode(&value); err != nil {
+		return errlib.Wrap(err, "could not parse response body")
+	}
+
+	return s.validateJson(media.Schema, value, "body")
+}
+
+func (s *Spec) operation(method string, route string) *Operation {
+	for path, item := range s.Paths {
+		if s.echoPath(path) != route {
+			continue
+		}
+
+		return item.operations()[method]
+	}
+
+	return nil
+}
+
+// validateJson checks the decoded JSON value against the schema. The
+// location of the value is reported in errors.
+func (s *Spec) validateJson(schema *Schema, value any, location string) error {
+	if schema.Ref != "" {
+		resolved, ok := s.Components.Schemas[strings.TrimPrefix(schema.Ref, componentsSchemasRef)]
+		if !ok {
+			return errors.New("unresolved schema reference: " + schema.Ref)
+		}
+
+		return s.validateJson(resolved, value, location)
+	}
+
+	if value == nil {
+		if schema.Nullable {
+			return nil
+		}
+
+		return errors.New(location + ": must not be null")
+	}
+
+	for _, sub := range schema.AllOf {
+		if err := s.validateJson(sub, value, location); err != nil {
+			return err
+		}
+	}
+
+	if len(schema.OneOf) > 0 {
+		matched := 0
+
+		for _, sub := range schema.OneOf {
+			if s.validateJson(sub, value, location) == nil {
+				matched++
+			}
+		}
+
+		if matched != 1 {
+			return fmt.Errorf("%s: matches %d schemas of oneOf instead of one", location, matched)
+		}
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return errors.New(location + ": not an object")
+		}
+
+		for _, name := range schema.Required {
+			if _, ok = object[name]; !ok {
+				return errors.New(location + ": property " + name + " is required")
+			}
+		}
+
+		for name, property := range schema.Properties {
+			if propertyValue, ok := object[name]; ok {
+				if err := s.validateJson(property, propertyValue, location+"."+name); err != nil {
+					return err
+				}
+			}
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return errors.New(location + ": not an array")
+		}
+
+		if (schema.MaxItems != nil) && (len(items) > *schema.MaxItems) {
+			return fmt.Errorf("%s: too many items, maximum is %d", location, *schema.MaxItems)
+		}
+
+		if schema.Items == nil {
+			return nil
+		}
+
+		for i, item := range items {
+			if err := s.validateJson(schema.Items, item, fmt.Sprintf("%s[%d]", location, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return errors.New(location + ": not a string")
+		}
+
+		pattern, err := compilePattern(schema)
+		if err != nil {
+			return err
+		}
+
+		if err = validateValue(schema, pattern, str); err != nil {
+			return fmt.Errorf("%s: %w", location, err)
+		}
+	case "integer", "number":
+		number, ok := value.(json.Number)
+		if !ok {
+			return errors.New(location + ": not a number")
+		}
+
+		if err := validateValue(schema, nil, number.String()); err != nil {
+			return fmt.Errorf("%s: %w", location, err)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return errors.New(location + ": not a boolean")
+		}
+	}
+
+	return nil
+}
+
+func isJson(mediaType string) bool {
+	return (mediaType == echo.MIMEApplicationJSON) || strings.HasSuffix(mediaType, "+json")
+}
+
+func (s *Spec) echoPath(path string) string {
+	return s.BasePath() + pathParamRegexp.ReplaceAllString(path, ":$1")
+}
+
+var pathParamRegexp = regexp.MustCompile(`\{([^}]+)\}`)
+
+func (p PathItem) operations() map[string]*Operation {
+	operations := make(map[string]*Operation)
+
+	for method, op := range map[string]*Operation{
+		http.MethodGet:    p.Get,
+		http.MethodPost:   p.Post,
+		http.MethodPut:    p.Put,
+		http.MethodPatch:  p.Patch,
+		http.MethodDelete: p.Delete,
+	} {
+		if op != nil {
+			operations[method] = op
+		}
+	}
+
+	return operations
+}
+
+type operation struct {
+	parameters   []parameter
+	body         *RequestBody
+	contentTypes map[string]bool
+}
+
+type parameter struct {
+	Parameter
+	pattern     *regexp.Regexp
+	itemPattern *regexp.Regexp
+}
+
+func (s *Spec) compile(item PathItem, op *Operation) (*operation, error) {
+	compiled := &operation{body: op.RequestBody}
+
+	for _, p := range append(append([]Parameter{}, item.Parameters...), op.Parameters...) {
+		if p.Ref != "" {
+			resolved, ok := s.Components.Parameters[strings.TrimPrefix(p.Ref, componentsParametersRef)]
+			if !ok {
+				return nil, errors.New("unresolved parameter reference: " + p.Ref)
+			}
+
+			p = resolved
+		}
+
+		param := parameter{Parameter: p}
+
+		if p.Schema == nil {
+			return nil, errors.New("no schema for parameter: " + p.Name)
+		}
+
+		var err error
+
+		if param.pattern, err = compilePattern(p.Schema); err != nil {
+			return nil, err
+		}
+
+		if p.Schema.Items != nil {
+			if param.itemPattern, err = compilePattern(p.Schema.Items); err != nil {
+				return nil, err
+			}
+		}
+
+		compiled.parameters = append(compiled.parameters, param)
+	}
+
+	if op.RequestBody != nil {
+		compiled.contentTypes = make(map[string]bool)
+
+		for contentType := range op.RequestBody.Content {
+			compiled.contentTypes[contentType] = true
+		}
+	}
+
+	return compiled, nil
+}
+
+func compilePattern(schema *Schema) (*regexp.Regexp, error) {
+	if schema.Pattern == "" {
+		return nil, nil
+	}
+
+	pattern, err := regexp.Compile(schema.Pattern)
+	if err != nil {
+		return nil, errlib.Wrap(err, "could not compile pattern "+schema.Pattern)
+	}
+
+	return pattern, nil
+}
+
+func (o *operation) validate(ctx echo.Context) error {
+	for _, param := range o.parameters {
+		if err := param.validate(ctx); err != nil {
+			return err
+		}
+	}
+
+	if o.body == nil {
+		return nil
+	}
+
+	req := ctx.Request()
+
+	if req.ContentLength == 0 {
+		if o.body.Required {
+			return errors.New("request body is required")
+		}
+
+		return nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get(echo.HeaderContentType))
+	if (err != nil) || !o.contentTypes[mediaType] {
+		return errors.New("unsupported content type of request body")
+	}
+
+	return nil
+}
+
+func (p parameter) validate(ctx echo.Context) error {
+	var (
+		values  []string
+		present bool
+	)
+
+	switch p.In {
+	case "path":
+		value := ctx.Param(p.Name)
+		values, present = []string{value}, value != ""
+	case "query":
+		values, present = ctx.QueryParams()[p.Name]
+	case "header":
+		values, present = ctx.Request().Header[http.CanonicalHeaderKey(p.Name)]
+	default:
+		return nil
+	}
+
+	if !present {
+		if p.Required {
+			return fmt.Errorf("%s parameter %s is required", p.In, p.Name)
+		}
+
+		return nil
+	}
+
+	if p.Schema.Type != "array" {
+		if err := validateValue(p.Schema, p.pattern, values[0]); err != nil {
+			return fmt.Errorf("%s parameter %s: %w", p.In, p.Name, err)
+		}
+
+		return nil
+	}
+
+	items := values
+
+	if (p.Explode != nil) && !*p.Explode {
+		items = strings.Split(values[0], ",")
+	}
+
+	if (p.Schema.MaxItems != nil) && (len(items) > *p.Schema.MaxItems) {
+		return fmt.Errorf("%s parameter %s: too many items, maximum is %d",
+			p.In, p.Name, *p.Schema.MaxItems)
+	}
+
+	if p.Schema.Items == nil {
+		return nil
+	}
+
+	for _, item := range items {
+		if err := validateValue(p.Schema.Items, p.itemPattern, item); err != nil {
+			return fmt.Errorf("%s parameter %s: %w", p.In, p.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func validateValue(schema *Schema, pattern *regexp.Regexp, value string) error {
+	var (
+		number   float64
+		isNumber bool
+		err      error
+	)
+
+	switch schema.Type {
+	case "integer":
+		var i int64
+
+		if i, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return errors.New("not an integer: " + value)
+		}
+
+		number, isNumber = float64(i), true
+	case "number":
+		if number, err = strconv.ParseFloat(value, 64); err != nil {
+			return errors.New("not a number: " + value)
+		}
+
+		isNumber = true
+	case "boolean":
+		if _, err = strconv.ParseBool(value); err != nil {
+			return errors.New("not a boolean: " + value)
+		}
+	case "string":
+		if (schema.MaxLength != nil) && (len([]rune(value)) > *schema.MaxLength) {
+			return fmt.Errorf("longer than %d characters", *schema.MaxLength)
+		}
+
+		switch schema.Format {
+		case "date":
+			if _, err = time.Parse(time.DateOnly, value); err != nil {
+				return errors.New("not a date: " + value)
+			}
+		case "date-time":
+			if _, err = time.Parse(time.RFC3339, value); err != nil {
+				return errors.New("not a date-time: " + value)
+			}
+		}
+	}
+
+	if isNumber {
+		if (schema.Minimum != nil) && (number < *schema.Minimum) {
+			return fmt.Errorf("less than %v", *schema.Minimum)
+		}
+
+		if (schema.Maximum != nil) && (number > *schema.Maximum) {
+			return fmt.Errorf("greater than %v", *schema.Maximum)
+		}
+	}
+
+	if (pattern != nil) && !pattern.MatchString(value) {
+		return errors.New("does not match pattern " + pattern.String() + ": " + value)
+	}
+
+	if len(schema.Enum) > 0 {
+		for _, allowed := range schema.Enum {
+			if fmt.Sprint(allowed) == value {
+				return nil
+			}
+		}
+
+		return errors.New("not one of allowed values: " + value)
+	}
+
+	return nil
+}
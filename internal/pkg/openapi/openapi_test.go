@@ -0,0 +1,316 @@
+package openapi_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	apperrors "github.com/mrumyantsev/currency-converter-app/internal/pkg/app-errors"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/calculator"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/clock"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/endpoint"
+	memcache "github.com/mrumyantsev/currency-converter-app/internal/pkg/mem-cache"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/openapi"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/server"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/service"
+)
+
+var now = time.Date(2024, time.March, 2, 12, 0, 0, 0, time.UTC)
+
+var currencies = models.Currencies{Currencies: []models.Currency{
+	{NumCode: 840, CharCode: "USD", Multiplier: 1, Name: "Доллар США", Value: "91.3336"},
+	{NumCode: 978, CharCode: "EUR", Multiplier: 1, Name: "Евро", Value: "99.1821"},
+	{NumCode: 704, CharCode: "VND", Multiplier: 10000, Name: "Вьетнамский донг", Value: "36.9977"},
+}}
+
+var previousCurrencies = models.Currencies{Currencies: []models.Currency{
+	{NumCode: 840, CharCode: "USD", Multiplier: 1, Name: "Доллар США", Value: "91.1217"},
+}}
+
+var dailyRates = []models.DailyRate{
+	{EffectiveDate: "2024-01-10", UnitValue: "90.0"},
+	{EffectiveDate: "2024-01-11", UnitValue: "91.5"},
+	{EffectiveDate: "2024-01-12", UnitValue: "89.75"},
+}
+
+type fakeCurrencies struct{}
+
+func (fakeCurrencies) Create(models.Currencies, int) error { return nil }
+
+func (fakeCurrencies) GetLatest(int) (models.Currencies, error) { return currencies, nil }
+
+type fakeHistory struct{}
+
+func (fakeHistory) GetByDate(date string) (models.DatedCurrencies, error) {
+	if date < "2024-01-01" {
+		return models.DatedCurrencies{}, apperrors.NoDataForDate(date)
+	}
+
+	return models.DatedCurrencies{
+		SnapshotVersion: "3.1",
+		EffectiveDate:   "2024-02-01",
+		Currencies:      currencies,
+	}, nil
+}
+
+type fakeDailyRates struct{}
+
+func (fakeDailyRates) Get(code string, _ string, _ string) (string, []models.DailyRate, error) {
+	if code != "USD" {
+		return "", nil, apperrors.UnknownCurrency(code)
+	}
+
+	return code, dailyRates, nil
+}
+
+func (r fakeDailyRates) GetCarriedForward(code string, from string, to string) (string, []models.DailyRate, error) {
+	return r.Get(code, from, to)
+}
+
+type fakeAverageRates struct{}
+
+func (fakeAverageRates) Refresh(string) error { return nil }
+
+func (fakeAverageRates) RefreshAll() error { return nil }
+
+func (fakeAverageRates) Get(code string, _ string, _ string, _ string) (string, []models.AverageRate, error) {
+	return code, []models.AverageRate{
+		{PeriodStart: "2024-01-01", PeriodEnd: "2024-01-31", Average: "90.41666666666667", Count: 3},
+	}, nil
+}
+
+type fakeExport struct{}
+
+func (fakeExport) Stream(_ []string, from string, _ string, fn func(row models.ExportRow) error) error {
+	return fn(models.ExportRow{
+		EffectiveDate: from,
+		NumCode:       840,
+		CharCode:      "USD",
+		Name:          "Доллар США",
+		Nominal:       1,
+		Value:         "91.3336",
+		UnitValue:     "91.3336",
+	})
+}
+
+func newServer(t *testing.T, spec *openapi.Spec) *server.Server {
+	t.Helper()
+
+	t.Setenv("DB_PASSWORD", "test")
+
+	cfg := config.New()
+
+	if err := cfg.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	info := models.SnapshotInfo{
+		Version:         1,
+		SnapshotVersion: "7.1",
+		EffectiveDate:   "2024-03-02",
+		FetchedAt:       now.Add(-time.Hour).Format(time.RFC3339),
+		Source:          cfg.CurrencySourceUrl,
+	}
+
+	rates, err := calculator.New(cfg).Rates(info, currencies, previousCurrencies, cfg.DefaultBaseCurrency)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	view, err := memcache.NewView(rates)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mc := memcache.New()
+
+	mc.SetCurrencies(&currencies)
+	mc.SetSnapshot(memcache.NewSnapshot("7.1", now.Add(-time.Hour), now.Add(time.Hour), now.Add(time.Hour),
+		info, currencies, previousCurrencies, view))
+
+	svc := &service.Service{
+		Currencies:   fakeCurrencies{},
+		History:      fakeHistory{},
+		DailyRates:   fakeDailyRates{},
+		AverageRates: fakeAverageRates{},
+		Export:       fakeExport{},
+	}
+
+	ep := endpoint.New(cfg, mc, svc, nil, clock.NewSimulated(now))
+
+	validator, err := spec.Validator()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return server.New(cfg, ep, validator)
+}
+
+func TestCheckRoutes(t *testing.T) {
+	spec, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = spec.CheckRoutes(newServer(t, spec).Routes()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestResponses sends requests to the handlers and validates responses
+// against the document. Every route must be covered by a successful
+// response at least.
+func TestResponses(t *testing.T) {
+	spec, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := newServer(t, spec)
+
+	tests := []struct {
+		method string
+		route  string
+		target string
+		body   string
+		status int
+	}{
+		{http.MethodGet, "/api/v1/currencies", "/api/v1/currencies", "", http.StatusOK},
+		{http.MethodGet, "/api/v1/currencies", "/api/v1/currencies?codes=USD,RUB&base=EUR", "", http.StatusOK},
+		{http.MethodGet, "/api/v1/currencies", "/api/v1/currencies?base=XYZ", "", http.StatusNotFound},
+		{http.MethodGet, "/api/v1/currencies", "/api/v1/currencies?base=!", "", http.StatusBadRequest},
+		{http.MethodGet, "/api/v1/currencies/:code", "/api/v1/currencies/USD", "", http.StatusOK},
+		{http.MethodGet, "/api/v1/currencies/:code", "/api/v1/currencies/978?base=USD", "", http.StatusOK},
+		{http.MethodGet, "/api/v1/currencies/:code", "/api/v1/currencies/XYZ", "", http.StatusNotFound},
+		{http.MethodGet, "/api/v1/currencies/:code/stats",
+			"/api/v1/currencies/USD/stats?from=2024-01-01&to=2024-01-31", "", http.StatusOK},
+		{http.MethodGet, "/api/v1/currencies/:code/stats",
+			"/api/v1/currencies/XYZ/stats?from=2024-01-01&to=2024-01-31", "", http.StatusNotFound},
+		{http.MethodGet, "/api/v1/currencies/:code/stats",
+			"/api/v1/currencies/USD/stats?from=2024-01-31&to=2024-01-01", "", http.StatusBadRequest},
+		{http.MethodGet, "/api/v1/currencies/:code/averages",
+			"/api/v1/currencies/USD/averages?from=2024-01-01&to=2024-01-31", "", http.StatusOK},
+		{http.MethodGet, "/api/v1/currencies/:code/candles",
+			"/api/v1/currencies/USD/candles?interval=week&from=2024-01-10&to=2024-01-12", "", http.StatusOK},
+		{http.MethodGet, "/api/v1/matrix", "/api/v1/matrix?codes=USD,EUR", "", http.StatusOK},
+		{http.MethodGet, "/api/v1/matrix", "/api/v1/matrix?format=csv", "", http.StatusOK},
+		{http.MethodPost, "/api/v1/convert/batch", "/api/v1/convert/batch",
+			`[{"from":"USD","to":"EUR","amount":"10"},{"from":"XYZ","to":"EUR","amount":1},` +
+				`{"from":"USD","to":"RUB","amount":"1","date":"2023-01-01"}]`, http.StatusOK},
+		{http.MethodPost, "/api/v1/convert/batch", "/api/v1/convert/batch", `{`, http.StatusBadRequest},
+		{http.MethodGet, "/api/v1/export", "/api/v1/export?from=2024-01-10&to=2024-01-12", "", http.StatusOK},
+		{http.MethodGet, "/api/v1/export",
+			"/api/v1/export?from=2024-01-10&to=2024-01-12&format=jsonl", "", http.StatusOK},
+		{http.MethodGet, "/api/v1/export", "/api/v1/export?from=2024-01-10", "", http.StatusBadRequest},
+		{http.MethodGet, "/api/v1/openapi.json", "/api/v1/openapi.json", "", http.StatusOK},
+	}
+
+	succeeded := make(map[string]bool)
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.target, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+
+			rec := httptest.NewRecorder()
+
+			srv.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body: %s", rec.Code, tt.status, rec.Body)
+			}
+
+			err := spec.ValidateResponse(tt.method, tt.route, rec.Code,
+				rec.Header().Get("Content-Type"), rec.Body.Bytes())
+			if err != nil {
+				t.Fatalf("response does not match document: %v, body: %s", err, rec.Body)
+			}
+
+			if rec.Code == http.StatusOK {
+				succeeded[tt.method+" "+tt.route] = true
+			}
+		})
+	}
+
+	for _, route := range srv.Routes() {
+		if !succeeded[route.Method+" "+route.Path] {
+			t.Errorf("no successful response is validated for route %s %s", route.Method, route.Path)
+		}
+	}
+}
+
+// TestNotAcceptable checks the response to a client, which refuses the
+// uncompressed body and accepts no compression of it.
+func TestNotAcceptable(t *testing.T) {
+	spec, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/currencies", nil)
+	req.Header.Set("Accept-Encoding", "identity;q=0, deflate")
+
+	rec := httptest.NewRecorder()
+
+	newServer(t, spec).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotAcceptable {
+		t.Fatalf("status = %d, want %d, body: %s", rec.Code, http.StatusNotAcceptable, rec.Body)
+	}
+
+	err = spec.ValidateResponse(http.MethodGet, "/api/v1/currencies", rec.Code,
+		rec.Header().Get("Content-Type"), rec.Body.Bytes())
+	if err != nil {
+		t.Fatalf("response does not match document: %v, body: %s", err, rec.Body)
+	}
+
+	if !strings.Contains(rec.Body.String(), `"code":"not_acceptable"`) {
+		t.Fatalf("body = %s, want code not_acceptable", rec.Body)
+	}
+}
+
+func TestValidateResponseRejects(t *testing.T) {
+	spec, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+	}{
+		{"undocumented status", http.StatusTeapot, "application/json", `{}`},
+		{"undocumented content type", http.StatusOK, "text/plain", `USD`},
+		{"missing property", http.StatusOK, "application/json", `{"version":1}`},
+		{"wrong type", http.StatusOK, "application/json",
+			`{"version":"1","snapshotVersion":"1","base":"RUB","effectiveDate":"2024-01-01",` +
+				`"fetchedAt":"2024-01-01T00:00:00Z","source":"x","codes":[],"rates":[]}`},
+		{"bad decimal", http.StatusOK, "application/json",
+			`{"version":1,"snapshotVersion":"1","base":"RUB","effectiveDate":"2024-01-01",` +
+				`"fetchedAt":"2024-01-01T00:00:00Z","source":"x","codes":["USD"],"rates":[["1,5"]]}`},
+		{"bad date-time", http.StatusOK, "application/json",
+			`{"version":1,"snapshotVersion":"1","base":"RUB","effectiveDate":"2024-01-01",` +
+				`"fetchedAt":"2024-01-01","source":"x","codes":[],"rates":[]}`},
+		{"unknown problem code", http.StatusNotFound, "application/problem+json",
+			`{"type":"x","title":"x","status":404,"instance":"/","code":"teapot"}`},
+		{"body of not modified", http.StatusNotModified, "", `{}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := spec.ValidateResponse(http.MethodGet, "/api/v1/matrix", tt.status, tt.contentType, []byte(tt.body))
+			if err == nil {
+				t.Fatal("response is accepted")
+			}
+		})
+	}
+}
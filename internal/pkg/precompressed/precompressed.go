@@ -0,0 +1,173 @@
+package precompressed
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/mrumyantsev/go-errlib"
+)
+
+const (
+	EncodingIdentity = ""
+	EncodingGzip     = "gzip"
+	EncodingBrotli   = "br"
+
+	// encodingIdentityName is the name of the identity encoding in the
+	// Accept-Encoding header
+	encodingIdentityName = "identity"
+)
+
+// A Body is a response body serialized and compressed once, so it can be
+// served to any number of clients without extra work.
+type Body struct {
+	version  string
+	identity []byte
+	gzip     []byte
+	brotli   []byte
+}
+
+// New compresses the data with every supported encoding. The version
+// is used to build entity tags of the encoded variants.
+func New(data []byte, version string) (*Body, error) {
+	var (
+		gzipped  bytes.Buffer
+		brotlied bytes.Buffer
+	)
+
+	gzipWriter, err := gzip.NewWriterLevel(&gzipped, gzip.BestCompression)
+	if err != nil {
+		return nil, errlib.Wrap(err, "could not create gzip writer")
+	}
+
+	if _, err = gzipWriter.Write(data); err != nil {
+		return nil, errlib.Wrap(err, "could not gzip data")
+	}
+
+	if err = gzipWriter.Close(); err != nil {
+		return nil, errlib.Wrap(err, "could not finish gzip stream")
+	}
+
+	brotliWriter := brotli.NewWriterLevel(&brotlied, brotli.BestCompression)
+
+	if _, err = brotliWriter.Write(data); err != nil {
+		return nil, errlib.Wrap(err, "could not compress data with brotli")
+	}
+
+	if err = brotliWriter.Close(); err != nil {
+		return nil, errlib.Wrap(err, "could not finish brotli stream")
+	}
+
+	return &Body{
+		version:  version,
+		identity: data,
+		gzip:     gzipped.Bytes(),
+		brotli:   brotlied.Bytes(),
+	}, nil
+}
+
+// Negotiate picks the best encoding accepted by the client and returns
+// its name and the encoded data. It reports false, if the client accepts
+// none of the encodings, refusing identity as well.
+func (b *Body) Negotiate(acceptEncoding string) (string, []byte, bool) {
+	encoding, ok := preferredEncoding(acceptEncoding)
+	if !ok {
+		return "", nil, false
+	}
+
+	switch encoding {
+	case EncodingBrotli:
+		return EncodingBrotli, b.brotli, true
+	case EncodingGzip:
+		return EncodingGzip, b.gzip, true
+	default:
+		return EncodingIdentity, b.identity, true
+	}
+}
+
+// ETag returns a strong entity tag of the given encoded variant. Variants
+// must have different tags, because their bytes differ.
+func (b *Body) ETag(encoding string) string {
+	if encoding == EncodingIdentity {
+		return `"` + b.version + `"`
+	}
+
+	return `"` + b.version + "-" + encoding + `"`
+}
+
+// preferredEncoding parses the Accept-Encoding header with its quality
+// values. On equal quality brotli wins over gzip, as it is smaller, and
+// both win over identity, unless the client prefers identity explicitly.
+// Identity is refused by a zero quality of its own or, if it is not
+// listed, of the wildcard, as RFC 9110 says. It reports false, if nothing
+// is acceptable then.
+func preferredEncoding(acceptEncoding string) (string, bool) {
+	var (
+		qualities = map[string]float64{}
+		wildcard  = -1.0
+	)
+
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		quality := 1.0
+
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+
+			quality = parsed
+		}
+
+		if name == "*" {
+			wildcard = quality
+		} else {
+			qualities[name] = quality
+		}
+	}
+
+	var (
+		best        = EncodingIdentity
+		bestQuality = 0.0
+	)
+
+	// identity is acceptable, unless it is refused, so only its explicit
+	// quality is compared
+	identityQuality, isIdentityListed := qualities[encodingIdentityName]
+	if isIdentityListed {
+		bestQuality = identityQuality
+	}
+
+	isIdentityRefused := (isIdentityListed && (identityQuality <= 0)) ||
+		(!isIdentityListed && (wildcard == 0))
+
+	for _, encoding := range []string{EncodingBrotli, EncodingGzip} {
+		quality, ok := qualities[encoding]
+		if !ok {
+			quality = wildcard
+		}
+
+		isPreferred := quality > bestQuality ||
+			((best == EncodingIdentity) && (quality == bestQuality) && (quality > 0))
+
+		if isPreferred {
+			best = encoding
+			bestQuality = quality
+		}
+	}
+
+	if (best == EncodingIdentity) && isIdentityRefused {
+		return "", false
+	}
+
+	return best, true
+}
@@ -0,0 +1,201 @@
+package precompressed
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"strconv"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestPreferredEncoding(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		want           string
+		isAcceptable   bool
+	}{
+		{"empty", "", EncodingIdentity, true},
+		{"gzip only", "gzip", EncodingGzip, true},
+		{"brotli only", "br", EncodingBrotli, true},
+		{"tie prefers brotli", "gzip, br", EncodingBrotli, true},
+		{"tie with qualities prefers brotli", "gzip;q=0.8, br;q=0.8", EncodingBrotli, true},
+		{"higher quality wins", "br;q=0.5, gzip;q=0.9", EncodingGzip, true},
+		{"zero quality refuses", "br;q=0, gzip", EncodingGzip, true},
+		{"all refused", "br;q=0, gzip;q=0", EncodingIdentity, true},
+		{"wildcard", "*", EncodingBrotli, true},
+		{"wildcard does not override explicit", "*, br;q=0", EncodingGzip, true},
+		{"wildcard refused", "*;q=0", "", false},
+		{"wildcard refused except identity", "*;q=0, identity", EncodingIdentity, true},
+		{"wildcard with lower quality", "*;q=0.1, gzip;q=0.5", EncodingGzip, true},
+		{"unsupported only", "deflate, compress", EncodingIdentity, true},
+		{"case and spaces", " GZip ; q=1 , BR;q=0.2", EncodingGzip, true},
+		{"invalid quality is skipped", "br;q=high, gzip", EncodingGzip, true},
+		{"identity preferred", "gzip;q=0.5, identity", EncodingIdentity, true},
+		{"identity tie prefers compression", "gzip, identity", EncodingGzip, true},
+		{"identity refused", "identity;q=0", "", false},
+		{"identity refused with alternative", "identity;q=0, gzip", EncodingGzip, true},
+		{"identity and alternatives refused", "identity;q=0, br;q=0, gzip;q=0", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, isAcceptable := preferredEncoding(tt.acceptEncoding)
+			if (got != tt.want) || (isAcceptable != tt.isAcceptable) {
+				t.Errorf("preferredEncoding(%q) = %q, %t, want %q, %t",
+					tt.acceptEncoding, got, isAcceptable, tt.want, tt.isAcceptable)
+			}
+		})
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	data := testRates(50)
+
+	body, err := New(data, "1.1700000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		acceptEncoding string
+		encoding       string
+		etag           string
+		decode         func(io.Reader) (io.Reader, error)
+	}{
+		{"", EncodingIdentity, `"1.1700000000"`, func(r io.Reader) (io.Reader, error) { return r, nil }},
+		{"gzip", EncodingGzip, `"1.1700000000-gzip"`, func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+		{"br, gzip", EncodingBrotli, `"1.1700000000-br"`, func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil }},
+	}
+
+	for _, tt := range tests {
+		encoding, encoded, ok := body.Negotiate(tt.acceptEncoding)
+		if !ok || (encoding != tt.encoding) {
+			t.Fatalf("Negotiate(%q) encoding = %q, want %q", tt.acceptEncoding, encoding, tt.encoding)
+		}
+
+		if etag := body.ETag(encoding); etag != tt.etag {
+			t.Errorf("ETag(%q) = %s, want %s", encoding, etag, tt.etag)
+		}
+
+		reader, err := tt.decode(bytes.NewReader(encoded))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(decoded, data) {
+			t.Errorf("variant %q does not decode to the original data", encoding)
+		}
+	}
+
+	if encoding, encoded, ok := body.Negotiate("identity;q=0"); ok {
+		t.Fatalf("Negotiate refusing identity = %q, %d bytes, want not acceptable", encoding, len(encoded))
+	}
+}
+
+type testCurrency struct {
+	NumCode     int    `json:"numCode"`
+	CharCode    string `json:"charCode"`
+	Name        string `json:"name"`
+	Nominal     int    `json:"nominal"`
+	Value       string `json:"value"`
+	UnitRate    string `json:"unitRate"`
+	InverseRate string `json:"inverseRate"`
+}
+
+func testCurrencies(count int) []testCurrency {
+	currencies := make([]testCurrency, count)
+
+	for i := range currencies {
+		currencies[i] = testCurrency{
+			NumCode:     100 + i,
+			CharCode:    "C" + strconv.Itoa(10+i),
+			Name:        "Currency number " + strconv.Itoa(i),
+			Nominal:     1,
+			Value:       "92." + strconv.Itoa(1000+i),
+			UnitRate:    "92." + strconv.Itoa(1000+i),
+			InverseRate: "0.0108" + strconv.Itoa(10+i),
+		}
+	}
+
+	return currencies
+}
+
+func testRates(count int) []byte {
+	data, err := json.Marshal(testCurrencies(count))
+	if err != nil {
+		panic(err)
+	}
+
+	return data
+}
+
+// BenchmarkServePrecompressed is the hot path of a request: negotiation
+// of the encoding of the body, which is prepared on publication.
+func BenchmarkServePrecompressed(b *testing.B) {
+	body, err := New(testRates(50), "1.1700000000")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		encoding, data, _ := body.Negotiate("gzip, deflate, br")
+		_ = body.ETag(encoding)
+
+		b.SetBytes(int64(len(data)))
+	}
+}
+
+// BenchmarkMarshalPerRequest is the path, which precompressed bodies
+// replace: the rates are serialized and compressed on every request.
+func BenchmarkMarshalPerRequest(b *testing.B) {
+	currencies := testCurrencies(50)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		data, err := json.Marshal(currencies)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+
+		writer := gzip.NewWriter(&buf)
+
+		if _, err = writer.Write(data); err != nil {
+			b.Fatal(err)
+		}
+
+		if err = writer.Close(); err != nil {
+			b.Fatal(err)
+		}
+
+		b.SetBytes(int64(buf.Len()))
+	}
+}
+
+// BenchmarkNew is the work made once per snapshot publication.
+func BenchmarkNew(b *testing.B) {
+	data := testRates(50)
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := New(data, "1.1700000000"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
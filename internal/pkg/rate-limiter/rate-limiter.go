@@ -0,0 +1,117 @@
+package ratelimiter
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/clock"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+	"golang.org/x/time/rate"
+)
+
+// Limits describe a token bucket: it refills with PerMinute tokens per
+// minute and holds no more than Burst tokens.
+type Limits struct {
+	PerMinute int
+	Burst     int
+}
+
+// A Result is the outcome of a single request against a bucket. Limit is
+// the quota of requests per minute.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+type visitor struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// A RateLimiter keeps a token bucket per client identifier.
+type RateLimiter struct {
+	config    *config.Config
+	clock     clock.Clock
+	mu        sync.Mutex
+	visitors  map[string]*visitor
+	lastSweep time.Time
+}
+
+func New(cfg *config.Config, clk clock.Clock) *RateLimiter {
+	return &RateLimiter{
+		config:    cfg,
+		clock:     clk,
+		visitors:  make(map[string]*visitor),
+		lastSweep: clk.Now(),
+	}
+}
+
+// Allow takes one token from the bucket of the given client.
+func (l *RateLimiter) Allow(id string, limits Limits) Result {
+	now := l.clock.Now()
+	limit := rate.Limit(float64(limits.PerMinute) / 60)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	v, ok := l.visitors[id]
+	if !ok {
+		v = &visitor{limiter: rate.NewLimiter(limit, limits.Burst)}
+		l.visitors[id] = v
+	} else {
+		if v.limiter.Limit() != limit {
+			v.limiter.SetLimitAt(now, limit)
+		}
+		if v.limiter.Burst() != limits.Burst {
+			v.limiter.SetBurstAt(now, limits.Burst)
+		}
+	}
+
+	v.lastSeen = now
+
+	allowed := v.limiter.AllowN(now, 1)
+	tokens := v.limiter.TokensAt(now)
+
+	result := Result{
+		Allowed:   allowed,
+		Limit:     limits.PerMinute,
+		Remaining: int(math.Max(0, math.Floor(tokens))),
+		Reset:     timeToRefill(float64(limits.Burst)-tokens, limit),
+	}
+
+	if !allowed {
+		result.RetryAfter = timeToRefill(1-tokens, limit)
+	}
+
+	return result
+}
+
+// sweep forgets clients which have not been seen for a while, so the
+// map does not grow with every address that ever made a request.
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.config.RateLimiterExpiresIn {
+		return
+	}
+
+	for id, v := range l.visitors {
+		if now.Sub(v.lastSeen) >= l.config.RateLimiterExpiresIn {
+			delete(l.visitors, id)
+		}
+	}
+
+	l.lastSweep = now
+}
+
+func timeToRefill(tokens float64, limit rate.Limit) time.Duration {
+	if (tokens <= 0) || (limit <= 0) {
+		return 0
+	}
+
+	return time.Duration(tokens / float64(limit) * float64(time.Second))
+}
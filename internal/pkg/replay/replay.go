@@ -0,0 +1,155 @@
+package replay
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/archive"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+	sourceclient "github.com/mrumyantsev/currency-converter-app/internal/pkg/source-client"
+	"github.com/mrumyantsev/go-errlib"
+)
+
+// ErrNoPayload is returned, when no payload of the daily feed had been
+// fetched by the moment.
+var ErrNoPayload = errors.New("no payload is fetched by this moment")
+
+type entry struct {
+	key          string
+	fetchedAt    time.Time
+	isCompressed bool
+}
+
+// A Replay serves archived payloads of the daily feed in place of the
+// source. At any moment it serves the payload, which the source responded
+// with by then.
+type Replay struct {
+	config     *config.Config
+	dir        string
+	backend    archive.Backend
+	fetchDelay time.Duration
+	entries    []entry
+
+	// served is the index of the last served entry
+	served int
+}
+
+// New indexes the payloads of the daily feed in the directory, which has
+// the layout of the local archive. Payloads, fetched within the delay after
+// a moment, are served at that moment, since the update, which started
+// then, took time to fetch them.
+func New(cfg *config.Config, dir string, fetchDelay time.Duration) (*Replay, error) {
+	backend := archive.NewLocalBackend(dir)
+
+	keys, err := backend.List("")
+	if err != nil {
+		return nil, errlib.Wrap(err, "could not index replay directory")
+	}
+
+	var (
+		name    = archive.PayloadName(cfg.CurrencySourceUrl)
+		entries []entry
+	)
+
+	for _, key := range keys {
+		// reports and payloads of other feeds are skipped
+		info, err := archive.ParseKey(key)
+		if (err != nil) || (info.Name != name) {
+			continue
+		}
+
+		entries = append(entries, entry{
+			key:          key,
+			fetchedAt:    info.FetchedAt,
+			isCompressed: info.IsCompressed,
+		})
+	}
+
+	if len(entries) == 0 {
+		return nil, errors.New("there are no payloads of daily feed in replay directory: " + dir)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].fetchedAt.Before(entries[j].fetchedAt)
+	})
+
+	return &Replay{
+		config:     cfg,
+		dir:        dir,
+		backend:    backend,
+		fetchDelay: fetchDelay,
+		entries:    entries,
+		served:     -1,
+	}, nil
+}
+
+func (r *Replay) Dir() string {
+	return r.dir
+}
+
+// Len returns the number of the payloads.
+func (r *Replay) Len() int {
+	return len(r.entries)
+}
+
+// First returns the fetch time of the first payload.
+func (r *Replay) First() time.Time {
+	return r.entries[0].fetchedAt
+}
+
+// Last returns the fetch time of the last payload.
+func (r *Replay) Last() time.Time {
+	return r.entries[len(r.entries)-1].fetchedAt
+}
+
+// Payload returns the data of the latest payload, fetched by the moment,
+// and its fetch time. As the source does for conditional requests, it
+// returns sourceclient.ErrNotModified, if the payload has been served
+// already.
+func (r *Replay) Payload(moment time.Time) ([]byte, time.Time, error) {
+	moment = moment.Add(r.fetchDelay)
+
+	i := sort.Search(len(r.entries), func(i int) bool {
+		return r.entries[i].fetchedAt.After(moment)
+	}) - 1
+
+	if i < 0 {
+		return nil, time.Time{}, ErrNoPayload
+	}
+
+	current := r.entries[i]
+
+	if i == r.served {
+		return nil, current.fetchedAt, sourceclient.ErrNotModified
+	}
+
+	data, err := r.read(current)
+	if err != nil {
+		return nil, current.fetchedAt, errlib.Wrap(err, "could not read payload "+current.key)
+	}
+
+	r.served = i
+
+	return data, current.fetchedAt, nil
+}
+
+func (r *Replay) read(current entry) ([]byte, error) {
+	object, err := r.backend.Get(current.key)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = object.Close() }()
+
+	reader := io.Reader(object)
+
+	if current.isCompressed {
+		if reader, err = gzip.NewReader(object); err != nil {
+			return nil, errlib.Wrap(err, "could not decompress payload")
+		}
+	}
+
+	return io.ReadAll(reader)
+}
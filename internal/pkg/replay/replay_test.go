@@ -0,0 +1,258 @@
+package replay
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/archive"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/clock"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+	sourceclient "github.com/mrumyantsev/currency-converter-app/internal/pkg/source-client"
+	timechecks "github.com/mrumyantsev/currency-converter-app/internal/pkg/time-checks"
+)
+
+const (
+	zone       = "Europe/Moscow"
+	sourceUrl  = "https://www.cbr.ru/scripts/XML_daily.asp"
+	fetchDelay = time.Minute
+)
+
+// payloads are fetched at the wall clock time of the zone. The one of the
+// 4th is fetched later than the delay after the update.
+var payloads = []struct {
+	fetchedAt string
+	data      string
+}{
+	{"2024-03-01 13:30:20", "first"},
+	{"2024-03-02 13:30:40", "second"},
+	{"2024-03-02 15:00:00", "second-late"},
+	{"2024-03-04 13:32:00", "fourth"},
+	{"2024-03-05 13:30:10", "fifth"},
+}
+
+func loadLocation(t *testing.T) *time.Location {
+	t.Helper()
+
+	location, err := time.LoadLocation(zone)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return location
+}
+
+// at returns the wall clock time of the zone.
+func at(t *testing.T, value string) time.Time {
+	t.Helper()
+
+	moment, err := time.ParseInLocation(time.DateTime, value, loadLocation(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return moment
+}
+
+// newReplay archives the payloads, every other one compressed, along with
+// a payload of another feed and a report, and indexes them.
+func newReplay(t *testing.T) *Replay {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	cfg := &config.Config{
+		CurrencySourceUrl: sourceUrl,
+		ArchiveBackend:    archive.BackendLocal,
+		ArchiveDir:        dir,
+	}
+
+	for i, payload := range payloads {
+		cfg.IsArchiveCompressed = i%2 == 1
+
+		a, err := archive.New(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err = a.Write(sourceUrl, nil, []byte(payload.data), at(t, payload.fetchedAt)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a, err := archive.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = a.Write("https://www.cbr.ru/scripts/XML_valFull.asp", nil, []byte("other"), at(t, "2024-03-03 13:30:00")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = a.WriteReport("export.csv", strings.NewReader("report"), at(t, "2024-03-03 13:30:00")); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := New(cfg, dir, fetchDelay)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return r
+}
+
+func TestNew(t *testing.T) {
+	r := newReplay(t)
+
+	if r.Len() != len(payloads) {
+		t.Fatalf("len = %d, want %d", r.Len(), len(payloads))
+	}
+
+	if want := at(t, payloads[0].fetchedAt); !r.First().Equal(want) {
+		t.Fatalf("first = %s, want %s", r.First(), want)
+	}
+
+	if want := at(t, payloads[len(payloads)-1].fetchedAt); !r.Last().Equal(want) {
+		t.Fatalf("last = %s, want %s", r.Last(), want)
+	}
+
+	if _, err := New(&config.Config{CurrencySourceUrl: sourceUrl}, t.TempDir(), fetchDelay); err == nil {
+		t.Fatal("replay of empty directory is created")
+	}
+}
+
+// TestPayload asks for payloads at moments one after another, as updates
+// do, since a payload is served only once.
+func TestPayload(t *testing.T) {
+	r := newReplay(t)
+
+	steps := []struct {
+		name      string
+		moment    string
+		want      string
+		fetchedAt string
+		err       error
+	}{
+		{"before first payload", "2024-03-01 13:00:00", "", "", ErrNoPayload},
+		{"first payload later than delay", "2024-03-01 13:29:10", "", "", ErrNoPayload},
+		{"first payload within delay", "2024-03-01 13:29:30", "first", "2024-03-01 13:30:20", nil},
+		{"first payload again", "2024-03-01 14:00:00", "", "2024-03-01 13:30:20", sourceclient.ErrNotModified},
+		{"next day", "2024-03-02 13:30:00", "second", "2024-03-02 13:30:40", nil},
+		{"latest of previous day", "2024-03-03 13:30:00", "second-late", "2024-03-02 15:00:00", nil},
+		{"payload later than delay", "2024-03-04 13:30:00", "", "2024-03-02 15:00:00", sourceclient.ErrNotModified},
+		{"payload exactly at delay", "2024-03-04 13:31:00", "fourth", "2024-03-04 13:32:00", nil},
+		{"after last payload", "2024-03-10 13:30:00", "fifth", "2024-03-05 13:30:10", nil},
+		{"last payload again", "2024-03-11 13:30:00", "", "2024-03-05 13:30:10", sourceclient.ErrNotModified},
+	}
+
+	for _, step := range steps {
+		data, fetchedAt, err := r.Payload(at(t, step.moment))
+
+		if !errors.Is(err, step.err) {
+			t.Fatalf("%s: error = %v, want %v", step.name, err, step.err)
+		}
+
+		if string(data) != step.want {
+			t.Fatalf("%s: payload = %q, want %q", step.name, data, step.want)
+		}
+
+		if step.fetchedAt == "" {
+			if !fetchedAt.IsZero() {
+				t.Fatalf("%s: fetched at %s, want none", step.name, fetchedAt)
+			}
+
+			continue
+		}
+
+		if want := at(t, step.fetchedAt); !fetchedAt.Equal(want) {
+			t.Fatalf("%s: fetched at %s, want %s", step.name, fetchedAt, want)
+		}
+	}
+}
+
+// TestSchedule runs the updates of the work loop for days on a simulated
+// clock: an update, which has no payload yet, is retried, and a payload,
+// which has been served, leaves the stored data as it is.
+func TestSchedule(t *testing.T) {
+	const retryDelay = 5 * time.Minute
+
+	r := newReplay(t)
+
+	clk := clock.NewSimulated(at(t, "2024-03-01 00:00:00"))
+
+	timeChecks, err := timechecks.New(&config.Config{
+		TimeWhenNeedToUpdateCurrency: "13:30:00",
+		UpdateTimeZone:               zone,
+	}, clk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	location := loadLocation(t)
+
+	var (
+		end      = at(t, "2024-03-06 00:00:00")
+		stored   models.UpdateDatetime
+		updates  = map[string]string{}
+		failures int
+	)
+
+	for clk.Now().Before(end) {
+		isNeedUpdate, err := timeChecks.IsNeedForUpdateDb(&stored)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		isSourceFailed := false
+
+		if isNeedUpdate {
+			data, _, err := r.Payload(clk.Now())
+
+			switch {
+			case errors.Is(err, sourceclient.ErrNotModified):
+			case err != nil:
+				isSourceFailed = true
+				failures++
+			default:
+				stored.Id++
+				stored.UpdateDatetime = clk.Now().UTC().Format(time.RFC3339)
+
+				updates[clk.Now().In(location).Format(time.DateTime)] = string(data)
+			}
+		}
+
+		timeToNextUpdate := timeChecks.TimeToNextUpdate()
+
+		if isSourceFailed && (retryDelay < timeToNextUpdate) {
+			timeToNextUpdate = retryDelay
+		}
+
+		clk.Sleep(timeToNextUpdate)
+	}
+
+	// the payload of the 4th came after the update and is superseded by
+	// the one of the 5th
+	want := map[string]string{
+		"2024-03-01 13:30:00": "first",
+		"2024-03-02 13:30:00": "second",
+		"2024-03-03 13:30:00": "second-late",
+		"2024-03-05 13:30:00": "fifth",
+	}
+
+	if len(updates) != len(want) {
+		t.Fatalf("updates = %v, want %v", updates, want)
+	}
+
+	for moment, data := range want {
+		if updates[moment] != data {
+			t.Fatalf("updates = %v, want %v", updates, want)
+		}
+	}
+
+	// from midnight the update of the 1st is retried until its time
+	if want := int((13*time.Hour + 30*time.Minute) / retryDelay); failures != want {
+		t.Fatalf("failures = %d, want %d", failures, want)
+	}
+}
@@ -0,0 +1,154 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/database"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+	"github.com/mrumyantsev/go-errlib"
+)
+
+var ErrApiKeyNotFound = errors.New("api key not found")
+
+type ApiKeysRepository struct {
+	config   *config.Config
+	database *database.Database
+}
+
+func NewApiKeysRepository(cfg *config.Config, db *database.Database) *ApiKeysRepository {
+	return &ApiKeysRepository{
+		config:   cfg,
+		database: db,
+	}
+}
+
+func (r *ApiKeysRepository) Create(apiKey models.ApiKey) (models.ApiKey, error) {
+	query := `INSERT INTO public.api_keys (name, key_hash, rate_limit, rate_burst)
+VALUES
+($1,$2,$3,$4)
+RETURNING id, created_at;
+	`
+
+	stmt, err := r.database.Prepare(query)
+	if err != nil {
+		return apiKey, errlib.Wrap(err, "could not prepare statement for inserting api key")
+	}
+
+	err = stmt.QueryRow(
+		apiKey.Name,
+		apiKey.KeyHash,
+		apiKey.RateLimit,
+		apiKey.RateBurst,
+	).Scan(&apiKey.Id, &apiKey.CreatedAt)
+	if err != nil {
+		return apiKey, errlib.Wrap(err, "could not execute inserting of api key")
+	}
+
+	return apiKey, nil
+}
+
+func (r *ApiKeysRepository) GetByHash(keyHash string) (models.ApiKey, error) {
+	query := `SELECT id, name, key_hash, rate_limit, rate_burst, created_at, revoked_at
+FROM public.api_keys
+WHERE key_hash = $1;
+	`
+
+	var apiKey models.ApiKey
+
+	stmt, err := r.database.Prepare(query)
+	if err != nil {
+		return apiKey, errlib.Wrap(err, "could not prepare statement for getting api key")
+	}
+
+	apiKey, err = scanApiKey(stmt.QueryRow(keyHash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return apiKey, ErrApiKeyNotFound
+	}
+	if err != nil {
+		return apiKey, errlib.Wrap(err, "could not scan api key from a row")
+	}
+
+	return apiKey, nil
+}
+
+func (r *ApiKeysRepository) GetAll() ([]models.ApiKey, error) {
+	query := `SELECT id, name, key_hash, rate_limit, rate_burst, created_at, revoked_at
+FROM public.api_keys
+ORDER BY id;
+	`
+
+	apiKeys := []models.ApiKey{}
+
+	rows, err := r.database.Query(query)
+	if err != nil {
+		return apiKeys, errlib.Wrap(err, "could not perform select of api keys")
+	}
+	defer func() { _ = rows.Close() }()
+
+	var apiKey models.ApiKey
+
+	for rows.Next() {
+		if apiKey, err = scanApiKey(rows); err != nil {
+			return apiKeys, errlib.Wrap(err, "could not scan api key from a row")
+		}
+
+		apiKeys = append(apiKeys, apiKey)
+	}
+
+	return apiKeys, nil
+}
+
+func (r *ApiKeysRepository) Revoke(id int) error {
+	query := `UPDATE public.api_keys
+SET revoked_at = NOW()
+WHERE id = $1 AND revoked_at IS NULL;
+	`
+
+	stmt, err := r.database.Prepare(query)
+	if err != nil {
+		return errlib.Wrap(err, "could not prepare statement for revoking api key")
+	}
+
+	res, err := stmt.Exec(id)
+	if err != nil {
+		return errlib.Wrap(err, "could not execute revoking of api key")
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return errlib.Wrap(err, "could not get count of revoked api keys")
+	}
+
+	if affected == 0 {
+		return ErrApiKeyNotFound
+	}
+
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanApiKey(row rowScanner) (models.ApiKey, error) {
+	var (
+		apiKey    models.ApiKey
+		revokedAt sql.NullString
+	)
+
+	err := row.Scan(
+		&apiKey.Id,
+		&apiKey.Name,
+		&apiKey.KeyHash,
+		&apiKey.RateLimit,
+		&apiKey.RateBurst,
+		&apiKey.CreatedAt,
+		&revokedAt,
+	)
+
+	apiKey.RevokedAt = revokedAt.String
+
+	return apiKey, err
+}
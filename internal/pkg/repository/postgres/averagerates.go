@@ -0,0 +1,197 @@
+package postgres
+
+import (
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/database"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+	"github.com/mrumyantsev/go-errlib"
+)
+
+// averagePeriods are the periods averages are maintained for, with the
+// intervals they last.
+var averagePeriods = []struct {
+	name     string
+	interval string
+}{
+	{name: models.PeriodDay, interval: "1 day"},
+	{name: models.PeriodMonth, interval: "1 month"},
+	{name: models.PeriodQuarter, interval: "3 months"},
+}
+
+type AverageRatesRepository struct {
+	config   *config.Config
+	database *database.Database
+}
+
+func NewAverageRatesRepository(cfg *config.Config, db *database.Database) *AverageRatesRepository {
+	return &AverageRatesRepository{
+		config:   cfg,
+		database: db,
+	}
+}
+
+// Refresh recomputes averages of all the periods, which include the
+// effective date, in a single transaction.
+func (r *AverageRatesRepository) Refresh(effectiveDate string) error {
+	query := `INSERT INTO public.average_rates
+(period, period_start, info_num_code, average_value, rates_count, updated_at)
+SELECT
+	$1,
+	DATE_TRUNC($1, effective_date)::DATE,
+	num_code,
+	AVG(unit_value),
+	COUNT(*),
+	NOW()
+FROM public.daily_rates
+WHERE effective_date >= DATE_TRUNC($1, $2::DATE)
+	AND effective_date < DATE_TRUNC($1, $2::DATE) + $3::INTERVAL
+GROUP BY 2, 3
+ON CONFLICT (period, info_num_code, period_start) DO UPDATE
+SET
+	average_value = EXCLUDED.average_value,
+	rates_count = EXCLUDED.rates_count,
+	updated_at = EXCLUDED.updated_at;
+	`
+
+	tx, err := r.database.Begin()
+	if err != nil {
+		return errlib.Wrap(err, "could not begin transaction for refreshing average rates")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		return errlib.Wrap(err, "could not prepare statement for refreshing average rates")
+	}
+
+	for _, period := range averagePeriods {
+		if _, err = stmt.Exec(period.name, effectiveDate, period.interval); err != nil {
+			return errlib.Wrap(err, "could not execute refreshing of "+period.name+" average rates")
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return errlib.Wrap(err, "could not commit refreshing of average rates")
+	}
+
+	return nil
+}
+
+// RefreshAll recomputes averages of all the periods over the whole
+// history. Averages of periods, which have no daily rates anymore, are
+// deleted in the same transaction, so the table holds exactly the
+// refreshed averages.
+func (r *AverageRatesRepository) RefreshAll() error {
+	deleteQuery := `DELETE FROM public.average_rates
+WHERE public.average_rates.period = $1
+	AND NOT EXISTS (
+		SELECT 1
+		FROM public.daily_rates
+		WHERE public.daily_rates.num_code = public.average_rates.info_num_code
+			AND public.daily_rates.effective_date >= public.average_rates.period_start
+			AND public.daily_rates.effective_date < public.average_rates.period_start + $2::INTERVAL
+	);
+	`
+
+	query := `INSERT INTO public.average_rates
+(period, period_start, info_num_code, average_value, rates_count, updated_at)
+SELECT
+	$1,
+	DATE_TRUNC($1, effective_date)::DATE,
+	num_code,
+	AVG(unit_value),
+	COUNT(*),
+	NOW()
+FROM public.daily_rates
+GROUP BY 2, 3
+ON CONFLICT (period, info_num_code, period_start) DO UPDATE
+SET
+	average_value = EXCLUDED.average_value,
+	rates_count = EXCLUDED.rates_count,
+	updated_at = EXCLUDED.updated_at;
+	`
+
+	tx, err := r.database.Begin()
+	if err != nil {
+		return errlib.Wrap(err, "could not begin transaction for rebuilding average rates")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	deleteStmt, err := tx.Prepare(deleteQuery)
+	if err != nil {
+		return errlib.Wrap(err, "could not prepare statement for deleting stale average rates")
+	}
+
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		return errlib.Wrap(err, "could not prepare statement for rebuilding average rates")
+	}
+
+	for _, period := range averagePeriods {
+		if _, err = deleteStmt.Exec(period.name, period.interval); err != nil {
+			return errlib.Wrap(err, "could not execute deleting of stale "+period.name+" average rates")
+		}
+
+		if _, err = stmt.Exec(period.name); err != nil {
+			return errlib.Wrap(err, "could not execute rebuilding of "+period.name+" average rates")
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return errlib.Wrap(err, "could not commit rebuilding of average rates")
+	}
+
+	return nil
+}
+
+// Get returns averages of the currency with the alphabetic code over the
+// periods, which overlap the first and the last dates, ordered by start.
+func (r *AverageRatesRepository) Get(
+	charCode string,
+	period string,
+	from string,
+	to string,
+) ([]models.AverageRate, error) {
+	query := `SELECT
+	public.average_rates.period_start::TEXT,
+	public.average_rates.average_value::TEXT,
+	public.average_rates.rates_count
+FROM public.average_rates
+JOIN public.info
+	ON public.average_rates.info_num_code = public.info.num_code
+WHERE public.info.char_code = $1
+	AND public.average_rates.period = $2
+	AND public.average_rates.period_start BETWEEN DATE_TRUNC($2, $3::DATE)::DATE AND $4
+ORDER BY public.average_rates.period_start;
+	`
+
+	averageRates := []models.AverageRate{}
+
+	stmt, err := r.database.Prepare(query)
+	if err != nil {
+		return averageRates, errlib.Wrap(err, "could not prepare statement for getting average rates")
+	}
+
+	rows, err := stmt.Query(charCode, period, from, to)
+	if err != nil {
+		return averageRates, errlib.Wrap(err, "could not perform select of average rates")
+	}
+	defer func() { _ = rows.Close() }()
+
+	var averageRate models.AverageRate
+
+	for rows.Next() {
+		err = rows.Scan(
+			&averageRate.PeriodStart,
+			&averageRate.Average,
+			&averageRate.Count,
+		)
+		if err != nil {
+			return averageRates, errlib.Wrap(err, "could not scan average rate from a row")
+		}
+
+		averageRates = append(averageRates, averageRate)
+	}
+
+	return averageRates, nil
+}
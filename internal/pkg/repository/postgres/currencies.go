@@ -21,18 +21,26 @@ func NewCurrenciesRepository(cfg *config.Config, db *database.Database) *Currenc
 	}
 }
 
+// Create stores values of the currencies and records their codes in the
+// source for the known currencies, which do not have them yet.
 func (r *CurrenciesRepository) Create(currencies models.Currencies, updateDatetimeId int) error {
 	query := `INSERT INTO public.currency_values
-(currency_value, update_datetime_id, info_num_code)
+(currency_value, multiplier, unit_rate, update_datetime_id, info_num_code)
 VALUES
-($1,$2,$3)
+($1,$2,$3,$4,$5)
+	`
+
+	sourceIdQuery := `UPDATE public.info
+SET source_id = $1
+WHERE num_code = $2
+	AND source_id IS NULL;
 	`
 
 	currenciesLength := len(currencies.Currencies)
 
 	extendCurrenciesQuery(
 		&query,
-		4, // means next placeholder ($4)
+		6, // means next placeholder ($6)
 		0,
 		currenciesLength-1,
 	)
@@ -50,6 +58,8 @@ VALUES
 		entries = append(
 			entries,
 			currency.Value,
+			currency.Multiplier,
+			nullDecimal(currency.UnitRate),
 			updateDatetimeId,
 			currency.NumCode,
 		)
@@ -59,6 +69,21 @@ VALUES
 		return errlib.Wrap(err, "could not execute inserting of currencies")
 	}
 
+	sourceIdStmt, err := r.database.Prepare(sourceIdQuery)
+	if err != nil {
+		return errlib.Wrap(err, "could not prepare statement for updating source ids")
+	}
+
+	for _, currency := range currencies.Currencies {
+		if currency.SourceId == "" {
+			continue
+		}
+
+		if _, err = sourceIdStmt.Exec(currency.SourceId, currency.NumCode); err != nil {
+			return errlib.Wrap(err, "could not execute updating of source id of "+currency.CharCode)
+		}
+	}
+
 	return nil
 }
 
@@ -66,12 +91,12 @@ func (r *CurrenciesRepository) GetLatest(updateDatetimeId int) (models.Currencie
 	query := `SELECT
 	public.info.num_code,
 	public.info.char_code,
-	public.multipliers.multiplier,
+	public.currency_values.multiplier,
 	public.info.name,
-	public.currency_values.currency_value
-FROM public.multipliers
-JOIN public.info
-	ON public.multipliers.id = public.info.multiplier_id
+	public.currency_values.currency_value,
+	COALESCE(public.currency_values.unit_rate::TEXT, ''),
+	COALESCE(public.info.source_id, '')
+FROM public.info
 JOIN public.currency_values
 	ON public.info.num_code = public.currency_values.info_num_code
 WHERE public.currency_values.update_datetime_id = $1
@@ -106,6 +131,8 @@ ORDER BY public.info.name;
 			&currency.Multiplier,
 			&currency.Name,
 			&currency.Value,
+			&currency.UnitRate,
+			&currency.SourceId,
 		)
 		if err != nil {
 			return currencies, errlib.Wrap(err, "could not scan currency entry from a row")
@@ -122,7 +149,18 @@ ORDER BY public.info.name;
 
 func extendCurrenciesQuery(query *string, startPlaceholder int, startLine int, endLine int) {
 	for i := startLine; i < endLine; i++ {
-		*query += fmt.Sprintf(",($%d,$%d,$%d)", startPlaceholder, startPlaceholder+1, startPlaceholder+2)
-		startPlaceholder += 3
+		*query += fmt.Sprintf(",($%d,$%d,$%d,$%d,$%d)",
+			startPlaceholder, startPlaceholder+1, startPlaceholder+2, startPlaceholder+3, startPlaceholder+4)
+		startPlaceholder += 5
 	}
 }
+
+// nullDecimal returns NULL for an empty decimal, since optional values of
+// feeds are absent rather than zero.
+func nullDecimal(d models.Decimal) any {
+	if d == "" {
+		return nil
+	}
+
+	return string(d)
+}
@@ -0,0 +1,121 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/database"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+	"github.com/mrumyantsev/go-errlib"
+)
+
+var ErrCurrencyNotFound = errors.New("currency not found")
+
+type DailyRatesRepository struct {
+	config   *config.Config
+	database *database.Database
+}
+
+func NewDailyRatesRepository(cfg *config.Config, db *database.Database) *DailyRatesRepository {
+	return &DailyRatesRepository{
+		config:   cfg,
+		database: db,
+	}
+}
+
+// GetCharCode returns the alphabetic code of the currency, which is
+// looked up by its alphabetic or numeric code.
+func (r *DailyRatesRepository) GetCharCode(code string) (string, error) {
+	query := `SELECT char_code
+FROM public.info
+WHERE char_code = UPPER($1) OR num_code = $2;
+	`
+
+	numCode, err := strconv.Atoi(code)
+	if err != nil {
+		numCode = -1
+	}
+
+	stmt, err := r.database.Prepare(query)
+	if err != nil {
+		return "", errlib.Wrap(err, "could not prepare statement for getting currency code")
+	}
+
+	var charCode string
+
+	err = stmt.QueryRow(code, numCode).Scan(&charCode)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrCurrencyNotFound
+	}
+	if err != nil {
+		return "", errlib.Wrap(err, "could not scan currency code from a row")
+	}
+
+	return charCode, nil
+}
+
+// Get returns daily rates of the currency with the alphabetic code from
+// the first to the last date inclusive, ordered by date.
+func (r *DailyRatesRepository) Get(charCode string, from string, to string) ([]models.DailyRate, error) {
+	query := `SELECT effective_date::TEXT, unit_value::TEXT
+FROM public.daily_rates
+WHERE char_code = $1 AND effective_date BETWEEN $2 AND $3
+ORDER BY effective_date;
+	`
+
+	dailyRates := []models.DailyRate{}
+
+	stmt, err := r.database.Prepare(query)
+	if err != nil {
+		return dailyRates, errlib.Wrap(err, "could not prepare statement for getting daily rates")
+	}
+
+	rows, err := stmt.Query(charCode, from, to)
+	if err != nil {
+		return dailyRates, errlib.Wrap(err, "could not perform select of daily rates")
+	}
+	defer func() { _ = rows.Close() }()
+
+	var dailyRate models.DailyRate
+
+	for rows.Next() {
+		if err = rows.Scan(&dailyRate.EffectiveDate, &dailyRate.UnitValue); err != nil {
+			return dailyRates, errlib.Wrap(err, "could not scan daily rate from a row")
+		}
+
+		dailyRates = append(dailyRates, dailyRate)
+	}
+
+	return dailyRates, nil
+}
+
+// GetLast returns the latest daily rate of the currency with the
+// alphabetic code before the date. Zero value is returned, if there is
+// no such.
+func (r *DailyRatesRepository) GetLast(charCode string, before string) (models.DailyRate, error) {
+	query := `SELECT effective_date::TEXT, unit_value::TEXT
+FROM public.daily_rates
+WHERE char_code = $1 AND effective_date < $2
+ORDER BY effective_date DESC
+LIMIT 1;
+	`
+
+	var dailyRate models.DailyRate
+
+	stmt, err := r.database.Prepare(query)
+	if err != nil {
+		return dailyRate, errlib.Wrap(err, "could not prepare statement for getting last daily rate")
+	}
+
+	err = stmt.QueryRow(charCode, before).Scan(&dailyRate.EffectiveDate, &dailyRate.UnitValue)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.DailyRate{}, nil
+	}
+	if err != nil {
+		return dailyRate, errlib.Wrap(err, "could not scan last daily rate from a row")
+	}
+
+	return dailyRate, nil
+}
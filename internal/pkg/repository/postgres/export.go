@@ -0,0 +1,137 @@
+package postgres
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/database"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+	"github.com/mrumyantsev/go-errlib"
+)
+
+type ExportRepository struct {
+	config   *config.Config
+	database *database.Database
+}
+
+func NewExportRepository(cfg *config.Config, db *database.Database) *ExportRepository {
+	return &ExportRepository{
+		config:   cfg,
+		database: db,
+	}
+}
+
+// Stream passes daily rates of the currencies with the given alphabetic
+// or numeric codes, or of all the currencies, if no codes are given, from
+// the first to the last date inclusive to fn, ordered by date and numeric
+// code. Rows are read by a cursor in batches, so the whole result is
+// never held in memory. Streaming stops on the first error of fn.
+func (r *ExportRepository) Stream(
+	codes []string,
+	from string,
+	to string,
+	fn func(row models.ExportRow) error,
+) error {
+	declareQuery := `DECLARE export_cursor NO SCROLL CURSOR FOR
+SELECT DISTINCT ON (public.update_datetimes.effective_date, public.info.num_code)
+	public.update_datetimes.effective_date::TEXT,
+	public.info.num_code,
+	public.info.char_code,
+	public.info.name,
+	public.currency_values.multiplier,
+	public.currency_values.currency_value::TEXT,
+	(public.currency_values.currency_value / public.currency_values.multiplier)::TEXT
+FROM public.currency_values
+JOIN public.update_datetimes
+	ON public.currency_values.update_datetime_id = public.update_datetimes.id
+JOIN public.info
+	ON public.currency_values.info_num_code = public.info.num_code
+WHERE public.update_datetimes.effective_date BETWEEN $1 AND $2
+	AND (
+		CARDINALITY($3::TEXT[]) = 0
+		OR public.info.char_code = ANY($3::TEXT[])
+		OR public.info.num_code::TEXT = ANY($3::TEXT[])
+	)
+ORDER BY
+	public.update_datetimes.effective_date,
+	public.info.num_code,
+	public.update_datetimes.id DESC;
+	`
+
+	fetchQuery := `FETCH FORWARD ` + strconv.Itoa(r.config.ExportFetchSize) + ` FROM export_cursor;`
+
+	tx, err := r.database.Begin()
+	if err != nil {
+		return errlib.Wrap(err, "could not begin transaction for exporting")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err = tx.Exec(declareQuery, from, to, pq.Array(normalizeCodes(codes))); err != nil {
+		return errlib.Wrap(err, "could not declare cursor for exporting")
+	}
+
+	var row models.ExportRow
+
+	for {
+		rows, err := tx.Query(fetchQuery)
+		if err != nil {
+			return errlib.Wrap(err, "could not fetch rows for exporting")
+		}
+
+		fetched := 0
+
+		for rows.Next() {
+			err = rows.Scan(
+				&row.EffectiveDate,
+				&row.NumCode,
+				&row.CharCode,
+				&row.Name,
+				&row.Nominal,
+				&row.Value,
+				&row.UnitValue,
+			)
+			if err == nil {
+				err = fn(row)
+			}
+			if err != nil {
+				_ = rows.Close()
+
+				return errlib.Wrap(err, "could not export row")
+			}
+
+			fetched++
+		}
+
+		if err = rows.Close(); err != nil {
+			return errlib.Wrap(err, "could not close fetched rows")
+		}
+
+		if fetched < r.config.ExportFetchSize {
+			break
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return errlib.Wrap(err, "could not commit exporting")
+	}
+
+	return nil
+}
+
+// normalizeCodes drops leading zeros of numeric codes and upper-cases
+// alphabetic ones, so they match the stored codes.
+func normalizeCodes(codes []string) []string {
+	normalized := make([]string, len(codes))
+
+	for i, code := range codes {
+		if numCode, err := strconv.Atoi(code); err == nil {
+			code = strconv.Itoa(numCode)
+		}
+
+		normalized[i] = strings.ToUpper(code)
+	}
+
+	return normalized
+}
@@ -0,0 +1,270 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/database"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+	"github.com/mrumyantsev/go-errlib"
+)
+
+type ImportRepository struct {
+	config   *config.Config
+	database *database.Database
+}
+
+func NewImportRepository(cfg *config.Config, db *database.Database) *ImportRepository {
+	return &ImportRepository{
+		config:   cfg,
+		database: db,
+	}
+}
+
+// GetInfo returns the known currencies with their current nominals and
+// codes in the source, and without values.
+func (r *ImportRepository) GetInfo() ([]models.Currency, error) {
+	query := `SELECT
+	public.info.num_code,
+	public.info.char_code,
+	public.multipliers.multiplier,
+	public.info.name,
+	COALESCE(public.info.source_id, '')
+FROM public.info
+JOIN public.multipliers
+	ON public.info.multiplier_id = public.multipliers.id
+ORDER BY public.info.num_code;
+	`
+
+	currencies := make([]models.Currency, 0, r.config.InitialCurrenciesCapacity)
+
+	rows, err := r.database.Query(query)
+	if err != nil {
+		return nil, errlib.Wrap(err, "could not perform select of currency info")
+	}
+	defer func() { _ = rows.Close() }()
+
+	var currency models.Currency
+
+	for rows.Next() {
+		err = rows.Scan(
+			&currency.NumCode,
+			&currency.CharCode,
+			&currency.Multiplier,
+			&currency.Name,
+			&currency.SourceId,
+		)
+		if err != nil {
+			return nil, errlib.Wrap(err, "could not scan currency info from a row")
+		}
+
+		currencies = append(currencies, currency)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, errlib.Wrap(err, "could not read currency info")
+	}
+
+	return currencies, nil
+}
+
+// CreateSnapshot stores the currencies as a snapshot of the effective
+// date in a single transaction, adding the currencies and nominals, which
+// are not known yet. Dates, which already have a snapshot, are left as
+// they are, and false is returned for them.
+func (r *ImportRepository) CreateSnapshot(snapshot models.DatedCurrencies, updateDatetime string) (bool, error) {
+	lockQuery := `SELECT pg_advisory_xact_lock(HASHTEXT('import:' || $1));`
+
+	existsQuery := `SELECT EXISTS (
+	SELECT 1
+	FROM public.update_datetimes
+	WHERE effective_date = $1
+);
+	`
+
+	multiplierQuery := `INSERT INTO public.multipliers (id, multiplier)
+SELECT
+	(SELECT COALESCE(MAX(id), -1) + 1 FROM public.multipliers),
+	$1::INTEGER
+WHERE NOT EXISTS (
+	SELECT 1
+	FROM public.multipliers
+	WHERE multiplier = $1
+);
+	`
+
+	infoQuery := `INSERT INTO public.info (num_code, char_code, multiplier_id, name, source_id)
+VALUES (
+	$1,
+	$2,
+	(SELECT MIN(id) FROM public.multipliers WHERE multiplier = $3),
+	$4,
+	NULLIF($5, '')
+)
+ON CONFLICT (num_code) DO UPDATE
+SET source_id = COALESCE(public.info.source_id, EXCLUDED.source_id);
+	`
+
+	updateDatetimeQuery := `INSERT INTO public.update_datetimes (update_datetime, effective_date, feed_name)
+VALUES
+($1,$2,NULLIF($3, ''))
+RETURNING id;
+	`
+
+	valueQuery := `INSERT INTO public.currency_values
+(currency_value, multiplier, unit_rate, update_datetime_id, info_num_code)
+VALUES
+($1,$2,$3,$4,$5);
+	`
+
+	tx, err := r.database.Begin()
+	if err != nil {
+		return false, errlib.Wrap(err, "could not begin transaction for importing snapshot")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// serializes concurrent imports of the same date
+	if _, err = tx.Exec(lockQuery, snapshot.EffectiveDate); err != nil {
+		return false, errlib.Wrap(err, "could not lock date for importing")
+	}
+
+	var isExist bool
+
+	if err = tx.QueryRow(existsQuery, snapshot.EffectiveDate).Scan(&isExist); err != nil {
+		return false, errlib.Wrap(err, "could not check for existing snapshot")
+	}
+
+	if isExist {
+		return false, nil
+	}
+
+	multiplierStmt, err := tx.Prepare(multiplierQuery)
+	if err != nil {
+		return false, errlib.Wrap(err, "could not prepare statement for inserting multipliers")
+	}
+
+	infoStmt, err := tx.Prepare(infoQuery)
+	if err != nil {
+		return false, errlib.Wrap(err, "could not prepare statement for inserting currency info")
+	}
+
+	valueStmt, err := tx.Prepare(valueQuery)
+	if err != nil {
+		return false, errlib.Wrap(err, "could not prepare statement for inserting currencies")
+	}
+
+	var updateDatetimeId int
+
+	err = tx.QueryRow(updateDatetimeQuery, updateDatetime, snapshot.EffectiveDate,
+		snapshot.Currencies.Name).Scan(&updateDatetimeId)
+	if err != nil {
+		return false, errlib.Wrap(err, "could not execute inserting of datetime")
+	}
+
+	for _, currency := range snapshot.Currencies.Currencies {
+		if _, err = multiplierStmt.Exec(currency.Multiplier); err != nil {
+			return false, errlib.Wrap(err, "could not execute inserting of multiplier")
+		}
+
+		_, err = infoStmt.Exec(currency.NumCode, currency.CharCode, currency.Multiplier, currency.Name,
+			currency.SourceId)
+		if err != nil {
+			return false, errlib.Wrap(err, "could not execute inserting of currency info")
+		}
+
+		_, err = valueStmt.Exec(currency.Value, currency.Multiplier, nullDecimal(currency.UnitRate),
+			updateDatetimeId, currency.NumCode)
+		if err != nil {
+			return false, errlib.Wrap(err, "could not execute inserting of currency "+currency.CharCode)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return false, errlib.Wrap(err, "could not commit importing of snapshot")
+	}
+
+	return true, nil
+}
+
+// SyncInfo adds the currencies of the reference list, which are not known
+// yet, and updates codes, names, nominals and codes in the source of the
+// known ones in a single transaction. Numbers of added and updated
+// currencies are returned, the unchanged ones are not counted.
+func (r *ImportRepository) SyncInfo(references []models.CurrencyReference) (int, int, error) {
+	multiplierQuery := `INSERT INTO public.multipliers (id, multiplier)
+SELECT
+	(SELECT COALESCE(MAX(id), -1) + 1 FROM public.multipliers),
+	$1::INTEGER
+WHERE NOT EXISTS (
+	SELECT 1
+	FROM public.multipliers
+	WHERE multiplier = $1
+);
+	`
+
+	infoQuery := `INSERT INTO public.info (num_code, char_code, multiplier_id, name, source_id)
+VALUES (
+	$1,
+	$2,
+	(SELECT MIN(id) FROM public.multipliers WHERE multiplier = $3),
+	$4,
+	$5
+)
+ON CONFLICT (num_code) DO UPDATE
+SET
+	char_code = EXCLUDED.char_code,
+	multiplier_id = EXCLUDED.multiplier_id,
+	name = EXCLUDED.name,
+	source_id = EXCLUDED.source_id
+WHERE (public.info.char_code, public.info.multiplier_id, public.info.name, public.info.source_id)
+	IS DISTINCT FROM (EXCLUDED.char_code, EXCLUDED.multiplier_id, EXCLUDED.name, EXCLUDED.source_id)
+RETURNING (xmax = 0);
+	`
+
+	tx, err := r.database.Begin()
+	if err != nil {
+		return 0, 0, errlib.Wrap(err, "could not begin transaction for syncing currency info")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	multiplierStmt, err := tx.Prepare(multiplierQuery)
+	if err != nil {
+		return 0, 0, errlib.Wrap(err, "could not prepare statement for inserting multipliers")
+	}
+
+	infoStmt, err := tx.Prepare(infoQuery)
+	if err != nil {
+		return 0, 0, errlib.Wrap(err, "could not prepare statement for syncing currency info")
+	}
+
+	var created, updated int
+
+	for _, reference := range references {
+		if _, err = multiplierStmt.Exec(reference.Multiplier); err != nil {
+			return 0, 0, errlib.Wrap(err, "could not execute inserting of multiplier")
+		}
+
+		var isCreated bool
+
+		err = infoStmt.QueryRow(reference.NumCode, reference.CharCode, reference.Multiplier,
+			reference.Name, reference.SourceId).Scan(&isCreated)
+
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			continue
+		case err != nil:
+			return 0, 0, errlib.Wrap(err, "could not execute syncing of currency info "+reference.CharCode)
+		case isCreated:
+			created++
+		default:
+			updated++
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, 0, errlib.Wrap(err, "could not commit syncing of currency info")
+	}
+
+	return created, updated, nil
+}
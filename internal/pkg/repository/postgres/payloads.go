@@ -0,0 +1,167 @@
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/database"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+	"github.com/mrumyantsev/go-errlib"
+)
+
+type PayloadsRepository struct {
+	config   *config.Config
+	database *database.Database
+}
+
+func NewPayloadsRepository(cfg *config.Config, db *database.Database) *PayloadsRepository {
+	return &PayloadsRepository{
+		config:   cfg,
+		database: db,
+	}
+}
+
+func (r *PayloadsRepository) Create(payload models.Payload) (models.Payload, error) {
+	query := `INSERT INTO public.source_payloads
+(url, fetched_at, sha256, size, header, archive_key, is_compressed)
+VALUES
+($1,$2,$3,$4,$5,$6,$7)
+RETURNING id;
+	`
+
+	header, err := json.Marshal(payload.Header)
+	if err != nil {
+		return payload, errlib.Wrap(err, "could not encode payload header")
+	}
+
+	stmt, err := r.database.Prepare(query)
+	if err != nil {
+		return payload, errlib.Wrap(err, "could not prepare statement for inserting payload")
+	}
+
+	err = stmt.QueryRow(
+		payload.Url,
+		payload.FetchedAt,
+		payload.Sha256,
+		payload.Size,
+		header,
+		payload.Key,
+		payload.IsCompressed,
+	).Scan(&payload.Id)
+	if err != nil {
+		return payload, errlib.Wrap(err, "could not execute inserting of payload")
+	}
+
+	return payload, nil
+}
+
+// SetUpdateDatetime links the payload to the update, which snapshot has
+// been stored from it.
+func (r *PayloadsRepository) SetUpdateDatetime(id int, updateDatetimeId int) error {
+	query := `UPDATE public.source_payloads
+SET update_datetime_id = $2
+WHERE id = $1;
+	`
+
+	stmt, err := r.database.Prepare(query)
+	if err != nil {
+		return errlib.Wrap(err, "could not prepare statement for linking payload")
+	}
+
+	if _, err = stmt.Exec(id, updateDatetimeId); err != nil {
+		return errlib.Wrap(err, "could not execute linking of payload")
+	}
+
+	return nil
+}
+
+func (r *PayloadsRepository) GetAll() ([]models.Payload, error) {
+	query := `SELECT id, url, fetched_at, sha256, size, header, archive_key, is_compressed, update_datetime_id
+FROM public.source_payloads
+ORDER BY fetched_at, id;
+	`
+
+	return r.get(query)
+}
+
+// GetFetchedBefore returns the payloads, which were fetched before the
+// given time.
+func (r *PayloadsRepository) GetFetchedBefore(datetime string) ([]models.Payload, error) {
+	query := `SELECT id, url, fetched_at, sha256, size, header, archive_key, is_compressed, update_datetime_id
+FROM public.source_payloads
+WHERE fetched_at < $1
+ORDER BY fetched_at, id;
+	`
+
+	return r.get(query, datetime)
+}
+
+func (r *PayloadsRepository) Delete(id int) error {
+	query := `DELETE FROM public.source_payloads
+WHERE id = $1;
+	`
+
+	stmt, err := r.database.Prepare(query)
+	if err != nil {
+		return errlib.Wrap(err, "could not prepare statement for deleting payload")
+	}
+
+	if _, err = stmt.Exec(id); err != nil {
+		return errlib.Wrap(err, "could not execute deleting of payload")
+	}
+
+	return nil
+}
+
+func (r *PayloadsRepository) get(query string, args ...any) ([]models.Payload, error) {
+	payloads := []models.Payload{}
+
+	stmt, err := r.database.Prepare(query)
+	if err != nil {
+		return payloads, errlib.Wrap(err, "could not prepare statement for getting payloads")
+	}
+
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		return payloads, errlib.Wrap(err, "could not perform select of payloads")
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var (
+			payload          models.Payload
+			header           []byte
+			updateDatetimeId sql.NullInt64
+		)
+
+		err = rows.Scan(
+			&payload.Id,
+			&payload.Url,
+			&payload.FetchedAt,
+			&payload.Sha256,
+			&payload.Size,
+			&header,
+			&payload.Key,
+			&payload.IsCompressed,
+			&updateDatetimeId,
+		)
+		if err != nil {
+			return payloads, errlib.Wrap(err, "could not scan payload from a row")
+		}
+
+		if err = json.Unmarshal(header, &payload.Header); err != nil {
+			return payloads, errlib.Wrap(err, "could not decode payload header")
+		}
+
+		payload.UpdateDatetimeId = int(updateDatetimeId.Int64)
+
+		payloads = append(payloads, payload)
+	}
+
+	if err = rows.Err(); err != nil {
+		return payloads, errlib.Wrap(err, "could not read payloads")
+	}
+
+	return payloads, nil
+}
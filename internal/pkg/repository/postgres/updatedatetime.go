@@ -19,15 +19,18 @@ func NewUpdateDatetimeRepository(cfg *config.Config, db *database.Database) *Upd
 	}
 }
 
-func (r *UpdateDatetimeRepository) Create(datetime string) (models.UpdateDatetime, error) {
-	query := `INSERT INTO public.update_datetimes (update_datetime)
+// Create stores the update with the name of its feed, which may be empty.
+func (r *UpdateDatetimeRepository) Create(datetime string, effectiveDate string, feedName string) (models.UpdateDatetime, error) {
+	query := `INSERT INTO public.update_datetimes (update_datetime, effective_date, feed_name)
 VALUES
-($1)
+($1,$2,NULLIF($3, ''))
 RETURNING id;
 	`
 
 	updateDatetime := models.UpdateDatetime{
 		UpdateDatetime: datetime,
+		EffectiveDate:  effectiveDate,
+		FeedName:       feedName,
 	}
 
 	stmt, err := r.database.Prepare(query)
@@ -35,23 +38,22 @@ RETURNING id;
 		return updateDatetime, errlib.Wrap(err, "could not prepare statement for inserting datetime")
 	}
 
-	row := stmt.QueryRow(datetime)
+	err = stmt.QueryRow(datetime, effectiveDate, feedName).Scan(&updateDatetime.Id)
 	if err != nil {
 		return updateDatetime, errlib.Wrap(err, "could not execute inserting state of datetime")
 	}
 
-	row.Scan(&updateDatetime.Id)
-
 	return updateDatetime, nil
 }
 
+// GetLatest returns the update with the latest effective date. Imported
+// history gets later ids than the updates it precedes, so ids alone do
+// not tell the latest one.
 func (r *UpdateDatetimeRepository) GetLatest() (models.UpdateDatetime, error) {
-	query := `SELECT id, update_datetime
+	query := `SELECT id, update_datetime, effective_date::TEXT, COALESCE(feed_name, '')
 FROM public.update_datetimes
-WHERE id = (
-	SELECT MAX(ID)
-	FROM public.update_datetimes
-);
+ORDER BY effective_date DESC, id DESC
+LIMIT 1;
 	`
 
 	var updateDatetime models.UpdateDatetime
@@ -63,7 +65,92 @@ WHERE id = (
 	defer func() { _ = rows.Close() }()
 
 	for rows.Next() {
-		err = rows.Scan(&updateDatetime.Id, &updateDatetime.UpdateDatetime)
+		err = rows.Scan(
+			&updateDatetime.Id,
+			&updateDatetime.UpdateDatetime,
+			&updateDatetime.EffectiveDate,
+			&updateDatetime.FeedName,
+		)
+		if err != nil {
+			return updateDatetime, errlib.Wrap(err, "could not scan from a row")
+		}
+	}
+
+	return updateDatetime, nil
+}
+
+// GetPrevious returns the latest update with an earlier effective date
+// than the given one has. Zero value is returned, if there is no such.
+func (r *UpdateDatetimeRepository) GetPrevious(updateDatetimeId int) (models.UpdateDatetime, error) {
+	query := `SELECT id, update_datetime, effective_date::TEXT, COALESCE(feed_name, '')
+FROM public.update_datetimes
+WHERE effective_date < (
+	SELECT effective_date
+	FROM public.update_datetimes
+	WHERE id = $1
+)
+ORDER BY effective_date DESC, id DESC
+LIMIT 1;
+	`
+
+	var updateDatetime models.UpdateDatetime
+
+	stmt, err := r.database.Prepare(query)
+	if err != nil {
+		return updateDatetime, errlib.Wrap(err, "could not prepare statement for getting previous datetime")
+	}
+
+	rows, err := stmt.Query(updateDatetimeId)
+	if err != nil {
+		return updateDatetime, errlib.Wrap(err, "could not perform select of previous update datetime")
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		err = rows.Scan(
+			&updateDatetime.Id,
+			&updateDatetime.UpdateDatetime,
+			&updateDatetime.EffectiveDate,
+			&updateDatetime.FeedName,
+		)
+		if err != nil {
+			return updateDatetime, errlib.Wrap(err, "could not scan from a row")
+		}
+	}
+
+	return updateDatetime, nil
+}
+
+// GetByEffectiveDate returns the latest update, which rates were in force
+// on the given date. Zero value is returned, if there is no such.
+func (r *UpdateDatetimeRepository) GetByEffectiveDate(date string) (models.UpdateDatetime, error) {
+	query := `SELECT id, update_datetime, effective_date::TEXT, COALESCE(feed_name, '')
+FROM public.update_datetimes
+WHERE effective_date <= $1
+ORDER BY effective_date DESC, id DESC
+LIMIT 1;
+	`
+
+	var updateDatetime models.UpdateDatetime
+
+	stmt, err := r.database.Prepare(query)
+	if err != nil {
+		return updateDatetime, errlib.Wrap(err, "could not prepare statement for getting datetime by date")
+	}
+
+	rows, err := stmt.Query(date)
+	if err != nil {
+		return updateDatetime, errlib.Wrap(err, "could not perform select of update datetime by date")
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		err = rows.Scan(
+			&updateDatetime.Id,
+			&updateDatetime.UpdateDatetime,
+			&updateDatetime.EffectiveDate,
+			&updateDatetime.FeedName,
+		)
 		if err != nil {
 			return updateDatetime, errlib.Wrap(err, "could not scan from a row")
 		}
@@ -8,8 +8,10 @@ import (
 )
 
 type UpdateDatetime interface {
-	Create(datetime string) (models.UpdateDatetime, error)
+	Create(datetime string, effectiveDate string, feedName string) (models.UpdateDatetime, error)
 	GetLatest() (models.UpdateDatetime, error)
+	GetPrevious(updateDatetimeId int) (models.UpdateDatetime, error)
+	GetByEffectiveDate(date string) (models.UpdateDatetime, error)
 }
 
 type Currencies interface {
@@ -17,14 +19,63 @@ type Currencies interface {
 	GetLatest(updateDatetimeId int) (models.Currencies, error)
 }
 
+type DailyRates interface {
+	GetCharCode(code string) (string, error)
+	Get(charCode string, from string, to string) ([]models.DailyRate, error)
+	GetLast(charCode string, before string) (models.DailyRate, error)
+}
+
+type AverageRates interface {
+	Refresh(effectiveDate string) error
+	RefreshAll() error
+	Get(charCode string, period string, from string, to string) ([]models.AverageRate, error)
+}
+
+type Export interface {
+	Stream(codes []string, from string, to string, fn func(row models.ExportRow) error) error
+}
+
+type Import interface {
+	GetInfo() ([]models.Currency, error)
+	CreateSnapshot(snapshot models.DatedCurrencies, updateDatetime string) (bool, error)
+	SyncInfo(references []models.CurrencyReference) (int, int, error)
+}
+
+type Payloads interface {
+	Create(payload models.Payload) (models.Payload, error)
+	SetUpdateDatetime(id int, updateDatetimeId int) error
+	GetAll() ([]models.Payload, error)
+	GetFetchedBefore(datetime string) ([]models.Payload, error)
+	Delete(id int) error
+}
+
+type ApiKeys interface {
+	Create(apiKey models.ApiKey) (models.ApiKey, error)
+	GetByHash(keyHash string) (models.ApiKey, error)
+	GetAll() ([]models.ApiKey, error)
+	Revoke(id int) error
+}
+
 type Repository struct {
 	UpdateDatetime UpdateDatetime
 	Currencies     Currencies
+	DailyRates     DailyRates
+	AverageRates   AverageRates
+	Export         Export
+	Import         Import
+	Payloads       Payloads
+	ApiKeys        ApiKeys
 }
 
 func New(cfg *config.Config, db *database.Database) *Repository {
 	return &Repository{
 		UpdateDatetime: postgres.NewUpdateDatetimeRepository(cfg, db),
 		Currencies:     postgres.NewCurrenciesRepository(cfg, db),
+		DailyRates:     postgres.NewDailyRatesRepository(cfg, db),
+		AverageRates:   postgres.NewAverageRatesRepository(cfg, db),
+		Export:         postgres.NewExportRepository(cfg, db),
+		Import:         postgres.NewImportRepository(cfg, db),
+		Payloads:       postgres.NewPayloadsRepository(cfg, db),
+		ApiKeys:        postgres.NewApiKeysRepository(cfg, db),
 	}
 }
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"crypto/tls"
+	"sync"
+
+	"github.com/mrumyantsev/go-errlib"
+)
+
+// A certificate holds the TLS key pair, which may be replaced at runtime
+// without restarting the server.
+type certificate struct {
+	certFile string
+	keyFile  string
+	mu       sync.RWMutex
+	keyPair  *tls.Certificate
+}
+
+func newCertificate(certFile string, keyFile string) *certificate {
+	return &certificate{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+}
+
+func (c *certificate) load() error {
+	keyPair, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return errlib.Wrap(err, "could not load key pair")
+	}
+
+	c.mu.Lock()
+	c.keyPair = &keyPair
+	c.mu.Unlock()
+
+	return nil
+}
+
+func (c *certificate) get(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.keyPair, nil
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	apperrors "github.com/mrumyantsev/currency-converter-app/internal/pkg/app-errors"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	mimeApplicationProblemJson = "application/problem+json"
+	problemTypePrefix          = "urn:currency-converter:problem:"
+
+	codeInternalError = "internal_error"
+)
+
+// codesByStatus gives stable codes to errors raised by echo and by the
+// middlewares, which are not domain errors.
+var codesByStatus = map[int]string{
+	http.StatusBadRequest:            "invalid_request",
+	http.StatusUnauthorized:          "unauthorized",
+	http.StatusForbidden:             "forbidden",
+	http.StatusNotFound:              "not_found",
+	http.StatusMethodNotAllowed:      "method_not_allowed",
+	http.StatusNotAcceptable:         "not_acceptable",
+	http.StatusRequestEntityTooLarge: "request_too_large",
+	http.StatusUnsupportedMediaType:  "unsupported_media_type",
+	http.StatusTooManyRequests:       "rate_limit_exceeded",
+	http.StatusServiceUnavailable:    "service_unavailable",
+}
+
+// A Problem is an RFC 7807 problem details object with the stable error
+// code as an extension member.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance"`
+	Code     string `json:"code"`
+}
+
+// errorHandler is the echo HTTP error handler, which renders every error
+// as a problem+json response.
+func errorHandler(err error, ctx echo.Context) {
+	if ctx.Response().Committed {
+		return
+	}
+
+	problem := problemOf(err)
+
+	problem.Instance = ctx.Request().URL.Path
+
+	if problem.Status >= http.StatusInternalServerError {
+		log.Error().Err(err).Str("path", problem.Instance).Msg("request failed")
+	}
+
+	var sendErr error
+
+	if ctx.Request().Method == http.MethodHead {
+		sendErr = ctx.NoContent(problem.Status)
+	} else {
+		ctx.Response().Header().Set(echo.HeaderContentType, mimeApplicationProblemJson)
+
+		sendErr = ctx.JSON(problem.Status, problem)
+	}
+
+	if sendErr != nil {
+		log.Error().Err(sendErr).Msg("could not send error response")
+	}
+}
+
+func problemOf(err error) Problem {
+	var (
+		appErr  *apperrors.Error
+		httpErr *echo.HTTPError
+	)
+
+	if errors.As(err, &appErr) {
+		return Problem{
+			Type:   problemTypePrefix + appErr.Code,
+			Title:  appErr.Title,
+			Status: appErr.Status,
+			Detail: appErr.Detail,
+			Code:   appErr.Code,
+		}
+	}
+
+	if errors.As(err, &httpErr) {
+		code, ok := codesByStatus[httpErr.Code]
+		if !ok {
+			code = codeInternalError
+		}
+
+		problem := Problem{
+			Type:   problemTypePrefix + code,
+			Title:  http.StatusText(httpErr.Code),
+			Status: httpErr.Code,
+			Code:   code,
+		}
+
+		if msg, ok := httpErr.Message.(string); ok && (msg != problem.Title) {
+			problem.Detail = msg
+		}
+
+		return problem
+	}
+
+	return Problem{
+		Type:   problemTypePrefix + codeInternalError,
+		Title:  http.StatusText(http.StatusInternalServerError),
+		Status: http.StatusInternalServerError,
+		Code:   codeInternalError,
+	}
+}
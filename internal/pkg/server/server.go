@@ -2,47 +2,137 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"net/http"
 
 	"github.com/labstack/echo/v4"
 	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
 	"github.com/mrumyantsev/currency-converter-app/internal/pkg/endpoint"
 	"github.com/mrumyantsev/go-errlib"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 type Server struct {
-	config *config.Config
-	echo   *echo.Echo
+	config      *config.Config
+	echo        *echo.Echo
+	httpServer  *http.Server
+	certificate *certificate
 }
 
 func New(cfg *config.Config, ep *endpoint.Endpoint, mw ...echo.MiddlewareFunc) *Server {
 	echo := echo.New()
 
 	echo.HideBanner = true
+	echo.HTTPErrorHandler = errorHandler
 
 	ep.InitRoutes(echo)
 
 	echo.Use(mw...)
 
-	return &Server{
-		config: cfg,
-		echo:   echo,
+	httpServer := &http.Server{
+		Addr:              cfg.HttpServerListenIp + ":" + cfg.HttpServerListenPort,
+		Handler:           echo,
+		ReadTimeout:       cfg.HttpServerReadTimeout,
+		ReadHeaderTimeout: cfg.HttpServerReadHeaderTimeout,
+		WriteTimeout:      cfg.HttpServerWriteTimeout,
+		IdleTimeout:       cfg.HttpServerIdleTimeout,
+		MaxHeaderBytes:    cfg.HttpServerMaxHeaderBytes,
 	}
+
+	s := &Server{
+		config:     cfg,
+		echo:       echo,
+		httpServer: httpServer,
+	}
+
+	// the certificate is created here, not in Start, which runs in its own
+	// goroutine, as reloads read it concurrently
+	if s.isTls() {
+		s.certificate = newCertificate(cfg.TlsCertFile, cfg.TlsKeyFile)
+
+		httpServer.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: s.certificate.get,
+			NextProtos:     []string{http2.NextProtoTLS, "http/1.1"},
+		}
+	}
+
+	return s
 }
 
+// LoadCertificate reads the TLS certificate and key files, if they are
+// configured. It must be called before Start.
+func (s *Server) LoadCertificate() error {
+	if s.certificate == nil {
+		return nil
+	}
+
+	if err := s.certificate.load(); err != nil {
+		return errlib.Wrap(err, "could not load tls certificate")
+	}
+
+	return nil
+}
+
+// Start starts the HTTP server. When a TLS certificate is configured, the
+// server terminates TLS itself and negotiates HTTP/2 over ALPN, otherwise
+// it serves plain HTTP/1.1 and, if enabled, HTTP/2 cleartext (h2c).
 func (s *Server) Start() error {
-	listenAddr := s.config.HttpServerListenIp + ":" + s.config.HttpServerListenPort
+	var err error
+
+	if s.certificate != nil {
+		err = s.httpServer.ListenAndServeTLS("", "")
+	} else {
+		if s.config.IsEnableH2c {
+			s.httpServer.Handler = h2c.NewHandler(s.echo, &http2.Server{
+				IdleTimeout: s.config.HttpServerIdleTimeout,
+			})
+		}
 
-	if err := s.echo.Start(listenAddr); err != nil {
+		err = s.httpServer.ListenAndServe()
+	}
+
+	if err != nil {
 		return errlib.Wrap(err, "could not start http server")
 	}
 
 	return nil
 }
 
+// Routes returns the routes registered in the server.
+func (s *Server) Routes() []*echo.Route {
+	return s.echo.Routes()
+}
+
+// ServeHTTP handles the request with the routes and the middleware of the
+// server, so it can be served without listening.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.echo.ServeHTTP(w, r)
+}
+
+// ReloadCertificate re-reads the TLS certificate and key files. New
+// connections use the new certificate, established ones are not affected.
+func (s *Server) ReloadCertificate() error {
+	if s.certificate == nil {
+		return nil
+	}
+
+	if err := s.certificate.load(); err != nil {
+		return errlib.Wrap(err, "could not reload tls certificate")
+	}
+
+	return nil
+}
+
 func (s *Server) Shutdown(ctx context.Context) error {
-	if err := s.echo.Shutdown(ctx); err != nil {
+	if err := s.httpServer.Shutdown(ctx); err != nil {
 		return errlib.Wrap(err, "could not shutdown http server")
 	}
 
 	return nil
 }
+
+func (s *Server) isTls() bool {
+	return (s.config.TlsCertFile != "") && (s.config.TlsKeyFile != "")
+}
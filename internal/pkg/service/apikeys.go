@@ -0,0 +1,157 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/clock"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/repository"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/repository/postgres"
+	"github.com/mrumyantsev/go-errlib"
+)
+
+const (
+	apiKeyPrefix      = "cc_"
+	apiKeyRandomBytes = 24
+)
+
+var ErrInvalidApiKey = errors.New("invalid api key")
+
+type cachedApiKey struct {
+	apiKey    models.ApiKey
+	expiresAt time.Time
+}
+
+type ApiKeysService struct {
+	config     *config.Config
+	repository repository.ApiKeys
+	clock      clock.Clock
+	mu         sync.Mutex
+	cache      map[string]cachedApiKey
+}
+
+func NewApiKeysService(cfg *config.Config, repo repository.ApiKeys, clk clock.Clock) *ApiKeysService {
+	return &ApiKeysService{
+		config:     cfg,
+		repository: repo,
+		clock:      clk,
+		cache:      make(map[string]cachedApiKey),
+	}
+}
+
+// Create generates a new API key and stores its hash. The plain key is
+// returned only once and can not be restored afterwards.
+func (s *ApiKeysService) Create(name string, rateLimit int, rateBurst int) (string, models.ApiKey, error) {
+	random := make([]byte, apiKeyRandomBytes)
+
+	if _, err := rand.Read(random); err != nil {
+		return "", models.ApiKey{}, errlib.Wrap(err, "could not generate api key")
+	}
+
+	key := apiKeyPrefix + hex.EncodeToString(random)
+
+	apiKey, err := s.repository.Create(models.ApiKey{
+		Name:      name,
+		KeyHash:   hashApiKey(key),
+		RateLimit: rateLimit,
+		RateBurst: rateBurst,
+	})
+	if err != nil {
+		return "", apiKey, err
+	}
+
+	return key, apiKey, nil
+}
+
+// Authenticate returns the active API key matching the given plain key.
+// Found keys are cached for the configured time to spare the database.
+// Unknown keys are not cached, so random keys do not fill the cache.
+func (s *ApiKeysService) Authenticate(key string) (models.ApiKey, error) {
+	if apiKey, ok := s.GetCached(key); ok {
+		return apiKey, nil
+	}
+
+	keyHash := hashApiKey(key)
+	now := s.clock.Now()
+
+	apiKey, err := s.repository.GetByHash(keyHash)
+	if errors.Is(err, postgres.ErrApiKeyNotFound) || ((err == nil) && (apiKey.RevokedAt != "")) {
+		return apiKey, ErrInvalidApiKey
+	}
+	if err != nil {
+		return apiKey, errlib.Wrap(err, "could not get api key")
+	}
+
+	s.mu.Lock()
+	s.evict(now)
+	s.cache[keyHash] = cachedApiKey{
+		apiKey:    apiKey,
+		expiresAt: now.Add(s.config.ApiKeysCacheTtl),
+	}
+	s.mu.Unlock()
+
+	return apiKey, nil
+}
+
+// GetCached returns the API key matching the given plain key, if it is
+// cached and has not expired, without looking it up in the database.
+func (s *ApiKeysService) GetCached(key string) (models.ApiKey, bool) {
+	keyHash := hashApiKey(key)
+	now := s.clock.Now()
+
+	s.mu.Lock()
+	cached, ok := s.cache[keyHash]
+	s.mu.Unlock()
+
+	if !ok || !now.Before(cached.expiresAt) {
+		return models.ApiKey{}, false
+	}
+
+	return cached.apiKey, true
+}
+
+func (s *ApiKeysService) GetAll() ([]models.ApiKey, error) {
+	return s.repository.GetAll()
+}
+
+func (s *ApiKeysService) Revoke(id int) error {
+	if err := s.repository.Revoke(id); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.cache = make(map[string]cachedApiKey)
+	s.mu.Unlock()
+
+	return nil
+}
+
+// evict drops expired entries, and all of them, if the cache is still
+// full, so it can not grow unbounded.
+func (s *ApiKeysService) evict(now time.Time) {
+	if len(s.cache) < s.config.ApiKeysCacheSize {
+		return
+	}
+
+	for keyHash, cached := range s.cache {
+		if !now.Before(cached.expiresAt) {
+			delete(s.cache, keyHash)
+		}
+	}
+
+	if len(s.cache) >= s.config.ApiKeysCacheSize {
+		s.cache = make(map[string]cachedApiKey)
+	}
+}
+
+func hashApiKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+
+	return hex.EncodeToString(sum[:])
+}
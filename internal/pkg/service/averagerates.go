@@ -0,0 +1,97 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	apperrors "github.com/mrumyantsev/currency-converter-app/internal/pkg/app-errors"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/repository"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/repository/postgres"
+	"github.com/mrumyantsev/go-errlib"
+)
+
+type AverageRatesService struct {
+	config               *config.Config
+	repository           repository.AverageRates
+	dailyRatesRepository repository.DailyRates
+}
+
+func NewAverageRatesService(
+	cfg *config.Config,
+	repo repository.AverageRates,
+	dailyRatesRepo repository.DailyRates,
+) *AverageRatesService {
+	return &AverageRatesService{
+		config:               cfg,
+		repository:           repo,
+		dailyRatesRepository: dailyRatesRepo,
+	}
+}
+
+// Refresh recomputes averages of the periods, which include the date.
+// It must be called after every write of a snapshot.
+func (s *AverageRatesService) Refresh(effectiveDate string) error {
+	return s.repository.Refresh(effectiveDate)
+}
+
+// RefreshAll recomputes averages over the whole history, e.g. after an
+// import or a failed refresh.
+func (s *AverageRatesService) RefreshAll() error {
+	return s.repository.RefreshAll()
+}
+
+// Get returns the alphabetic code of the currency, which is looked up by
+// its alphabetic or numeric code, and its averages over the periods,
+// which overlap the first and the last dates.
+func (s *AverageRatesService) Get(
+	code string,
+	period string,
+	from string,
+	to string,
+) (string, []models.AverageRate, error) {
+	charCode, err := s.dailyRatesRepository.GetCharCode(code)
+	if errors.Is(err, postgres.ErrCurrencyNotFound) {
+		return "", nil, apperrors.UnknownCurrency(code)
+	}
+	if err != nil {
+		return "", nil, errlib.Wrap(err, "could not get currency code")
+	}
+
+	averageRates, err := s.repository.Get(charCode, period, from, to)
+	if err != nil {
+		return charCode, nil, errlib.Wrap(err, "could not get average rates")
+	}
+
+	for i := range averageRates {
+		if averageRates[i].PeriodEnd, err = periodEnd(averageRates[i].PeriodStart, period); err != nil {
+			return charCode, nil, err
+		}
+	}
+
+	return charCode, averageRates, nil
+}
+
+// periodEnd returns the last date of the period starting on the date.
+func periodEnd(periodStart string, period string) (string, error) {
+	start, err := time.Parse(time.DateOnly, periodStart)
+	if err != nil {
+		return "", errlib.Wrap(err, "could not parse period start")
+	}
+
+	var end time.Time
+
+	switch period {
+	case models.PeriodDay:
+		end = start
+	case models.PeriodMonth:
+		end = start.AddDate(0, 1, -1)
+	case models.PeriodQuarter:
+		end = start.AddDate(0, 3, -1)
+	default:
+		return "", errors.New("unknown period: " + period)
+	}
+
+	return end.Format(time.DateOnly), nil
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	apperrors "github.com/mrumyantsev/currency-converter-app/internal/pkg/app-errors"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/clock"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/repository"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/repository/postgres"
+	"github.com/mrumyantsev/go-errlib"
+)
+
+type DailyRatesService struct {
+	config     *config.Config
+	repository repository.DailyRates
+	clock      clock.Clock
+}
+
+func NewDailyRatesService(cfg *config.Config, repo repository.DailyRates, clk clock.Clock) *DailyRatesService {
+	return &DailyRatesService{
+		config:     cfg,
+		repository: repo,
+		clock:      clk,
+	}
+}
+
+// Get returns the alphabetic code of the currency, which is looked up by
+// its alphabetic or numeric code, and its daily rates from the first to
+// the last date inclusive.
+func (s *DailyRatesService) Get(code string, from string, to string) (string, []models.DailyRate, error) {
+	charCode, err := s.charCode(code)
+	if err != nil {
+		return "", nil, err
+	}
+
+	dailyRates, err := s.repository.Get(charCode, from, to)
+	if err != nil {
+		return charCode, nil, errlib.Wrap(err, "could not get daily rates")
+	}
+
+	return charCode, dailyRates, nil
+}
+
+// GetCarriedForward is like Get, but returns a rate for every calendar
+// date: the last published rate is carried forward over weekends and
+// holidays. Dates before the first published rate and after today, or
+// after the last published rate, if it is later, are not filled.
+func (s *DailyRatesService) GetCarriedForward(code string, from string, to string) (string, []models.DailyRate, error) {
+	charCode, published, err := s.Get(code, from, to)
+	if err != nil {
+		return charCode, nil, err
+	}
+
+	last, err := s.repository.GetLast(charCode, from)
+	if err != nil {
+		return charCode, nil, errlib.Wrap(err, "could not get last daily rate")
+	}
+
+	start, err := time.Parse(time.DateOnly, from)
+	if err != nil {
+		return charCode, nil, errlib.Wrap(err, "could not parse first date")
+	}
+
+	end := s.clock.Now().In(s.config.UpdateLocation).Format(time.DateOnly)
+
+	if (len(published) > 0) && (published[len(published)-1].EffectiveDate > end) {
+		end = published[len(published)-1].EffectiveDate
+	}
+
+	if to < end {
+		end = to
+	}
+
+	dailyRates := []models.DailyRate{}
+
+	for day := start; ; day = day.AddDate(0, 0, 1) {
+		date := day.Format(time.DateOnly)
+		if date > end {
+			break
+		}
+
+		if (len(published) > 0) && (published[0].EffectiveDate == date) {
+			last, published = published[0], published[1:]
+
+			dailyRates = append(dailyRates, last)
+
+			continue
+		}
+
+		if last.EffectiveDate == "" {
+			continue
+		}
+
+		dailyRates = append(dailyRates, models.DailyRate{
+			EffectiveDate:    date,
+			UnitValue:        last.UnitValue,
+			IsCarriedForward: true,
+		})
+	}
+
+	return charCode, dailyRates, nil
+}
+
+func (s *DailyRatesService) charCode(code string) (string, error) {
+	charCode, err := s.repository.GetCharCode(code)
+	if errors.Is(err, postgres.ErrCurrencyNotFound) {
+		return "", apperrors.UnknownCurrency(code)
+	}
+	if err != nil {
+		return "", errlib.Wrap(err, "could not get currency code")
+	}
+
+	return charCode, nil
+}
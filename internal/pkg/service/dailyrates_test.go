@@ -0,0 +1,147 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	apperrors "github.com/mrumyantsev/currency-converter-app/internal/pkg/app-errors"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/clock"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/repository/postgres"
+)
+
+// fakeDailyRates keeps published rates of a single currency, ordered by
+// date.
+type fakeDailyRates struct {
+	published []models.DailyRate
+}
+
+func (f fakeDailyRates) GetCharCode(code string) (string, error) {
+	if (code != "USD") && (code != "840") {
+		return "", postgres.ErrCurrencyNotFound
+	}
+
+	return "USD", nil
+}
+
+func (f fakeDailyRates) Get(_ string, from string, to string) ([]models.DailyRate, error) {
+	dailyRates := []models.DailyRate{}
+
+	for _, dailyRate := range f.published {
+		if (dailyRate.EffectiveDate >= from) && (dailyRate.EffectiveDate <= to) {
+			dailyRates = append(dailyRates, dailyRate)
+		}
+	}
+
+	return dailyRates, nil
+}
+
+func (f fakeDailyRates) GetLast(_ string, before string) (models.DailyRate, error) {
+	var last models.DailyRate
+
+	for _, dailyRate := range f.published {
+		if dailyRate.EffectiveDate < before {
+			last = dailyRate
+		}
+	}
+
+	return last, nil
+}
+
+func rate(date string, value string) models.DailyRate {
+	return models.DailyRate{EffectiveDate: date, UnitValue: value}
+}
+
+func carried(date string, value string) models.DailyRate {
+	return models.DailyRate{EffectiveDate: date, UnitValue: value, IsCarriedForward: true}
+}
+
+func TestGetCarriedForward(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Friday's feed is effective on Saturday, the next one on Tuesday after
+	// a holiday Monday
+	published := []models.DailyRate{
+		rate("2024-01-05", "88"),
+		rate("2024-01-10", "90"),
+		rate("2024-01-11", "91.5"),
+		rate("2024-01-13", "89.75"),
+		rate("2024-01-16", "89.5"),
+	}
+
+	// the clock is in UTC, and it is already the next day in Moscow
+	today := time.Date(2024, time.January, 14, 22, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		published []models.DailyRate
+		from      string
+		to        string
+		want      []models.DailyRate
+	}{
+		{"gaps between rates", published, "2024-01-10", "2024-01-14", []models.DailyRate{
+			rate("2024-01-10", "90"),
+			rate("2024-01-11", "91.5"),
+			carried("2024-01-12", "91.5"),
+			rate("2024-01-13", "89.75"),
+			carried("2024-01-14", "89.75"),
+		}},
+		{"rate before period", published, "2024-01-08", "2024-01-10", []models.DailyRate{
+			carried("2024-01-08", "88"),
+			carried("2024-01-09", "88"),
+			rate("2024-01-10", "90"),
+		}},
+		{"no rate before period", published[1:], "2024-01-08", "2024-01-10", []models.DailyRate{
+			rate("2024-01-10", "90"),
+		}},
+		{"period without rates", published, "2024-01-06", "2024-01-07", []models.DailyRate{
+			carried("2024-01-06", "88"),
+			carried("2024-01-07", "88"),
+		}},
+		{"single date", published, "2024-01-12", "2024-01-12", []models.DailyRate{
+			carried("2024-01-12", "91.5"),
+		}},
+		{"future dates are not filled", published[:4], "2024-01-13", "2024-01-20", []models.DailyRate{
+			rate("2024-01-13", "89.75"),
+			carried("2024-01-14", "89.75"),
+			carried("2024-01-15", "89.75"),
+		}},
+		{"rate published ahead", published, "2024-01-15", "2024-01-20", []models.DailyRate{
+			carried("2024-01-15", "89.75"),
+			rate("2024-01-16", "89.5"),
+		}},
+		{"nothing published", nil, "2024-01-10", "2024-01-12", []models.DailyRate{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewDailyRatesService(&config.Config{UpdateLocation: moscow},
+				fakeDailyRates{published: tt.published}, clock.NewSimulated(today))
+
+			code, got, err := s.GetCarriedForward("840", tt.from, tt.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if code != "USD" {
+				t.Fatalf("code = %s, want USD", code)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("daily rates =\n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+
+	s := NewDailyRatesService(&config.Config{UpdateLocation: moscow}, fakeDailyRates{}, clock.NewSimulated(today))
+
+	if _, _, err = s.GetCarriedForward("XYZ", "2024-01-10", "2024-01-12"); !errors.Is(err, apperrors.ErrUnknownCurrency) {
+		t.Fatalf("error of unknown currency = %v, want unknown currency", err)
+	}
+}
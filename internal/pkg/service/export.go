@@ -0,0 +1,28 @@
+package service
+
+import (
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/repository"
+)
+
+type ExportService struct {
+	config     *config.Config
+	repository repository.Export
+}
+
+func NewExportService(cfg *config.Config, repo repository.Export) *ExportService {
+	return &ExportService{
+		config:     cfg,
+		repository: repo,
+	}
+}
+
+func (s *ExportService) Stream(
+	codes []string,
+	from string,
+	to string,
+	fn func(row models.ExportRow) error,
+) error {
+	return s.repository.Stream(codes, from, to, fn)
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	apperrors "github.com/mrumyantsev/currency-converter-app/internal/pkg/app-errors"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/clock"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/repository"
+	"github.com/mrumyantsev/go-errlib"
+)
+
+type cachedHistory struct {
+	currencies models.DatedCurrencies
+	err        error
+	expiresAt  time.Time
+}
+
+// A HistoryService looks up stored snapshots by date. Lookups are cached
+// for the configured time, as historical data changes only on imports.
+type HistoryService struct {
+	config                   *config.Config
+	updateDatetimeRepository repository.UpdateDatetime
+	currenciesRepository     repository.Currencies
+	clock                    clock.Clock
+	mu                       sync.Mutex
+	cache                    map[string]cachedHistory
+}
+
+func NewHistoryService(
+	cfg *config.Config,
+	updateDatetimeRepo repository.UpdateDatetime,
+	currenciesRepo repository.Currencies,
+	clk clock.Clock,
+) *HistoryService {
+	return &HistoryService{
+		config:                   cfg,
+		updateDatetimeRepository: updateDatetimeRepo,
+		currenciesRepository:     currenciesRepo,
+		clock:                    clk,
+		cache:                    make(map[string]cachedHistory),
+	}
+}
+
+// GetByDate returns the snapshot, which rates were in force on the date
+// in the ISO format.
+func (s *HistoryService) GetByDate(date string) (models.DatedCurrencies, error) {
+	now := s.clock.Now()
+
+	s.mu.Lock()
+	cached, ok := s.cache[date]
+	s.mu.Unlock()
+
+	if ok && now.Before(cached.expiresAt) {
+		return cached.currencies, cached.err
+	}
+
+	currencies, err := s.getByDate(date)
+	if (err != nil) && !errors.Is(err, apperrors.ErrNoDataForDate) {
+		return currencies, err
+	}
+
+	s.mu.Lock()
+	s.evict(now)
+	s.cache[date] = cachedHistory{
+		currencies: currencies,
+		err:        err,
+		expiresAt:  now.Add(s.config.HistoryCacheTtl),
+	}
+	s.mu.Unlock()
+
+	return currencies, err
+}
+
+func (s *HistoryService) getByDate(date string) (models.DatedCurrencies, error) {
+	var currencies models.DatedCurrencies
+
+	updateDatetime, err := s.updateDatetimeRepository.GetByEffectiveDate(date)
+	if err != nil {
+		return currencies, errlib.Wrap(err, "could not get update datetime by date")
+	}
+
+	if updateDatetime.Id == 0 {
+		return currencies, apperrors.NoDataForDate(date)
+	}
+
+	if currencies.SnapshotVersion, err = SnapshotVersion(updateDatetime); err != nil {
+		return currencies, err
+	}
+
+	currencies.EffectiveDate = updateDatetime.EffectiveDate
+
+	if currencies.Currencies, err = s.currenciesRepository.GetLatest(updateDatetime.Id); err != nil {
+		return currencies, errlib.Wrap(err, "could not get currencies by date")
+	}
+
+	return currencies, nil
+}
+
+// evict drops expired entries, and all of them, if the cache is still
+// full, so it can not grow unbounded.
+func (s *HistoryService) evict(now time.Time) {
+	if len(s.cache) < s.config.HistoryCacheSize {
+		return
+	}
+
+	for date, cached := range s.cache {
+		if !now.Before(cached.expiresAt) {
+			delete(s.cache, date)
+		}
+	}
+
+	if len(s.cache) >= s.config.HistoryCacheSize {
+		s.cache = make(map[string]cachedHistory)
+	}
+}
+
+// SnapshotVersion identifies the stored snapshot. The update time is
+// included, so versions do not repeat if the database is recreated.
+func SnapshotVersion(updateDatetime models.UpdateDatetime) (string, error) {
+	datetime, err := time.Parse(time.RFC3339, updateDatetime.UpdateDatetime)
+	if err != nil {
+		return "", errlib.Wrap(err, "could not parse update datetime")
+	}
+
+	return strconv.Itoa(updateDatetime.Id) + "." + strconv.FormatInt(datetime.Unix(), 10), nil
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"time"
+
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/clock"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/repository"
+)
+
+type ImportService struct {
+	config     *config.Config
+	repository repository.Import
+	clock      clock.Clock
+}
+
+func NewImportService(cfg *config.Config, repo repository.Import, clk clock.Clock) *ImportService {
+	return &ImportService{
+		config:     cfg,
+		repository: repo,
+		clock:      clk,
+	}
+}
+
+func (s *ImportService) GetInfo() ([]models.Currency, error) {
+	return s.repository.GetInfo()
+}
+
+// CreateSnapshot stores the imported snapshot, unless its effective date
+// already has one. The time of import is recorded as its update time.
+func (s *ImportService) CreateSnapshot(snapshot models.DatedCurrencies) (bool, error) {
+	return s.repository.CreateSnapshot(snapshot, s.clock.Now().Format(time.RFC3339))
+}
+
+// SyncInfo updates the known currencies from the reference list of the
+// source. Entries without ISO 4217 codes can not be stored, and entries,
+// which repeat a numeric code of an earlier one, are left out too.
+func (s *ImportService) SyncInfo(references []models.CurrencyReference) (int, int, error) {
+	var (
+		stored = make([]models.CurrencyReference, 0, len(references))
+		seen   = make(map[int]bool, len(references))
+	)
+
+	for _, reference := range references {
+		if (reference.NumCode == 0) || (reference.CharCode == "") || seen[reference.NumCode] {
+			continue
+		}
+
+		seen[reference.NumCode] = true
+
+		stored = append(stored, reference)
+	}
+
+	return s.repository.SyncInfo(stored)
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"time"
+
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/archive"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/clock"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/repository"
+	"github.com/mrumyantsev/go-errlib"
+)
+
+type PayloadsService struct {
+	config     *config.Config
+	repository repository.Payloads
+	archive    *archive.Archive
+	clock      clock.Clock
+}
+
+func NewPayloadsService(cfg *config.Config, repo repository.Payloads, arc *archive.Archive, clk clock.Clock) *PayloadsService {
+	return &PayloadsService{
+		config:     cfg,
+		repository: repo,
+		archive:    arc,
+		clock:      clk,
+	}
+}
+
+// Create archives the data, fetched from the URL, and records it with its
+// hash and headers.
+func (s *PayloadsService) Create(rawUrl string, header map[string][]string, data []byte, fetchedAt time.Time) (models.Payload, error) {
+	payload, err := s.archive.Write(rawUrl, header, data, fetchedAt)
+	if err != nil {
+		return payload, err
+	}
+
+	payload, err = s.repository.Create(payload)
+	if err != nil {
+		// a file without a record is never pruned, so it is not kept
+		_ = s.archive.Remove(payload)
+
+		return payload, err
+	}
+
+	return payload, nil
+}
+
+func (s *PayloadsService) SetUpdateDatetime(id int, updateDatetimeId int) error {
+	return s.repository.SetUpdateDatetime(id, updateDatetimeId)
+}
+
+func (s *PayloadsService) GetAll() ([]models.Payload, error) {
+	return s.repository.GetAll()
+}
+
+// Prune removes the payloads, which were fetched longer than the retention
+// period ago, and returns their number. Nothing is removed, if retention
+// is not positive.
+func (s *PayloadsService) Prune(retention time.Duration) (int, error) {
+	if retention <= 0 {
+		return 0, nil
+	}
+
+	payloads, err := s.repository.GetFetchedBefore(s.clock.Now().Add(-retention).Format(time.RFC3339))
+	if err != nil {
+		return 0, err
+	}
+
+	for i, payload := range payloads {
+		if err = s.archive.Remove(payload); err != nil {
+			return i, err
+		}
+
+		if err = s.repository.Delete(payload.Id); err != nil {
+			return i, errlib.Wrap(err, "could not delete record of removed payload "+payload.Key)
+		}
+	}
+
+	return len(payloads), nil
+}
+
+// Verify reports, whether the archived data of the payload still has the
+// hash it was recorded with.
+func (s *PayloadsService) Verify(payload models.Payload) (bool, error) {
+	return s.archive.Verify(payload)
+}
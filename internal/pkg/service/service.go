@@ -1,14 +1,20 @@
 package service
 
 import (
+	"time"
+
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/archive"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/clock"
 	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
 	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
 	"github.com/mrumyantsev/currency-converter-app/internal/pkg/repository"
 )
 
 type UpdateDatetime interface {
-	Create(datetime string) (models.UpdateDatetime, error)
+	Create(datetime string, effectiveDate string, feedName string) (models.UpdateDatetime, error)
 	GetLatest() (models.UpdateDatetime, error)
+	GetPrevious(updateDatetimeId int) (models.UpdateDatetime, error)
+	GetByEffectiveDate(date string) (models.UpdateDatetime, error)
 }
 
 type Currencies interface {
@@ -16,14 +22,69 @@ type Currencies interface {
 	GetLatest(updateDatetimeId int) (models.Currencies, error)
 }
 
+type History interface {
+	GetByDate(date string) (models.DatedCurrencies, error)
+}
+
+type DailyRates interface {
+	Get(code string, from string, to string) (string, []models.DailyRate, error)
+	GetCarriedForward(code string, from string, to string) (string, []models.DailyRate, error)
+}
+
+type AverageRates interface {
+	Refresh(effectiveDate string) error
+	RefreshAll() error
+	Get(code string, period string, from string, to string) (string, []models.AverageRate, error)
+}
+
+type Export interface {
+	Stream(codes []string, from string, to string, fn func(row models.ExportRow) error) error
+}
+
+type Import interface {
+	GetInfo() ([]models.Currency, error)
+	CreateSnapshot(snapshot models.DatedCurrencies) (bool, error)
+	SyncInfo(references []models.CurrencyReference) (int, int, error)
+}
+
+type Payloads interface {
+	Create(rawUrl string, header map[string][]string, data []byte, fetchedAt time.Time) (models.Payload, error)
+	SetUpdateDatetime(id int, updateDatetimeId int) error
+	GetAll() ([]models.Payload, error)
+	Prune(retention time.Duration) (int, error)
+	Verify(payload models.Payload) (bool, error)
+}
+
+type ApiKeys interface {
+	Create(name string, rateLimit int, rateBurst int) (string, models.ApiKey, error)
+	Authenticate(key string) (models.ApiKey, error)
+	GetCached(key string) (models.ApiKey, bool)
+	GetAll() ([]models.ApiKey, error)
+	Revoke(id int) error
+}
+
 type Service struct {
 	UpdateDatetime UpdateDatetime
 	Currencies     Currencies
+	History        History
+	DailyRates     DailyRates
+	AverageRates   AverageRates
+	Export         Export
+	Import         Import
+	Payloads       Payloads
+	ApiKeys        ApiKeys
 }
 
-func New(cfg *config.Config, repo *repository.Repository) *Service {
+func New(cfg *config.Config, repo *repository.Repository, arc *archive.Archive, clk clock.Clock) *Service {
 	return &Service{
 		UpdateDatetime: NewUpdateDatetimeService(cfg, repo.UpdateDatetime),
 		Currencies:     NewCurrenciesService(cfg, repo.Currencies),
+		History:        NewHistoryService(cfg, repo.UpdateDatetime, repo.Currencies, clk),
+		DailyRates:     NewDailyRatesService(cfg, repo.DailyRates, clk),
+		AverageRates:   NewAverageRatesService(cfg, repo.AverageRates, repo.DailyRates),
+		Export:         NewExportService(cfg, repo.Export),
+		Import:         NewImportService(cfg, repo.Import, clk),
+		Payloads:       NewPayloadsService(cfg, repo.Payloads, arc, clk),
+		ApiKeys:        NewApiKeysService(cfg, repo.ApiKeys, clk),
 	}
 }
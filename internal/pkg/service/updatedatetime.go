@@ -18,10 +18,18 @@ func NewUpdateDatetimeService(cfg *config.Config, repo repository.UpdateDatetime
 	}
 }
 
-func (s *UpdateDatetimeService) Create(datetime string) (models.UpdateDatetime, error) {
-	return s.repository.Create(datetime)
+func (s *UpdateDatetimeService) Create(datetime string, effectiveDate string, feedName string) (models.UpdateDatetime, error) {
+	return s.repository.Create(datetime, effectiveDate, feedName)
 }
 
 func (s *UpdateDatetimeService) GetLatest() (models.UpdateDatetime, error) {
 	return s.repository.GetLatest()
 }
+
+func (s *UpdateDatetimeService) GetPrevious(updateDatetimeId int) (models.UpdateDatetime, error) {
+	return s.repository.GetPrevious(updateDatetimeId)
+}
+
+func (s *UpdateDatetimeService) GetByEffectiveDate(date string) (models.UpdateDatetime, error) {
+	return s.repository.GetByEffectiveDate(date)
+}
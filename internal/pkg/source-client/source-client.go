@@ -0,0 +1,304 @@
+package sourceclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	apperrors "github.com/mrumyantsev/currency-converter-app/internal/pkg/app-errors"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/clock"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+	"github.com/mrumyantsev/go-errlib"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	protocolHttp2 = "HTTP/2"
+
+	headerUserAgent       = "User-Agent"
+	headerAccept          = "Accept"
+	headerContentType     = "Content-Type"
+	headerETag            = "ETag"
+	headerLastModified    = "Last-Modified"
+	headerIfNoneMatch     = "If-None-Match"
+	headerIfModifiedSince = "If-Modified-Since"
+	headerRetryAfter      = "Retry-After"
+
+	acceptXml = "application/xml, text/xml;q=0.9"
+)
+
+// ErrNotModified is returned for conditional requests, when data of the
+// source has not changed since it was received last time.
+var ErrNotModified = errors.New("source data is not modified")
+
+// A Response is a successful response of the source.
+type Response struct {
+	Url       string
+	Header    http.Header
+	Body      []byte
+	FetchedAt time.Time
+}
+
+// validators are the headers of the last response of a URL, which make
+// the next request to it conditional.
+type validators struct {
+	etag         string
+	lastModified string
+}
+
+// A Client requests feeds of the source. Requests go through the proxy
+// of the environment, unless another one is configured, and are retried
+// on network errors and on responses, which tell to try later.
+type Client struct {
+	config *config.Config
+	client *http.Client
+	clock  clock.Clock
+
+	mu         sync.Mutex
+	validators map[string]validators
+}
+
+func New(cfg *config.Config, clk clock.Clock) (*Client, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if cfg.SourceCaFile != "" {
+		pool, err := certPool(cfg.SourceCaFile)
+		if err != nil {
+			return nil, errlib.Wrap(err, "could not load ca bundle of source")
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	proxy := http.ProxyFromEnvironment
+
+	if cfg.SourceProxyUrl != "" {
+		proxyUrl, err := url.Parse(cfg.SourceProxyUrl)
+		if err != nil {
+			return nil, errlib.Wrap(err, "could not parse proxy url of source")
+		}
+
+		proxy = http.ProxyURL(proxyUrl)
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   cfg.SourceConnectTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+
+	transport := &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   cfg.SourceConnectTimeout,
+		ResponseHeaderTimeout: cfg.SourceRequestTimeout,
+		ForceAttemptHTTP2:     cfg.HttpRequestProtocol == protocolHttp2,
+		MaxIdleConns:          4,
+		IdleConnTimeout:       90 * time.Second,
+	}
+
+	return &Client{
+		config: cfg,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   cfg.SourceRequestTimeout,
+		},
+		clock:      clk,
+		validators: make(map[string]validators),
+	}, nil
+}
+
+// Get requests the URL with the query, which may be nil. The request is
+// conditional, if the URL has been received before, and ErrNotModified
+// is returned, if its data has not changed since.
+func (c *Client) Get(rawUrl string, query url.Values) (*Response, error) {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return nil, errlib.Wrap(err, "could not parse url")
+	}
+
+	if query != nil {
+		u.RawQuery = query.Encode()
+	}
+
+	target := u.String()
+
+	for attempt := 0; ; attempt++ {
+		response, retryAfter, err := c.get(target)
+		if (retryAfter < 0) || (attempt >= c.config.SourceRetries) {
+			return response, err
+		}
+
+		delay := c.config.SourceRetryDelay << attempt
+		if retryAfter > delay {
+			delay = retryAfter
+		}
+
+		if delay > c.config.SourceRetryMaxDelay {
+			delay = c.config.SourceRetryMaxDelay
+		}
+
+		log.Warn().Err(err).Int("attempt", attempt+1).
+			Msg("request to source failed, retrying after " + delay.String())
+
+		c.clock.Sleep(delay)
+	}
+}
+
+// get makes a single request. Negative retryAfter tells, that the request
+// must not be retried, otherwise it is the delay the source asks for, if
+// it does.
+func (c *Client) get(target string) (*Response, time.Duration, error) {
+	startTime := c.clock.Now()
+
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return nil, -1, errlib.Wrap(err, "could not create request")
+	}
+
+	req.Header.Set(headerUserAgent, c.config.SourceUserAgent)
+	req.Header.Set(headerAccept, acceptXml)
+
+	c.mu.Lock()
+	previous, isConditional := c.validators[target]
+	c.mu.Unlock()
+
+	if isConditional {
+		if previous.etag != "" {
+			req.Header.Set(headerIfNoneMatch, previous.etag)
+		}
+
+		if previous.lastModified != "" {
+			req.Header.Set(headerIfModifiedSince, previous.lastModified)
+		}
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, 0, apperrors.UpstreamUnavailable("could not send request to source", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		return nil, -1, ErrNotModified
+	case (resp.StatusCode >= http.StatusInternalServerError) || (resp.StatusCode == http.StatusTooManyRequests):
+		_, _ = io.Copy(io.Discard, resp.Body)
+
+		return nil, retryAfter(resp.Header.Get(headerRetryAfter), c.clock.Now()),
+			apperrors.UpstreamUnavailable("source responded with status "+resp.Status, nil)
+	case resp.StatusCode != http.StatusOK:
+		return nil, -1, errors.New("source responded with status " + resp.Status)
+	}
+
+	if err = checkContentType(resp.Header.Get(headerContentType)); err != nil {
+		return nil, -1, err
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, apperrors.UpstreamUnavailable("could not read data from response body", err)
+	}
+
+	if len(data) == 0 {
+		return nil, 0, apperrors.UpstreamUnavailable("source responded with empty body", nil)
+	}
+
+	current := validators{
+		etag:         resp.Header.Get(headerETag),
+		lastModified: resp.Header.Get(headerLastModified),
+	}
+
+	c.mu.Lock()
+	if (current.etag != "") || (current.lastModified != "") {
+		c.validators[target] = current
+	} else {
+		delete(c.validators, target)
+	}
+	c.mu.Unlock()
+
+	log.Debug().Msg("getting http data time overall: " + c.clock.Now().Sub(startTime).String())
+
+	return &Response{
+		Url:       target,
+		Header:    resp.Header,
+		Body:      data,
+		FetchedAt: startTime,
+	}, -1, nil
+}
+
+// checkContentType accepts XML and responses without a type, and rejects
+// pages, which proxies and the source respond with instead of feeds.
+func checkContentType(contentType string) error {
+	if contentType == "" {
+		return nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return errlib.Wrap(err, "could not parse content type of source response")
+	}
+
+	if (mediaType == "application/xml") || (mediaType == "text/xml") || strings.HasSuffix(mediaType, "+xml") {
+		return nil
+	}
+
+	return errors.New("source responded with unexpected content type: " + contentType)
+}
+
+// retryAfter returns the delay of the Retry-After header, either in
+// seconds or as a date, or zero, if there is none.
+func retryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := date.Sub(now); delay > 0 {
+			return delay
+		}
+	}
+
+	return 0
+}
+
+// certPool returns the system certificates with the ones of the bundle,
+// so proxies with TLS inspection are trusted together with the public
+// authorities.
+func certPool(caFile string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, errlib.Wrap(err, "could not read ca bundle")
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("there are no certificates in ca bundle: " + caFile)
+	}
+
+	return pool, nil
+}
@@ -0,0 +1,207 @@
+package sourceclient
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	apperrors "github.com/mrumyantsev/currency-converter-app/internal/pkg/app-errors"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/clock"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+)
+
+const (
+	testUserAgent = "test-agent"
+	testEtag      = `"v1"`
+	testFeed      = `<?xml version="1.0" encoding="UTF-8"?><ValCurs Date="02.03.2024"></ValCurs>`
+)
+
+var start = time.Date(2024, time.March, 2, 12, 0, 0, 0, time.UTC)
+
+// A reply is the response of the fake source to a single request.
+type reply struct {
+	status      int
+	contentType string
+	retryAfter  string
+	body        string
+}
+
+// A fakeSource answers requests with the replies in order, and the last
+// reply to the rest of them. It records the requests it receives.
+type fakeSource struct {
+	replies []reply
+
+	mu       sync.Mutex
+	requests []*http.Request
+}
+
+func (f *fakeSource) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.requests = append(f.requests, r)
+	n := len(f.requests)
+	f.mu.Unlock()
+
+	rep := f.replies[len(f.replies)-1]
+	if n <= len(f.replies) {
+		rep = f.replies[n-1]
+	}
+
+	if rep.contentType != "" {
+		w.Header().Set(headerContentType, rep.contentType)
+	}
+
+	if rep.retryAfter != "" {
+		w.Header().Set(headerRetryAfter, rep.retryAfter)
+	}
+
+	if rep.status == http.StatusOK {
+		w.Header().Set(headerETag, testEtag)
+	}
+
+	w.WriteHeader(rep.status)
+	_, _ = w.Write([]byte(rep.body))
+}
+
+func newTestClient(t *testing.T, replies ...reply) (*Client, *fakeSource, *clock.Simulated, string) {
+	t.Helper()
+
+	source := &fakeSource{replies: replies}
+
+	server := httptest.NewServer(source)
+	t.Cleanup(server.Close)
+
+	clk := clock.NewSimulated(start)
+
+	client, err := New(&config.Config{
+		SourceConnectTimeout: time.Second,
+		SourceRequestTimeout: 5 * time.Second,
+		SourceRetries:        3,
+		SourceRetryDelay:     2 * time.Second,
+		SourceRetryMaxDelay:  time.Minute,
+		SourceUserAgent:      testUserAgent,
+	}, clk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client, source, clk, server.URL + "/scripts/XML_daily.asp"
+}
+
+var (
+	ok          = reply{http.StatusOK, "application/xml; charset=windows-1251", "", testFeed}
+	unavailable = reply{http.StatusServiceUnavailable, "text/html", "", "<html></html>"}
+)
+
+func TestGet(t *testing.T) {
+	client, source, clk, target := newTestClient(t, ok)
+
+	response, err := client.Get(target, url.Values{"date_req": {"02/03/2024"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(response.Body) != testFeed {
+		t.Fatalf("body = %q, want %q", response.Body, testFeed)
+	}
+
+	if response.Url != target+"?date_req=02%2F03%2F2024" {
+		t.Fatalf("url = %s", response.Url)
+	}
+
+	if !response.FetchedAt.Equal(start) {
+		t.Fatalf("fetched at %s, want %s", response.FetchedAt, start)
+	}
+
+	req := source.requests[0]
+
+	if got := req.Header.Get(headerUserAgent); got != testUserAgent {
+		t.Fatalf("user agent = %q, want %q", got, testUserAgent)
+	}
+
+	if got := req.URL.Query().Get("date_req"); got != "02/03/2024" {
+		t.Fatalf("query date = %q, want 02/03/2024", got)
+	}
+
+	if got := req.Header.Get(headerIfNoneMatch); got != "" {
+		t.Fatalf("first request is conditional: %s", got)
+	}
+
+	if !clk.Now().Equal(start) {
+		t.Fatalf("client slept until %s without retries", clk.Now())
+	}
+}
+
+func TestGetNotModified(t *testing.T) {
+	client, source, _, target := newTestClient(t, ok, reply{status: http.StatusNotModified})
+
+	if _, err := client.Get(target, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Get(target, nil); !errors.Is(err, ErrNotModified) {
+		t.Fatalf("error = %v, want ErrNotModified", err)
+	}
+
+	if len(source.requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(source.requests))
+	}
+
+	if got := source.requests[1].Header.Get(headerIfNoneMatch); got != testEtag {
+		t.Fatalf("If-None-Match = %q, want %q", got, testEtag)
+	}
+}
+
+func TestGetRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		replies  []reply
+		requests int
+		slept    time.Duration
+		isFailed bool
+	}{
+		{"success after errors", []reply{unavailable, unavailable, ok}, 3, 2*time.Second + 4*time.Second, false},
+		{"retries exhausted", []reply{unavailable}, 4, 2*time.Second + 4*time.Second + 8*time.Second, true},
+		{"too many requests", []reply{{http.StatusTooManyRequests, "", "", ""}, ok}, 2, 2 * time.Second, false},
+		{"retry after seconds", []reply{{http.StatusServiceUnavailable, "", "30", ""}, ok}, 2, 30 * time.Second, false},
+		{"retry after over max delay", []reply{{http.StatusServiceUnavailable, "", "3600", ""}, ok}, 2, time.Minute, false},
+		{"retry after date", []reply{
+			{http.StatusServiceUnavailable, "", start.Add(45 * time.Second).Format(http.TimeFormat), ""}, ok,
+		}, 2, 45 * time.Second, false},
+		{"retry after shorter than delay", []reply{{http.StatusServiceUnavailable, "", "1", ""}, ok}, 2, 2 * time.Second, false},
+		{"not found is not retried", []reply{{http.StatusNotFound, "", "", ""}}, 1, 0, true},
+		{"html page is not retried", []reply{{http.StatusOK, "text/html", "", "<html></html>"}}, 1, 0, true},
+		{"empty body is retried", []reply{{http.StatusOK, "text/xml", "", ""}, ok}, 2, 2 * time.Second, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, source, clk, target := newTestClient(t, tt.replies...)
+
+			_, err := client.Get(target, nil)
+
+			if isFailed := err != nil; isFailed != tt.isFailed {
+				t.Fatalf("failed = %t, want %t, error: %v", isFailed, tt.isFailed, err)
+			}
+
+			if len(source.requests) != tt.requests {
+				t.Fatalf("got %d requests, want %d", len(source.requests), tt.requests)
+			}
+
+			if slept := clk.Now().Sub(start); slept != tt.slept {
+				t.Fatalf("slept %s, want %s", slept, tt.slept)
+			}
+		})
+	}
+}
+
+func TestGetUnavailable(t *testing.T) {
+	client, _, _, target := newTestClient(t, unavailable)
+
+	if _, err := client.Get(target, nil); !errors.Is(err, apperrors.ErrUpstreamUnavailable) {
+		t.Fatalf("error = %v, want upstream unavailable", err)
+	}
+}
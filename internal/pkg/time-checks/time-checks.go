@@ -3,6 +3,7 @@ package timechecks
 import (
 	"time"
 
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/clock"
 	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
 	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
 	"github.com/mrumyantsev/go-errlib"
@@ -14,15 +15,44 @@ const (
 	dayTomorrow  = 1
 )
 
+// TimeChecks tell, when data of the source is updated. The update time is
+// a wall clock time in the time zone of updates, so it is kept on days of
+// DST transitions, when days are shorter or longer than 24 hours.
 type TimeChecks struct {
-	config *config.Config
+	config     *config.Config
+	clock      clock.Clock
+	updateTime time.Time
+	location   *time.Location
 }
 
-func New(cfg *config.Config) *TimeChecks {
-	return &TimeChecks{config: cfg}
+func New(cfg *config.Config, clk clock.Clock) (*TimeChecks, error) {
+	updateTime, err := time.Parse(time.TimeOnly, cfg.TimeWhenNeedToUpdateCurrency)
+	if err != nil {
+		return nil, errlib.Wrap(err, "could not parse update time from config")
+	}
+
+	location, err := time.LoadLocation(cfg.UpdateTimeZone)
+	if err != nil {
+		return nil, errlib.Wrap(err, "could not load time zone of updates")
+	}
+
+	return &TimeChecks{
+		config:     cfg,
+		clock:      clk,
+		updateTime: updateTime,
+		location:   location,
+	}, nil
 }
 
+// IsNeedForUpdateDb reports, whether the data, stored by the update, is
+// outdated. Data is up to date, if it was stored at today's update time or
+// later, or, until today's update time comes, at yesterday's one or later.
+// Data is always outdated, if nothing is stored yet.
 func (t *TimeChecks) IsNeedForUpdateDb(updateDatetime *models.UpdateDatetime) (bool, error) {
+	if updateDatetime.Id == 0 {
+		return true, nil
+	}
+
 	latestUpdateDatetime, err := time.Parse(
 		time.RFC3339,
 		updateDatetime.UpdateDatetime,
@@ -31,76 +61,65 @@ func (t *TimeChecks) IsNeedForUpdateDb(updateDatetime *models.UpdateDatetime) (b
 		return false, errlib.Wrap(err, "could not parse update time from db")
 	}
 
-	todayUpdateDatetime, err := t.DayUpdateDatetime(dayToday)
-	if err != nil {
-		return false, errlib.Wrap(err, "could not get today update datetime")
-	}
+	// all the checks are made for the same moment, otherwise "today" may
+	// change between them around midnight
+	currentDatetime := t.clock.Now()
 
-	yesterdayUpdateDatetime, err := t.DayUpdateDatetime(dayYesterday)
-	if err != nil {
-		return false, errlib.Wrap(err, "could not get yesterday update datetime")
+	todayUpdateDatetime := t.DayUpdateDatetime(currentDatetime, dayToday)
+
+	// update times are stored to the second, so the update, which started
+	// at the update time, is stored exactly at it
+	if !latestUpdateDatetime.Before(todayUpdateDatetime) {
+		return false, nil
 	}
 
-	currentDatetime := time.Now()
+	if currentDatetime.Before(todayUpdateDatetime) {
+		yesterdayUpdateDatetime := t.DayUpdateDatetime(currentDatetime, dayYesterday)
 
-	isNeedUpdate := !(latestUpdateDatetime.After(todayUpdateDatetime) ||
-		((latestUpdateDatetime.After(yesterdayUpdateDatetime) &&
-			latestUpdateDatetime.Before(todayUpdateDatetime)) &&
-			currentDatetime.Before(todayUpdateDatetime)))
+		return latestUpdateDatetime.Before(yesterdayUpdateDatetime), nil
+	}
 
-	return isNeedUpdate, nil
+	return true, nil
 }
 
-func (t *TimeChecks) TimeToNextUpdate() (time.Duration, error) {
-	currentDatetime := time.Now()
-	day := dayToday
+// TimeToNextUpdate returns the time, which is left until the next update
+// time. At the update time itself the next one is tomorrow's, as the
+// current update is being made.
+func (t *TimeChecks) TimeToNextUpdate() time.Duration {
+	currentDatetime := t.clock.Now()
 
-	var (
-		todayUpdateDatetime time.Time
-		nextUpdateDatetime  time.Time
-		timeToNextUpdate    time.Duration
-		err                 error
-	)
-
-	todayUpdateDatetime, err = t.DayUpdateDatetime(dayToday)
-	if err != nil {
-		return timeToNextUpdate, errlib.Wrap(err, "could not get today update datetime")
-	}
+	return t.NextUpdateDatetime(currentDatetime).Sub(currentDatetime)
+}
 
-	if currentDatetime.After(todayUpdateDatetime) {
-		day = dayTomorrow
-	}
+// NextUpdateDatetime returns the first update time after the moment. At
+// the update time itself the next one is tomorrow's.
+func (t *TimeChecks) NextUpdateDatetime(moment time.Time) time.Time {
+	nextUpdateDatetime := t.DayUpdateDatetime(moment, dayToday)
 
-	if nextUpdateDatetime, err = t.DayUpdateDatetime(day); err != nil {
-		return timeToNextUpdate, errlib.Wrap(err, "could not get next update datetime")
+	if !moment.Before(nextUpdateDatetime) {
+		nextUpdateDatetime = t.DayUpdateDatetime(moment, dayTomorrow)
 	}
 
-	timeToNextUpdate = time.Since(nextUpdateDatetime).Abs()
-
-	return timeToNextUpdate, nil
+	return nextUpdateDatetime
 }
 
-func (t *TimeChecks) DayUpdateDatetime(todayOffset int) (time.Time, error) {
-	updateTime, err := time.Parse(
-		time.TimeOnly,
-		t.config.TimeWhenNeedToUpdateCurrency,
-	)
-	if err != nil {
-		return updateTime, errlib.Wrap(err, "could not parse update time from config")
-	}
-
-	todayYear, todayMonth, todayDay := time.Now().Date()
-
-	todayUpdateDatetime := time.Date(
-		todayYear,
-		todayMonth,
-		todayDay+todayOffset,
-		updateTime.Hour(),
-		updateTime.Minute(),
-		updateTime.Second(),
+// DayUpdateDatetime returns the update time of the day, which is the given
+// number of days after the date of the moment in the time zone of updates.
+// Days are added to the date, not as 24 hours, and time.Date normalizes
+// dates across month and year boundaries. If the update time does not
+// exist on the day, as it falls into a DST gap, it is moved forward by the
+// length of the gap.
+func (t *TimeChecks) DayUpdateDatetime(moment time.Time, dayOffset int) time.Time {
+	year, month, day := moment.In(t.location).Date()
+
+	return time.Date(
+		year,
+		month,
+		day+dayOffset,
+		t.updateTime.Hour(),
+		t.updateTime.Minute(),
+		t.updateTime.Second(),
 		0, // drop nanoseconds
-		time.Now().Location(),
+		t.location,
 	)
-
-	return todayUpdateDatetime, nil
 }
@@ -0,0 +1,224 @@
+package timechecks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/clock"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+)
+
+func newTimeChecks(t *testing.T, zone string, updateTime string, now time.Time) *TimeChecks {
+	t.Helper()
+
+	timeChecks, err := New(&config.Config{
+		TimeWhenNeedToUpdateCurrency: updateTime,
+		UpdateTimeZone:               zone,
+	}, clock.NewSimulated(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return timeChecks
+}
+
+func loadLocation(t *testing.T, zone string) *time.Location {
+	t.Helper()
+
+	location, err := time.LoadLocation(zone)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return location
+}
+
+// at returns the wall clock time of the zone.
+func at(t *testing.T, zone string, value string) time.Time {
+	t.Helper()
+
+	moment, err := time.ParseInLocation(time.DateTime, value, loadLocation(t, zone))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return moment
+}
+
+func TestTimeToNextUpdate(t *testing.T) {
+	tests := []struct {
+		name       string
+		zone       string
+		updateTime string
+		now        string
+		nowZone    string
+		want       time.Duration
+	}{
+		{"before update", "Europe/Berlin", "13:30:00", "2024-03-02 12:00:00", "", 90 * time.Minute},
+		{"second before update", "Europe/Berlin", "13:30:00", "2024-03-02 13:29:59", "", time.Second},
+		{"exactly at update", "Europe/Berlin", "13:30:00", "2024-03-02 13:30:00", "", 24 * time.Hour},
+		{"after update", "Europe/Berlin", "13:30:00", "2024-03-02 20:00:00", "", 17*time.Hour + 30*time.Minute},
+		{"at midnight", "Europe/Berlin", "13:30:00", "2024-03-02 00:00:00", "", 13*time.Hour + 30*time.Minute},
+		{"before midnight", "Europe/Berlin", "13:30:00", "2024-03-02 23:59:59", "", 13*time.Hour + 30*time.Minute + time.Second},
+		{"update at midnight", "Europe/Berlin", "00:00:00", "2024-03-02 00:00:00", "", 24 * time.Hour},
+		{"day before spring dst", "Europe/Berlin", "13:30:00", "2024-03-30 13:30:00", "", 23 * time.Hour},
+		{"day before fall dst", "Europe/Berlin", "13:30:00", "2024-10-26 13:30:00", "", 25 * time.Hour},
+		{"update in spring dst gap", "Europe/Berlin", "02:30:00", "2024-03-31 00:00:00", "", 2*time.Hour + 30*time.Minute},
+		{"update after spring dst", "Europe/Berlin", "13:30:00", "2024-03-31 00:00:00", "", 12*time.Hour + 30*time.Minute},
+		{"update after fall dst", "Europe/Berlin", "13:30:00", "2024-10-27 00:00:00", "", 14*time.Hour + 30*time.Minute},
+		{"end of month", "Europe/Berlin", "13:30:00", "2024-01-31 14:00:00", "", 23*time.Hour + 30*time.Minute},
+		{"end of leap february", "Europe/Berlin", "13:30:00", "2024-02-28 14:00:00", "", 23*time.Hour + 30*time.Minute},
+		{"end of year", "Europe/Berlin", "13:30:00", "2023-12-31 14:00:00", "", 23*time.Hour + 30*time.Minute},
+		{"clock in other zone before its midnight", "Europe/Moscow", "13:30:00", "2024-03-02 22:00:00", "UTC", 12*time.Hour + 30*time.Minute},
+		{"clock in other zone after update", "Europe/Moscow", "13:30:00", "2024-03-02 11:00:00", "UTC", 23*time.Hour + 30*time.Minute},
+		{"end of year in other zone", "Europe/Moscow", "13:30:00", "2023-12-31 22:00:00", "UTC", 12*time.Hour + 30*time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nowZone := tt.nowZone
+			if nowZone == "" {
+				nowZone = tt.zone
+			}
+
+			timeChecks := newTimeChecks(t, tt.zone, tt.updateTime, at(t, nowZone, tt.now))
+
+			if got := timeChecks.TimeToNextUpdate(); got != tt.want {
+				t.Fatalf("time to next update = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsNeedForUpdateDb(t *testing.T) {
+	const zone = "Europe/Berlin"
+
+	tests := []struct {
+		name   string
+		now    string
+		stored string
+		want   bool
+	}{
+		{"nothing stored", "2024-03-02 12:00:00", "", true},
+		{"stored at today's update", "2024-03-02 14:00:00", "2024-03-02 13:30:00", false},
+		{"stored before today's update", "2024-03-02 14:00:00", "2024-03-02 13:29:59", true},
+		{"stored at yesterday's update", "2024-03-02 12:00:00", "2024-03-01 13:30:00", false},
+		{"stored before yesterday's update", "2024-03-02 12:00:00", "2024-03-01 13:29:59", true},
+		{"restart exactly at update", "2024-03-02 13:30:00", "2024-03-01 13:30:00", true},
+		{"restart after update at update time", "2024-03-02 13:30:00", "2024-03-02 13:30:00", false},
+		{"at midnight", "2024-03-02 00:00:00", "2024-03-01 13:30:00", false},
+		{"at midnight before yesterday's update", "2024-03-02 00:00:00", "2024-03-01 13:29:59", true},
+		{"before midnight", "2024-03-01 23:59:59", "2024-03-01 13:30:00", false},
+		{"first of month", "2024-02-01 10:00:00", "2024-01-31 13:30:00", false},
+		{"first of month at update", "2024-02-01 13:30:00", "2024-01-31 13:30:00", true},
+		{"first of march of leap year", "2024-03-01 10:00:00", "2024-02-29 13:30:00", false},
+		{"first of year", "2024-01-01 00:00:00", "2023-12-31 13:30:00", false},
+		{"first of year at update", "2024-01-01 13:30:00", "2023-12-31 13:30:00", true},
+		{"spring dst before update", "2024-03-31 13:29:59", "2024-03-30 13:30:00", false},
+		{"spring dst at update", "2024-03-31 13:30:00", "2024-03-30 13:30:00", true},
+		{"fall dst before update", "2024-10-27 13:29:59", "2024-10-26 13:30:00", false},
+		{"fall dst at update", "2024-10-27 13:30:00", "2024-10-26 13:30:00", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeChecks := newTimeChecks(t, zone, "13:30:00", at(t, zone, tt.now))
+
+			updateDatetime := &models.UpdateDatetime{}
+
+			// update times are stored in UTC, not in the zone of updates
+			if tt.stored != "" {
+				updateDatetime.Id = 1
+				updateDatetime.UpdateDatetime = at(t, zone, tt.stored).UTC().Format(time.RFC3339)
+			}
+
+			got, err := timeChecks.IsNeedForUpdateDb(updateDatetime)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tt.want {
+				t.Fatalf("need for update = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextUpdateDatetime(t *testing.T) {
+	const zone = "Europe/Berlin"
+
+	tests := []struct {
+		name   string
+		moment string
+		want   string
+	}{
+		{"stored before update", "2024-03-01 10:00:00", "2024-03-01 13:30:00"},
+		{"stored at update", "2024-03-01 13:30:00", "2024-03-02 13:30:00"},
+		{"stored after update", "2024-03-01 13:30:05", "2024-03-02 13:30:00"},
+		{"stored on last day of year", "2023-12-31 20:00:00", "2024-01-01 13:30:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeChecks := newTimeChecks(t, zone, "13:30:00", at(t, zone, tt.moment))
+
+			// update times are stored in UTC
+			got := timeChecks.NextUpdateDatetime(at(t, zone, tt.moment).UTC())
+
+			if want := at(t, zone, tt.want); !got.Equal(want) {
+				t.Fatalf("next update = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+// TestSchedule sleeps on a simulated clock until each next update for a
+// year, and checks, that updates keep the wall clock time of the zone
+// across DST transitions and month and year boundaries.
+func TestSchedule(t *testing.T) {
+	tests := []struct {
+		zone       string
+		updateTime string
+	}{
+		{"Europe/Berlin", "13:30:00"},
+		{"Europe/Berlin", "00:00:00"},
+		{"Europe/Moscow", "13:30:00"},
+		{"America/New_York", "23:59:59"},
+		{"UTC", "13:30:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.zone+" "+tt.updateTime, func(t *testing.T) {
+			location := loadLocation(t, tt.zone)
+
+			clk := clock.NewSimulated(time.Date(2023, time.December, 30, 12, 0, 0, 0, location))
+
+			timeChecks, err := New(&config.Config{
+				TimeWhenNeedToUpdateCurrency: tt.updateTime,
+				UpdateTimeZone:               tt.zone,
+			}, clk)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			previous := clk.Now().In(location)
+
+			for i := 0; i < 366; i++ {
+				clk.Sleep(timeChecks.TimeToNextUpdate())
+
+				now := clk.Now().In(location)
+
+				if now.Format(time.TimeOnly) != tt.updateTime {
+					t.Fatalf("update %d is at %s, want %s", i+1, now, tt.updateTime)
+				}
+
+				if want := previous.AddDate(0, 0, 1).Format(time.DateOnly); (i > 0) && (now.Format(time.DateOnly) != want) {
+					t.Fatalf("update %d is on %s, want %s", i+1, now.Format(time.DateOnly), want)
+				}
+
+				previous = now
+			}
+		})
+	}
+}
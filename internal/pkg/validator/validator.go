@@ -0,0 +1,282 @@
+package validator
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/calculator"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	feedDateLayout = "02.01.2006"
+
+	// snapshotCode marks problems of the whole snapshot
+	snapshotCode = "*"
+
+	percentDecimalPlaces = 2
+)
+
+var hundred = big.NewRat(100, 1)
+
+// A Problem is a reason to reject a snapshot. Code is the alphabetic code
+// of the currency, or its numeric one, if it has none, or an asterisk for
+// problems of the whole snapshot.
+type Problem struct {
+	Code    string
+	Message string
+}
+
+func (p Problem) String() string {
+	return p.Code + ": " + p.Message
+}
+
+// A Report lists all the problems, which make a snapshot rejected.
+type Report struct {
+	Problems []Problem
+}
+
+func (r *Report) Error() string {
+	problems := make([]string, len(r.Problems))
+
+	for i, problem := range r.Problems {
+		problems[i] = problem.String()
+	}
+
+	return "snapshot is rejected with " + strconv.Itoa(len(r.Problems)) + " problems: " +
+		strings.Join(problems, "; ")
+}
+
+// A Validator checks snapshots of the source, before they are stored, so
+// an error page or a truncated response is never taken for rates.
+type Validator struct {
+	config     *config.Config
+	calculator *calculator.Calculator
+
+	mu sync.Mutex
+	// movements are the rejected movements of rates by numeric codes of
+	// currencies, which are waiting for confirmation
+	movements map[int]movement
+	// skipMovements are the alphabetic codes of currencies, which
+	// movements of rates are not checked
+	skipMovements map[string]bool
+}
+
+// A movement is a rate, which has changed by more than allowed, and the
+// number of fetches in a row, which have held it.
+type movement struct {
+	rate  *big.Rat
+	count int
+}
+
+func New(cfg *config.Config) *Validator {
+	skipMovements := make(map[string]bool, len(cfg.ValidationSkipMovementCodes))
+
+	for _, code := range cfg.ValidationSkipMovementCodes {
+		skipMovements[strings.ToUpper(strings.TrimSpace(code))] = true
+	}
+
+	return &Validator{
+		config:        cfg,
+		calculator:    calculator.New(cfg),
+		movements:     make(map[int]movement),
+		skipMovements: skipMovements,
+	}
+}
+
+// Validate checks the snapshot and compares its rates with the previous
+// snapshot, which may be empty. A *Report is returned, if there are any
+// problems. A rate, which has changed by more than allowed, is accepted,
+// when it is held by the configured number of fetches in a row, as the
+// change is real then.
+func (v *Validator) Validate(currencies models.Currencies, previous models.Currencies) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	var report Report
+
+	problem := func(code string, message string) {
+		report.Problems = append(report.Problems, Problem{Code: code, Message: message})
+	}
+
+	if count := len(currencies.Currencies); count < v.config.ValidationMinCurrencies {
+		problem(snapshotCode, "there are "+strconv.Itoa(count)+" currencies, expected at least "+
+			strconv.Itoa(v.config.ValidationMinCurrencies))
+	}
+
+	if currencies.Date == "" {
+		problem(snapshotCode, "date is missing")
+	} else if _, err := time.Parse(feedDateLayout, currencies.Date); err != nil {
+		problem(snapshotCode, "date is not in DD.MM.YYYY format: "+currencies.Date)
+	}
+
+	var (
+		charCodes      = make(map[string]bool)
+		numCodes       = make(map[int]bool)
+		moved          = make(map[int]bool)
+		previousByCode = make(map[int]models.Currency, len(previous.Currencies))
+	)
+
+	for _, currency := range previous.Currencies {
+		previousByCode[currency.NumCode] = currency
+	}
+
+	for _, currency := range currencies.Currencies {
+		code := currency.CharCode
+		if code == "" {
+			code = strconv.Itoa(currency.NumCode)
+		}
+
+		for _, message := range v.currencyProblems(currency) {
+			problem(code, message)
+		}
+
+		if charCodes[currency.CharCode] && (currency.CharCode != "") {
+			problem(code, "alphabetic code is repeated")
+		}
+
+		if numCodes[currency.NumCode] {
+			problem(code, "numeric code is repeated: "+strconv.Itoa(currency.NumCode))
+		}
+
+		charCodes[currency.CharCode] = true
+		numCodes[currency.NumCode] = true
+
+		prev, ok := previousByCode[currency.NumCode]
+		if !ok || (v.config.ValidationMaxDailyChange <= 0) || v.skipMovements[currency.CharCode] {
+			continue
+		}
+
+		if message := v.movementProblem(currency, prev); message != "" {
+			moved[currency.NumCode] = true
+
+			if !v.isConfirmedMovement(currency) {
+				problem(code, message)
+			}
+		}
+	}
+
+	// movements, which are not held anymore, start over
+	for numCode := range v.movements {
+		if !moved[numCode] {
+			delete(v.movements, numCode)
+		}
+	}
+
+	if len(report.Problems) > 0 {
+		return &report
+	}
+
+	return nil
+}
+
+func (v *Validator) currencyProblems(currency models.Currency) []string {
+	var problems []string
+
+	if currency.CharCode == "" {
+		problems = append(problems, "alphabetic code is missing")
+	} else if numCode, ok := iso4217[currency.CharCode]; !ok {
+		problems = append(problems, "alphabetic code is not in ISO 4217")
+	} else if numCode != currency.NumCode {
+		problems = append(problems, "numeric code "+strconv.Itoa(currency.NumCode)+
+			" does not match ISO 4217 one "+strconv.Itoa(numCode))
+	}
+
+	if currency.CharCode == calculator.SourceCurrency {
+		problems = append(problems, "source currency is quoted against itself")
+	}
+
+	if strings.TrimSpace(currency.Name) == "" {
+		problems = append(problems, "name is missing")
+	}
+
+	if currency.Multiplier <= 0 {
+		problems = append(problems, "nominal is not positive: "+strconv.Itoa(currency.Multiplier))
+	}
+
+	if currency.Value == "" {
+		problems = append(problems, "value is missing")
+	} else if value, err := calculator.ParseDecimal(string(currency.Value)); err != nil {
+		problems = append(problems, "value is not a decimal number: "+string(currency.Value))
+	} else if value.Sign() <= 0 {
+		problems = append(problems, "value is not positive: "+string(currency.Value))
+	}
+
+	return problems
+}
+
+// movementProblem compares rates for a single unit of the currency, so
+// changes of nominals are not taken for movements.
+func (v *Validator) movementProblem(currency models.Currency, previous models.Currency) string {
+	rate, err := calculator.UnitRate(currency)
+	if err != nil {
+		return ""
+	}
+
+	previousRate, err := calculator.UnitRate(previous)
+	if err != nil {
+		return ""
+	}
+
+	change := changePercent(rate, previousRate)
+
+	if !v.isAllowedChange(change) {
+		return "rate has changed by " + calculator.FormatDecimal(change, percentDecimalPlaces) +
+			"% from " + v.calculator.Format(previousRate) + " to " + v.calculator.Format(rate) +
+			", more than " + strconv.Itoa(v.config.ValidationMaxDailyChange) + "% allowed"
+	}
+
+	return ""
+}
+
+// isConfirmedMovement counts the fetches in a row, which hold the moved
+// rate of the currency within the allowed change, and reports, whether
+// there are enough of them to accept the rate.
+func (v *Validator) isConfirmedMovement(currency models.Currency) bool {
+	rate, err := calculator.UnitRate(currency)
+	if err != nil {
+		return false
+	}
+
+	pending, ok := v.movements[currency.NumCode]
+
+	if ok && v.isAllowedChange(changePercent(rate, pending.rate)) {
+		pending.count++
+	} else {
+		pending.count = 1
+	}
+
+	pending.rate = rate
+	v.movements[currency.NumCode] = pending
+
+	confirmations := v.config.ValidationMovementConfirmations
+
+	if (confirmations <= 0) || (pending.count < confirmations) {
+		return false
+	}
+
+	log.Warn().Str("code", currency.CharCode).Int("fetches", pending.count).
+		Msg("rate has changed by more than allowed, but is accepted, as it is held by fetches in a row")
+
+	return true
+}
+
+func (v *Validator) isAllowedChange(change *big.Rat) bool {
+	limit := big.NewRat(int64(v.config.ValidationMaxDailyChange), 1)
+
+	return new(big.Rat).Abs(change).Cmp(limit) <= 0
+}
+
+// changePercent returns the change of the rate from the previous one in
+// percent of the previous one.
+func changePercent(rate *big.Rat, previous *big.Rat) *big.Rat {
+	change := new(big.Rat).Sub(rate, previous)
+	change.Quo(change, previous)
+
+	return change.Mul(change, hundred)
+}
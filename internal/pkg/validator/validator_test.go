@@ -0,0 +1,140 @@
+package validator
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+)
+
+func newTestConfig() *config.Config {
+	return &config.Config{
+		ValidationMinCurrencies:         1,
+		ValidationMaxDailyChange:        30,
+		ValidationMovementConfirmations: 3,
+	}
+}
+
+func snapshot(multiplier int, value string) models.Currencies {
+	return models.Currencies{
+		Date: "02.03.2024",
+		Currencies: []models.Currency{
+			{NumCode: 840, CharCode: "USD", Multiplier: multiplier, Name: "Доллар США", Value: models.Decimal(value)},
+		},
+	}
+}
+
+func TestMovementThresholds(t *testing.T) {
+	previous := snapshot(1, "100")
+
+	tests := []struct {
+		name       string
+		maxChange  int
+		skipCodes  []string
+		current    models.Currencies
+		isRejected bool
+	}{
+		{"no change", 30, nil, snapshot(1, "100"), false},
+		{"rise at limit", 30, nil, snapshot(1, "130"), false},
+		{"rise over limit", 30, nil, snapshot(1, "130.0001"), true},
+		{"fall at limit", 30, nil, snapshot(1, "70"), false},
+		{"fall over limit", 30, nil, snapshot(1, "69.9999"), true},
+		{"nominal change keeps unit rate", 30, nil, snapshot(10, "1000"), false},
+		{"nominal change moves unit rate", 30, nil, snapshot(10, "100"), true},
+		{"lower limit", 5, nil, snapshot(1, "106"), true},
+		{"check disabled", 0, nil, snapshot(1, "1000"), false},
+		{"currency skipped", 30, []string{"eur", " usd "}, snapshot(1, "1000"), false},
+		{"other currency skipped", 30, []string{"EUR"}, snapshot(1, "1000"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.ValidationMaxDailyChange = tt.maxChange
+			cfg.ValidationSkipMovementCodes = tt.skipCodes
+
+			err := New(cfg).Validate(tt.current, previous)
+
+			if isRejected := err != nil; isRejected != tt.isRejected {
+				t.Fatalf("rejected = %t, want %t, error: %v", isRejected, tt.isRejected, err)
+			}
+
+			var report *Report
+
+			if (err != nil) && !errors.As(err, &report) {
+				t.Fatalf("error = %v, want a report", err)
+			}
+		})
+	}
+}
+
+func TestMovementConfirmations(t *testing.T) {
+	previous := snapshot(1, "100")
+
+	tests := []struct {
+		name          string
+		confirmations int
+		fetches       []string
+		want          []bool
+	}{
+		{"held rate is accepted", 3, []string{"200", "200", "200"}, []bool{true, true, false}},
+		{"rate held within limit is accepted", 3, []string{"200", "220", "190"}, []bool{true, true, false}},
+		{"rate moved again starts over", 3, []string{"200", "300", "300", "300"}, []bool{true, true, true, false}},
+		{"return to previous rate starts over", 2, []string{"200", "100", "200", "200"}, []bool{true, false, true, false}},
+		{"single confirmation accepts at once", 1, []string{"200"}, []bool{false}},
+		{"confirmations disabled", 0, []string{"200", "200", "200", "200"}, []bool{true, true, true, true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.ValidationMovementConfirmations = tt.confirmations
+
+			v := New(cfg)
+
+			for i, value := range tt.fetches {
+				err := v.Validate(snapshot(1, value), previous)
+
+				if isRejected := err != nil; isRejected != tt.want[i] {
+					t.Fatalf("fetch %d of %s: rejected = %t, want %t, error: %v",
+						i+1, value, isRejected, tt.want[i], err)
+				}
+			}
+		})
+	}
+}
+
+func TestSnapshotProblems(t *testing.T) {
+	tests := []struct {
+		name     string
+		current  models.Currencies
+		problems int
+	}{
+		{"valid", snapshot(1, "91.3336"), 0},
+		{"date is missing", models.Currencies{Currencies: snapshot(1, "1").Currencies}, 1},
+		{"date in other format", models.Currencies{Date: "2024-03-02", Currencies: snapshot(1, "1").Currencies}, 1},
+		{"value is not positive", snapshot(1, "0"), 1},
+		{"nominal is not positive", snapshot(0, "1"), 1},
+		{"too few currencies", models.Currencies{Date: "02.03.2024"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := New(newTestConfig()).Validate(tt.current, models.Currencies{})
+
+			var report *Report
+
+			switch {
+			case tt.problems == 0:
+				if err != nil {
+					t.Fatalf("error = %v, want none", err)
+				}
+			case !errors.As(err, &report):
+				t.Fatalf("error = %v, want a report", err)
+			case len(report.Problems) != tt.problems:
+				t.Fatalf("got %d problems, want %d: %v", len(report.Problems), tt.problems, report)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package xmlparser
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+	"github.com/mrumyantsev/go-errlib"
+)
+
+// rawReference is a reference list as it is. Numeric codes are empty for
+// the currencies, which are not in ISO 4217, so they are not decoded as
+// integers.
+type rawReference struct {
+	Items []struct {
+		SourceId string `xml:"ID,attr"`
+		Name     string `xml:"Name"`
+		EngName  string `xml:"EngName"`
+		Nominal  string `xml:"Nominal"`
+		NumCode  string `xml:"ISO_Num_Code"`
+		CharCode string `xml:"ISO_Char_Code"`
+	} `xml:"Item"`
+}
+
+// ParseReference parses the reference list of currencies of the source.
+// All the invalid entries are reported at once.
+func (p *XmlParser) ParseReference(data []byte) ([]models.CurrencyReference, error) {
+	var raw rawReference
+
+	decoder := newDecoder(data)
+
+	if err := decoder.Decode(&raw); err != nil {
+		line, column := decoder.InputPos()
+
+		return nil, errlib.Wrap(err, "could not decode reference list at line "+
+			strconv.Itoa(line)+", column "+strconv.Itoa(column))
+	}
+
+	var (
+		references = make([]models.CurrencyReference, 0, len(raw.Items))
+		errs       []string
+		err        error
+	)
+
+	for _, item := range raw.Items {
+		reference := models.CurrencyReference{
+			SourceId: strings.TrimSpace(item.SourceId),
+			CharCode: strings.TrimSpace(item.CharCode),
+			Name:     strings.TrimSpace(item.Name),
+			EngName:  strings.TrimSpace(item.EngName),
+		}
+
+		if numCode := strings.TrimSpace(item.NumCode); numCode != "" {
+			if reference.NumCode, err = strconv.Atoi(numCode); err != nil {
+				errs = append(errs, reference.SourceId+": ISO_Num_Code is not an integer: "+strconv.Quote(item.NumCode))
+			}
+		}
+
+		reference.Multiplier, err = strconv.Atoi(strings.TrimSpace(item.Nominal))
+		if (err != nil) || (reference.Multiplier < 1) {
+			errs = append(errs, reference.SourceId+": Nominal is not a positive integer: "+strconv.Quote(item.Nominal))
+		}
+
+		if reference.SourceId == "" {
+			errs = append(errs, "ID is empty for "+strconv.Quote(reference.Name))
+		}
+
+		references = append(references, reference)
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.New(strconv.Itoa(len(errs)) + " invalid items of reference list: " +
+			strings.Join(errs, "; "))
+	}
+
+	return references, nil
+}
+
+// ParseDynamic parses a feed of rates of a single currency over a range
+// of dates.
+func (p *XmlParser) ParseDynamic(data []byte) (models.CurrencyDynamic, error) {
+	var dynamic models.CurrencyDynamic
+
+	decoder := newDecoder(data)
+
+	if err := decoder.Decode(&dynamic); err != nil {
+		line, column := decoder.InputPos()
+
+		return dynamic, errlib.Wrap(err, "could not decode dynamic of currency at line "+
+			strconv.Itoa(line)+", column "+strconv.Itoa(column))
+	}
+
+	return dynamic, nil
+}
@@ -0,0 +1,294 @@
+package xmlparser
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+	"github.com/mrumyantsev/go-errlib"
+)
+
+// An ElementError is a problem of a Valute element. Line, Column and
+// Offset point at the end of its start tag.
+type ElementError struct {
+	Line   int
+	Column int
+	Offset int64
+	Err    error
+}
+
+func (e *ElementError) Error() string {
+	return "line " + strconv.Itoa(e.Line) + ", column " + strconv.Itoa(e.Column) +
+		" (offset " + strconv.FormatInt(e.Offset, 10) + "): " + e.Err.Error()
+}
+
+func (e *ElementError) Unwrap() error {
+	return e.Err
+}
+
+// A ParseError lists all the invalid elements of a feed in the order they
+// follow in it.
+type ParseError struct {
+	Errors []*ElementError
+}
+
+func (e *ParseError) Error() string {
+	return strconv.Itoa(len(e.Errors)) + " invalid elements, the first at " + e.Errors[0].Error()
+}
+
+// rawCurrency is a Valute element as it is, so decoding of an element
+// never fails because of its content, and all of it can be validated.
+type rawCurrency struct {
+	SourceId string `xml:"ID,attr"`
+	NumCode  string `xml:"NumCode"`
+	CharCode string `xml:"CharCode"`
+	Nominal  string `xml:"Nominal"`
+	Name     string `xml:"Name"`
+	Value    string `xml:"Value"`
+	UnitRate string `xml:"VunitRate"`
+}
+
+// An element is a decoded Valute element, which waits for conversion.
+type element struct {
+	day    int
+	index  int
+	line   int
+	column int
+	offset int64
+	raw    rawCurrency
+}
+
+type converted struct {
+	day      int
+	index    int
+	currency models.Currency
+	err      *ElementError
+}
+
+// A stream decodes elements one after another, since XML can only be read
+// sequentially, and converts them on a pool of workers.
+type stream struct {
+	config  *config.Config
+	decoder *xml.Decoder
+}
+
+func newStream(cfg *config.Config, decoder *xml.Decoder) *stream {
+	return &stream{
+		config:  cfg,
+		decoder: decoder,
+	}
+}
+
+// parse returns currencies of every ValCurs element in its order. An
+// element, which can not be converted, does not stop parsing, so all of
+// them are reported at once.
+func (s *stream) parse() ([]models.Currencies, error) {
+	workers := s.config.ParserWorkers
+	if workers < 1 {
+		workers = 1
+	}
+
+	var (
+		jobs    = make(chan element, 4*workers)
+		results = make(chan converted, 4*workers)
+		wg      sync.WaitGroup
+	)
+
+	wg.Add(workers)
+
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+
+			for job := range jobs {
+				results <- convert(job)
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var (
+		collected = make(map[int][]models.Currency)
+		errs      []*ElementError
+		done      = make(chan struct{})
+	)
+
+	go func() {
+		defer close(done)
+
+		for result := range results {
+			if result.err != nil {
+				errs = append(errs, result.err)
+
+				continue
+			}
+
+			currencies := collected[result.day]
+
+			for len(currencies) <= result.index {
+				currencies = append(currencies, models.Currency{})
+			}
+
+			currencies[result.index] = result.currency
+			collected[result.day] = currencies
+		}
+	}()
+
+	days, decodeErr := s.decode(jobs)
+
+	close(jobs)
+	<-done
+
+	if decodeErr != nil {
+		return nil, decodeErr
+	}
+
+	if len(errs) > 0 {
+		sort.Slice(errs, func(a, b int) bool {
+			return errs[a].Offset < errs[b].Offset
+		})
+
+		return nil, &ParseError{Errors: errs}
+	}
+
+	for i := range days {
+		if currencies, ok := collected[i]; ok {
+			days[i].Currencies = currencies
+		}
+	}
+
+	return days, nil
+}
+
+// decode sends Valute elements to the jobs and returns the dates of the
+// ValCurs elements, which hold them.
+func (s *stream) decode(jobs chan<- element) ([]models.Currencies, error) {
+	var (
+		days  []models.Currencies
+		count int
+	)
+
+	for {
+		token, err := s.decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errlib.Wrap(err, "could not decode xml element")
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case rootXmlElement:
+			day := models.Currencies{
+				Currencies: make([]models.Currency, 0, s.config.InitialCurrenciesCapacity),
+			}
+
+			for _, attr := range start.Attr {
+				switch attr.Name.Local {
+				case dateXmlAttr:
+					day.Date = attr.Value
+				case nameXmlAttr:
+					day.Name = attr.Value
+				}
+			}
+
+			days = append(days, day)
+			count = 0
+		case firstXmlElement:
+			job := element{
+				day:    len(days) - 1,
+				index:  count,
+				offset: s.decoder.InputOffset(),
+			}
+
+			job.line, job.column = s.decoder.InputPos()
+
+			if err = s.decoder.DecodeElement(&job.raw, &start); err != nil {
+				return nil, errlib.Wrap(err, "could not decode currency at line "+strconv.Itoa(job.line))
+			}
+
+			if job.day < 0 {
+				return nil, errors.New("currency outside of " + rootXmlElement +
+					" element at line " + strconv.Itoa(job.line))
+			}
+
+			jobs <- job
+
+			count++
+		}
+	}
+
+	return days, nil
+}
+
+// convert validates the raw element and converts it to a currency.
+func convert(job element) converted {
+	var (
+		result = converted{day: job.day, index: job.index}
+		raw    = job.raw
+		errs   []string
+		err    error
+	)
+
+	currency := &result.currency
+
+	currency.SourceId = strings.TrimSpace(raw.SourceId)
+
+	if currency.NumCode, err = strconv.Atoi(strings.TrimSpace(raw.NumCode)); err != nil {
+		errs = append(errs, "NumCode is not an integer: "+strconv.Quote(raw.NumCode))
+	}
+
+	if currency.CharCode = strings.TrimSpace(raw.CharCode); currency.CharCode == "" {
+		errs = append(errs, "CharCode is empty")
+	}
+
+	currency.Multiplier, err = strconv.Atoi(strings.TrimSpace(raw.Nominal))
+	if (err != nil) || (currency.Multiplier < 1) {
+		errs = append(errs, "Nominal is not a positive integer: "+strconv.Quote(raw.Nominal))
+	}
+
+	if currency.Name = strings.TrimSpace(raw.Name); currency.Name == "" {
+		errs = append(errs, "Name is empty")
+	}
+
+	if currency.Value, err = models.NewDecimal(raw.Value); err != nil {
+		errs = append(errs, "Value is "+err.Error())
+	}
+
+	if strings.TrimSpace(raw.UnitRate) != "" {
+		if currency.UnitRate, err = models.NewDecimal(raw.UnitRate); err != nil {
+			errs = append(errs, "VunitRate is "+err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		name := currency.CharCode
+		if name == "" {
+			name = firstXmlElement
+		}
+
+		result.err = &ElementError{
+			Line:   job.line,
+			Column: job.column,
+			Offset: job.offset,
+			Err:    errors.New(name + ": " + strings.Join(errs, ", ")),
+		}
+	}
+
+	return result
+}
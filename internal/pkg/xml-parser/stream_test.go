@@ -0,0 +1,204 @@
+package xmlparser
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
+	"github.com/mrumyantsev/currency-converter-app/internal/pkg/models"
+)
+
+const valuteStartTag = `<Valute ID="R%05d">`
+
+// testArchive returns concatenated feeds of the days, each of the given
+// number of currencies, starting from the first of January 2024. Elements
+// with the ordinal numbers of the invalid set have a broken NumCode.
+func testArchive(days int, currencies int, invalid map[int]bool) []byte {
+	var (
+		sb    strings.Builder
+		start = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+		n     = 0
+	)
+
+	for d := 0; d < days; d++ {
+		fmt.Fprintf(&sb, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<ValCurs Date=\"%s\" name=\"Foreign Currency Market\">\n",
+			start.AddDate(0, 0, d).Format("02.01.2006"))
+
+		for c := 0; c < currencies; c++ {
+			numCode := strconv.Itoa(100 + c)
+			if invalid[n] {
+				numCode = "x" + numCode
+			}
+
+			fmt.Fprintf(&sb, valuteStartTag+"<NumCode>%s</NumCode><CharCode>C%02d</CharCode>"+
+				"<Nominal>%d</Nominal><Name>Currency %d</Name><Value>%d,%04d</Value>"+
+				"<VunitRate>%d,%04d</VunitRate></Valute>\n",
+				n, numCode, c, 1+c%3, c, 10+c, d, 10+c, d)
+
+			n++
+		}
+
+		sb.WriteString("</ValCurs>\n")
+	}
+
+	return []byte(sb.String())
+}
+
+// parseArchiveSingleThreaded decodes the feeds of the archive one after
+// another without the pool of workers.
+func parseArchiveSingleThreaded(p *XmlParser, data []byte) ([]models.Currencies, error) {
+	decoder := newDecoder(data)
+
+	var days []models.Currencies
+
+	for {
+		day, err := p.parsedDataSingleThreaded(decoder)
+		if errors.Is(err, io.EOF) {
+			return days, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		days = append(days, day)
+	}
+}
+
+func TestStreamOrder(t *testing.T) {
+	data := testArchive(30, 40, nil)
+
+	want, err := parseArchiveSingleThreaded(New(&config.Config{}), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(want) != 30 {
+		t.Fatalf("single-threaded parsing returned %d days, want 30", len(want))
+	}
+
+	for _, workers := range []int{0, 1, 2, 8} {
+		t.Run("workers="+strconv.Itoa(workers), func(t *testing.T) {
+			cfg := &config.Config{ParserWorkers: workers, InitialCurrenciesCapacity: 50}
+
+			got, err := newStream(cfg, newDecoder(data)).parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(got) != len(want) {
+				t.Fatalf("got %d days, want %d", len(got), len(want))
+			}
+
+			for i := range want {
+				if got[i].Date != want[i].Date {
+					t.Fatalf("day %d: date = %s, want %s", i, got[i].Date, want[i].Date)
+				}
+
+				if !reflect.DeepEqual(got[i].Currencies, want[i].Currencies) {
+					t.Fatalf("day %s: currencies differ from single-threaded parsing", want[i].Date)
+				}
+			}
+		})
+	}
+}
+
+func TestStreamErrorPositions(t *testing.T) {
+	invalid := map[int]bool{0: true, 7: true, 39: true, 40: true, 555: true, 1199: true}
+
+	data := testArchive(30, 40, invalid)
+	text := string(data)
+
+	for _, workers := range []int{1, 8} {
+		t.Run("workers="+strconv.Itoa(workers), func(t *testing.T) {
+			cfg := &config.Config{ParserWorkers: workers, InitialCurrenciesCapacity: 50}
+
+			_, err := newStream(cfg, newDecoder(data)).parse()
+
+			var parseErr *ParseError
+
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("error = %v, want a parse error", err)
+			}
+
+			if len(parseErr.Errors) != len(invalid) {
+				t.Fatalf("got %d element errors, want %d", len(parseErr.Errors), len(invalid))
+			}
+
+			previous := int64(-1)
+
+			for _, elementErr := range parseErr.Errors {
+				if elementErr.Offset <= previous {
+					t.Fatalf("element errors are not in the order of the archive: %d after %d",
+						elementErr.Offset, previous)
+				}
+
+				previous = elementErr.Offset
+
+				// the start tag ends right before the offset
+				tagStart := strings.LastIndex(text[:elementErr.Offset], "<Valute ")
+
+				var n int
+
+				if _, err := fmt.Sscanf(text[tagStart:elementErr.Offset], valuteStartTag, &n); err != nil {
+					t.Fatalf("offset %d is not at the end of a start tag: %v", elementErr.Offset, err)
+				}
+
+				if !invalid[n] {
+					t.Fatalf("element %d is reported, but it is valid", n)
+				}
+
+				line := strings.Count(text[:elementErr.Offset], "\n") + 1
+				column := int(elementErr.Offset) - strings.LastIndex(text[:elementErr.Offset], "\n")
+
+				if (elementErr.Line != line) || (elementErr.Column != column) {
+					t.Fatalf("element %d is reported at %d:%d, want %d:%d",
+						n, elementErr.Line, elementErr.Column, line, column)
+				}
+
+				if !strings.Contains(elementErr.Error(), "NumCode is not an integer") {
+					t.Fatalf("element %d is reported with unexpected error: %v", n, elementErr)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkParseArchive compares the pool of workers with single-threaded
+// decoding on an archive of a year of daily feeds.
+func BenchmarkParseArchive(b *testing.B) {
+	data := testArchive(365, 55, nil)
+
+	b.Run("single-threaded", func(b *testing.B) {
+		p := New(&config.Config{InitialCurrenciesCapacity: 50})
+
+		b.SetBytes(int64(len(data)))
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			if _, err := parseArchiveSingleThreaded(p, data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run("workers="+strconv.Itoa(workers), func(b *testing.B) {
+			cfg := &config.Config{ParserWorkers: workers, InitialCurrenciesCapacity: 50}
+
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := newStream(cfg, newDecoder(data)).parse(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
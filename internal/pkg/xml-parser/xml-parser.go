@@ -5,6 +5,7 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
+	"strconv"
 	"time"
 
 	"github.com/mrumyantsev/currency-converter-app/internal/pkg/config"
@@ -16,7 +17,10 @@ import (
 )
 
 const (
+	rootXmlElement  = "ValCurs"
 	firstXmlElement = "Valute"
+	dateXmlAttr     = "Date"
+	nameXmlAttr     = "name"
 )
 
 type XmlParser struct {
@@ -27,29 +31,26 @@ func New(cfg *config.Config) *XmlParser {
 	return &XmlParser{config: cfg}
 }
 
+// Parse parses a feed of a single date.
 func (p *XmlParser) Parse(data []byte) (models.Currencies, error) {
 	startTime := time.Now()
-	buffer := bytes.NewBuffer(data)
-	decoder := xml.NewDecoder(buffer)
 
 	var (
 		currencies models.Currencies
 		err        error
 	)
 
-	decoder.CharsetReader = charset.NewReaderLabel
-
 	if p.config.IsUseMultithreadedParsing {
 		log.Debug().Msg("using multithreaded parsing")
 
-		currencies, err = p.parsedDataMultiThreaded(decoder)
+		currencies, err = p.parsedDataMultiThreaded(data)
 		if err != nil {
 			return currencies, errlib.Wrap(err, "could not do multithreaded parsing")
 		}
 	} else {
 		log.Debug().Msg("using singlethreaded parsing")
 
-		currencies, err = p.parsedDataSingleThreaded(decoder)
+		currencies, err = p.parsedDataSingleThreaded(newDecoder(data))
 		if err != nil {
 			return currencies, errlib.Wrap(err, "could not do singlethreaded parsing")
 		}
@@ -62,43 +63,40 @@ func (p *XmlParser) Parse(data []byte) (models.Currencies, error) {
 	return currencies, nil
 }
 
-func (p *XmlParser) parsedDataMultiThreaded(decoder *xml.Decoder) (models.Currencies, error) {
-	currencies := models.Currencies{
-		Currencies: make([]models.Currency, 0, p.config.InitialCurrenciesCapacity),
-	}
-
-	var (
-		currency     models.Currency
-		token        xml.Token
-		startElement xml.StartElement
-		ok           bool
-		err          error
-	)
+// ParseArchive parses an archive of feeds of many dates, which are either
+// concatenated or wrapped into any root element. Feeds are returned in
+// the order they follow in the archive.
+func (p *XmlParser) ParseArchive(data []byte) ([]models.Currencies, error) {
+	startTime := time.Now()
 
-	for {
-		if token, err = decoder.Token(); err != nil {
-			if err == io.EOF {
-				break
-			}
+	days, err := newStream(p.config, newDecoder(data)).parse()
+	if err != nil {
+		return nil, errlib.Wrap(err, "could not parse archive")
+	}
 
-			return currencies, errlib.Wrap(err, "could not decode xml element")
-		}
-		if token == nil {
-			break
-		}
+	elapsedTime := time.Since(startTime)
 
-		if startElement, ok = token.(xml.StartElement); !ok {
-			continue
-		}
+	log.Debug().Msg(fmt.Sprintf("parsing time of %d feeds: %s", len(days), elapsedTime))
 
-		if startElement.Name.Local == firstXmlElement {
-			decoder.DecodeElement(&currency, &startElement)
+	return days, nil
+}
 
-			currencies.Currencies = append(currencies.Currencies, currency)
-		}
+func (p *XmlParser) parsedDataMultiThreaded(data []byte) (models.Currencies, error) {
+	days, err := newStream(p.config, newDecoder(data)).parse()
+	if err != nil {
+		return models.Currencies{}, err
 	}
 
-	return currencies, nil
+	switch len(days) {
+	case 0:
+		return models.Currencies{
+			Currencies: make([]models.Currency, 0),
+		}, nil
+	case 1:
+		return days[0], nil
+	default:
+		return models.Currencies{}, fmt.Errorf("expected a feed of a single date, found %d", len(days))
+	}
 }
 
 func (p *XmlParser) parsedDataSingleThreaded(decoder *xml.Decoder) (models.Currencies, error) {
@@ -107,8 +105,33 @@ func (p *XmlParser) parsedDataSingleThreaded(decoder *xml.Decoder) (models.Curre
 	}
 
 	if err := decoder.Decode(&currencies); err != nil {
-		return currencies, errlib.Wrap(err, "could not decode xml data")
+		line, column := decoder.InputPos()
+
+		return currencies, errlib.Wrap(err, "could not decode xml data at line "+
+			strconv.Itoa(line)+", column "+strconv.Itoa(column))
 	}
 
 	return currencies, nil
 }
+
+// newDecoder returns a decoder, which converts the data to UTF-8 from the
+// encoding of its declaration. Feeds of an archive repeat declarations,
+// and data is converted only by the first of them, since all the feeds
+// of an archive are in the same encoding.
+func newDecoder(data []byte) *xml.Decoder {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	isConverted := false
+
+	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		if isConverted {
+			return input, nil
+		}
+
+		isConverted = true
+
+		return charset.NewReaderLabel(label, input)
+	}
+
+	return decoder
+}
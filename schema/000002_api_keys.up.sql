@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS public.api_keys (
+	id         SERIAL                   NOT NULL UNIQUE,
+	name       TEXT                     NOT NULL,
+	key_hash   CHAR(64)                 NOT NULL UNIQUE,
+	rate_limit INTEGER                  NOT NULL,
+	rate_burst INTEGER                  NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+	revoked_at TIMESTAMP WITH TIME ZONE,
+		CONSTRAINT pk_api_keys PRIMARY KEY (id)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE public.update_datetimes
+	DROP COLUMN IF EXISTS effective_date;
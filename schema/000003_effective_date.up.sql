@@ -0,0 +1,6 @@
+ALTER TABLE public.update_datetimes
+	ADD COLUMN IF NOT EXISTS effective_date DATE;
+
+UPDATE public.update_datetimes
+SET effective_date = update_datetime::DATE
+WHERE effective_date IS NULL;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS public.idx_update_datetimes_effective_date;
+
+DROP INDEX IF EXISTS public.idx_currency_values_info_num_code;
+
+DROP VIEW IF EXISTS public.daily_rates;
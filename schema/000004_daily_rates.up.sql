@@ -0,0 +1,23 @@
+CREATE OR REPLACE VIEW public.daily_rates AS
+SELECT DISTINCT ON (public.update_datetimes.effective_date, public.info.num_code)
+	public.update_datetimes.effective_date,
+	public.info.num_code,
+	public.info.char_code,
+	public.currency_values.currency_value / public.multipliers.multiplier AS unit_value
+FROM public.currency_values
+JOIN public.update_datetimes
+	ON public.currency_values.update_datetime_id = public.update_datetimes.id
+JOIN public.info
+	ON public.currency_values.info_num_code = public.info.num_code
+JOIN public.multipliers
+	ON public.info.multiplier_id = public.multipliers.id
+ORDER BY
+	public.update_datetimes.effective_date,
+	public.info.num_code,
+	public.update_datetimes.id DESC;
+
+CREATE INDEX IF NOT EXISTS idx_currency_values_info_num_code
+	ON public.currency_values (info_num_code, update_datetime_id);
+
+CREATE INDEX IF NOT EXISTS idx_update_datetimes_effective_date
+	ON public.update_datetimes (effective_date, id);
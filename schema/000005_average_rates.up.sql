@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS public.average_rates (
+	period        VARCHAR(7)               NOT NULL,
+	period_start  DATE                     NOT NULL,
+	info_num_code INTEGER                  NOT NULL,
+	average_value NUMERIC                  NOT NULL,
+	rates_count   INTEGER                  NOT NULL,
+	updated_at    TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		CONSTRAINT pk_average_rates PRIMARY KEY (period, info_num_code, period_start),
+		CONSTRAINT ck_average_rates_period CHECK (period IN ('day', 'month', 'quarter')),
+		CONSTRAINT fk_average_rates_info FOREIGN KEY (info_num_code)
+			REFERENCES public.info (num_code) MATCH SIMPLE
+			ON UPDATE NO ACTION
+			ON DELETE CASCADE
+);
+
+INSERT INTO public.average_rates (period, period_start, info_num_code, average_value, rates_count)
+SELECT periods.period, DATE_TRUNC(periods.period, public.daily_rates.effective_date)::DATE,
+	public.daily_rates.num_code, AVG(public.daily_rates.unit_value), COUNT(*)
+FROM public.daily_rates
+CROSS JOIN (VALUES ('day'), ('month'), ('quarter')) AS periods (period)
+GROUP BY 1, 2, 3
+ON CONFLICT (period, info_num_code, period_start) DO NOTHING;
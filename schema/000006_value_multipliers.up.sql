@@ -0,0 +1,32 @@
+DROP VIEW IF EXISTS public.daily_rates;
+
+ALTER TABLE public.currency_values
+	ADD COLUMN IF NOT EXISTS multiplier INTEGER;
+
+UPDATE public.currency_values
+SET multiplier = public.multipliers.multiplier
+FROM public.info
+JOIN public.multipliers
+	ON public.info.multiplier_id = public.multipliers.id
+WHERE public.currency_values.info_num_code = public.info.num_code
+	AND public.currency_values.multiplier IS NULL;
+
+ALTER TABLE public.currency_values
+	ALTER COLUMN multiplier SET NOT NULL,
+	ALTER COLUMN currency_value TYPE NUMERIC(12, 4);
+
+CREATE VIEW public.daily_rates AS
+SELECT DISTINCT ON (public.update_datetimes.effective_date, public.info.num_code)
+	public.update_datetimes.effective_date,
+	public.info.num_code,
+	public.info.char_code,
+	public.currency_values.currency_value / public.currency_values.multiplier AS unit_value
+FROM public.currency_values
+JOIN public.update_datetimes
+	ON public.currency_values.update_datetime_id = public.update_datetimes.id
+JOIN public.info
+	ON public.currency_values.info_num_code = public.info.num_code
+ORDER BY
+	public.update_datetimes.effective_date,
+	public.info.num_code,
+	public.update_datetimes.id DESC;
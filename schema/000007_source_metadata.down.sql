@@ -0,0 +1,8 @@
+ALTER TABLE public.update_datetimes
+	DROP COLUMN IF EXISTS feed_name;
+
+ALTER TABLE public.currency_values
+	DROP COLUMN IF EXISTS unit_rate;
+
+ALTER TABLE public.info
+	DROP COLUMN IF EXISTS source_id;
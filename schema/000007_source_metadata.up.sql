@@ -0,0 +1,8 @@
+ALTER TABLE public.info
+	ADD COLUMN IF NOT EXISTS source_id VARCHAR(16);
+
+ALTER TABLE public.currency_values
+	ADD COLUMN IF NOT EXISTS unit_rate NUMERIC;
+
+ALTER TABLE public.update_datetimes
+	ADD COLUMN IF NOT EXISTS feed_name TEXT;
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS public.source_payloads (
+	id                 SERIAL                   NOT NULL UNIQUE,
+	url                TEXT                     NOT NULL,
+	fetched_at         TIMESTAMP WITH TIME ZONE NOT NULL,
+	sha256             CHAR(64)                 NOT NULL,
+	size               INTEGER                  NOT NULL,
+	header             JSONB                    NOT NULL,
+	archive_key        TEXT                     NOT NULL UNIQUE,
+	is_compressed      BOOLEAN                  NOT NULL,
+	update_datetime_id INTEGER,
+		CONSTRAINT pk_source_payloads PRIMARY KEY (id),
+		CONSTRAINT fk_source_payloads_update_datetimes FOREIGN KEY (update_datetime_id)
+			REFERENCES public.update_datetimes (id) MATCH SIMPLE
+			ON UPDATE NO ACTION
+			ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS ix_source_payloads_fetched_at
+	ON public.source_payloads (fetched_at);
+
+CREATE INDEX IF NOT EXISTS ix_source_payloads_update_datetime_id
+	ON public.source_payloads (update_datetime_id);
@@ -1,6 +1,11 @@
-const serverUrl = "http://127.0.0.1:8080/currencies";
+const apiUrl = "http://127.0.0.1:8080/api/v1";
+const serverUrl = apiUrl + "/currencies?base=RUB";
 const dataUpdateIntervalMilliseconds = 300000;
 const decimalDigitsInResultValue = 4;
+const chartWeeks = 26;
+const chartWidth = 800;
+const chartHeight = 300;
+const chartPadding = 30;
 
 const leftCurrencyButton = $("#left-currency-button");
 const leftCurrencyList = $("#left-currency-list");
@@ -8,13 +13,15 @@ const rightCurrencyButton = $("#right-currency-button");
 const rightCurrencyList = $("#right-currency-list");
 const result = $("#result");
 const info = $("#info-container");
+const chart = $("#chart");
+const chartTitle = $("#chart-title");
 const leftCurrencySide = 1;
 const rightCurrencySide = 2;
 
 const rubleCurrency = {
     name: "Российский рубль",
     charCode: "RUB",
-    ratio: "1.0"
+    inverseRate: "1"
 }
 
 let storedData;
@@ -79,9 +86,9 @@ const getDataFromServer = (url) => {
         type: "get",
         dataType: "json",
         async: false,
-        cache: false,
+        cache: true,
         success: (data) => {
-            result = data;
+            result = data.currencies;
         }
     });
 
@@ -97,10 +104,11 @@ const initPageElements = (data) => {
     rightCurrencyButton.text(
         getExtendedCurrencyName(secondCurrency));
     
-    leftRatio = firstCurrency.ratio;
-    rightRatio = secondCurrency.ratio;
+    leftRatio = parseFloat(firstCurrency.inverseRate);
+    rightRatio = parseFloat(secondCurrency.inverseRate);
 
     calculateResult();
+    updateChart(firstCurrency);
 
     fillList(leftCurrencyList, leftCurrencySide, data);
     fillList(rightCurrencyList, rightCurrencySide, data);
@@ -139,10 +147,11 @@ const initClicksListener = (data) => {
             getExtendedCurrencyName(currency);
 
         if (side === leftCurrencySide) {
-            leftRatio = parseFloat(currency.ratio);
+            leftRatio = parseFloat(currency.inverseRate);
             leftCurrencyButton.text(currencyNameWithCharCode);
+            updateChart(currency);
         } else {
-            rightRatio = parseFloat(currency.ratio);
+            rightRatio = parseFloat(currency.inverseRate);
             rightCurrencyButton.text(currencyNameWithCharCode);
         }
 
@@ -157,6 +166,81 @@ const calculateResult = () => {
         ratio.toFixed(decimalDigitsInResultValue).toString());
 }
 
+const updateChart = (currency) => {
+    chart.empty();
+
+    if (currency.charCode === rubleCurrency.charCode) {
+        chartTitle.text("");
+
+        return;
+    }
+
+    const to = new Date();
+    const from = new Date(to.getTime() - chartWeeks * 7 * 24 * 60 * 60 * 1000);
+    const url = apiUrl + "/currencies/" + currency.charCode + "/candles?interval=week" +
+        "&from=" + formatDate(from) + "&to=" + formatDate(to);
+
+    $.ajax({
+        url: url,
+        type: "get",
+        dataType: "json",
+        success: (data) => {
+            chartTitle.text(getExtendedCurrencyName(currency) + ", RUB за единицу, по неделям");
+            drawCandles(data.candles);
+        },
+        error: () => {
+            chartTitle.text("");
+        }
+    });
+}
+
+const drawCandles = (candles) => {
+    if (candles.length === 0) {
+        return;
+    }
+
+    const lows = candles.map(candle => parseFloat(candle.low));
+    const highs = candles.map(candle => parseFloat(candle.high));
+    const min = Math.min(...lows);
+    const max = Math.max(...highs);
+    const range = (max - min) || 1;
+    const step = (chartWidth - 2 * chartPadding) / candles.length;
+    const bodyWidth = Math.max(step * 0.6, 1);
+
+    const y = (value) =>
+        chartHeight - chartPadding - (value - min) / range * (chartHeight - 2 * chartPadding);
+
+    let svg = "";
+
+    candles.forEach((candle, i) => {
+        const open = parseFloat(candle.open);
+        const close = parseFloat(candle.close);
+        const x = chartPadding + i * step + step / 2;
+        const top = y(Math.max(open, close));
+        const height = Math.max(Math.abs(y(open) - y(close)), 1);
+        const cls = close >= open ? "candle-up" : "candle-down";
+
+        svg +=
+            "<g class=\"" + cls + "\"><title>" + candle.periodStart + " — " + candle.periodEnd +
+            ": " + candle.open + " / " + candle.high + " / " + candle.low + " / " + candle.close +
+            "</title>" +
+            "<line x1=\"" + x + "\" x2=\"" + x + "\" y1=\"" + y(parseFloat(candle.high)) +
+            "\" y2=\"" + y(parseFloat(candle.low)) + "\"/>" +
+            "<rect x=\"" + (x - bodyWidth / 2) + "\" y=\"" + top + "\" width=\"" + bodyWidth +
+            "\" height=\"" + height + "\"/></g>";
+    });
+
+    svg +=
+        "<text class=\"axis-label\" x=\"0\" y=\"" + (chartPadding - 8) + "\">" + max + "</text>" +
+        "<text class=\"axis-label\" x=\"0\" y=\"" + (chartHeight - 8) + "\">" + min + "</text>";
+
+    chart.html(svg);
+}
+
+const formatDate = (date) => {
+    return date.toISOString().slice(0, 10);
+}
+
 const getExtendedCurrencyName = (currency) => {
     return currency.name + " (" + currency.charCode + ")";
 }